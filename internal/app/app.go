@@ -8,6 +8,7 @@ import (
 	"math/rand"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jaxxstorm/sentinel/internal/config"
@@ -17,9 +18,12 @@ import (
 	"github.com/jaxxstorm/sentinel/internal/notify"
 	"github.com/jaxxstorm/sentinel/internal/onboarding"
 	"github.com/jaxxstorm/sentinel/internal/policy"
+	"github.com/jaxxstorm/sentinel/internal/severity"
 	"github.com/jaxxstorm/sentinel/internal/snapshot"
 	"github.com/jaxxstorm/sentinel/internal/source"
 	"github.com/jaxxstorm/sentinel/internal/state"
+	"github.com/jaxxstorm/sentinel/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -29,12 +33,25 @@ type Runner struct {
 	Diff       *diff.Engine
 	Policy     *policy.Engine
 	Notifier   *notify.Notifier
+	Severity   *severity.Classifier
 	Enrollment onboarding.EnrollmentManager
 	State      state.StateStore
-	Metrics    *metrics.Metrics
-	Log        *zap.Logger
-	Now        func() time.Time
-	Sleep      func(time.Duration)
+	// DetectorState stages the state stateful detectors save during a
+	// cycle. It is committed once the cycle's events are delivered and
+	// discarded when the cycle fails. Nil when detectors write directly to
+	// State.
+	DetectorState *diff.StagedStateStore
+	Metrics       *metrics.Metrics
+	Log           *zap.Logger
+	Now           func() time.Time
+	Sleep         func(time.Duration)
+
+	readyMu       sync.RWMutex
+	joined        bool
+	snapshotSaved bool
+
+	conn connectivity
+	live liveness
 }
 
 type CycleResult struct {
@@ -66,6 +83,11 @@ func (r *Runner) Run(ctx context.Context, once bool, dryRun bool) error {
 		backoff = 500 * time.Millisecond
 	}
 	realtimeMode := strings.EqualFold(strings.TrimSpace(r.Cfg.Source.Mode), "realtime")
+	if r.Cfg.Catchup.Threshold > 0 {
+		heartbeatCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go r.heartbeat(heartbeatCtx)
+	}
 	for {
 		_, err := r.RunOnce(ctx, dryRun)
 		if err != nil {
@@ -111,7 +133,54 @@ func (r *Runner) Run(ctx context.Context, once bool, dryRun bool) error {
 	}
 }
 
+// Ready reports nil once the node has joined the tailnet and the runner has
+// persisted its first snapshot.
+func (r *Runner) Ready() error {
+	r.readyMu.RLock()
+	defer r.readyMu.RUnlock()
+	if r.Enrollment != nil && !r.joined {
+		return errors.New("tailscale enrollment not complete")
+	}
+	if !r.snapshotSaved {
+		return errors.New("no snapshot saved yet")
+	}
+	return nil
+}
+
+func (r *Runner) setJoined(joined bool) {
+	r.readyMu.Lock()
+	r.joined = joined
+	r.readyMu.Unlock()
+}
+
+func (r *Runner) markSnapshotSaved() {
+	r.readyMu.Lock()
+	r.snapshotSaved = true
+	r.readyMu.Unlock()
+}
+
+// observeInventory refreshes the tailnet inventory gauges. It also runs for
+// unchanged snapshots so key expiry horizons track the passage of time.
+func (r *Runner) observeInventory(s snapshot.Snapshot) {
+	if r.Metrics == nil {
+		return
+	}
+	r.Metrics.ObserveSnapshot(s, r.Now(), r.Cfg.Metrics.KeyExpiryHorizons)
+}
+
 func (r *Runner) RunOnce(ctx context.Context, dryRun bool) (CycleResult, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "sentinel.RunOnce", trace.WithAttributes(tracing.AttrDryRun.Bool(dryRun)))
+	res, err := r.runOnce(ctx, dryRun)
+	if err != nil && r.DetectorState != nil {
+		// Undelivered events are detected again next cycle.
+		r.DetectorState.Discard()
+	}
+	span.SetAttributes(tracing.EventAttributes(res.Events)...)
+	tracing.EndSpan(span, err)
+	return res, err
+}
+
+func (r *Runner) runOnce(ctx context.Context, dryRun bool) (CycleResult, error) {
 	start := r.Now()
 	res := CycleResult{}
 	if r.Enrollment != nil {
@@ -123,8 +192,10 @@ func (r *Runner) RunOnce(ctx context.Context, dryRun bool) (CycleResult, error)
 			} else {
 				r.Log.Error("tailscale enrollment failed", enrollmentLogFields(enrollmentStatus)...)
 			}
+			r.setJoined(false)
 			return res, fmt.Errorf("enrollment: %w", err)
 		}
+		r.setJoined(enrollmentStatus.Joined())
 		if enrollmentStatusChanged(previousEnrollmentStatus, enrollmentStatus) {
 			r.Log.Info("tailscale enrollment complete",
 				zap.String("status", string(enrollmentStatus.State)),
@@ -135,7 +206,7 @@ func (r *Runner) RunOnce(ctx context.Context, dryRun bool) (CycleResult, error)
 		}
 	}
 
-	nm, err := r.Source.Poll(ctx)
+	nm, err := r.poll(ctx)
 	if err != nil {
 		return res, fmt.Errorf("poll source: %w", err)
 	}
@@ -144,7 +215,10 @@ func (r *Runner) RunOnce(ctx context.Context, dryRun bool) (CycleResult, error)
 		r.Metrics.NetmapPollDurationSeconds.Observe(time.Since(start).Seconds())
 	}
 
+	_, normalizeSpan := tracing.Tracer().Start(ctx, "snapshot.Normalize")
 	current := snapshot.Normalize(nm, r.Now())
+	normalizeSpan.SetAttributes(tracing.AttrPeerCount.Int(len(current.Peers)))
+	normalizeSpan.End()
 	previous, err := r.State.LoadSnapshot()
 	if err != nil && !errors.Is(err, state.ErrNoSnapshot) && !errors.Is(err, os.ErrNotExist) && !errors.Is(err, context.Canceled) {
 		if r.Metrics != nil {
@@ -157,20 +231,120 @@ func (r *Runner) RunOnce(ctx context.Context, dryRun bool) (CycleResult, error)
 		zap.String("current_hash", current.Hash),
 		zap.String("previous_hash", previous.Hash),
 	)
-	if previous.Hash != "" && previous.Hash == current.Hash {
-		r.Log.Debug("no-op netmap update detected")
+	wasDegraded, lostSince := r.conn.degraded, r.conn.since
+	connEvents := r.conn.observe(previous, current, r.Enrollment, r.Now().UTC())
+	switch {
+	case r.conn.degraded && !wasDegraded:
+		r.Log.Warn("sentinel connectivity degraded, holding back detection",
+			zap.String("reason", r.conn.reason),
+			zap.String("daemon_state", current.DaemonState),
+			zap.String("last_error", current.LastErrorText),
+		)
+	case !r.conn.degraded && wasDegraded:
+		r.Log.Info("sentinel connectivity restored, reconciling against last healthy snapshot",
+			zap.String("previous_hash", previous.Hash),
+		)
+	}
+	if len(connEvents) > 0 {
+		if err := r.process(ctx, r.Severity.Classify(connEvents), dryRun, &res); err != nil {
+			return res, err
+		}
+	}
+	if r.conn.degraded {
+		// Peers look offline or removed while Sentinel's own node is down,
+		// so detection waits for a healthy snapshot to reconcile against.
+		r.Log.Debug("skipping detection while connectivity is degraded", zap.String("reason", r.conn.reason))
+		r.setWatching(false, r.Now())
 		return res, nil
 	}
-
+	away := r.awayFor(r.Now(), lostSince, wasDegraded)
+	if r.Cfg.Catchup.Threshold > 0 {
+		r.setWatching(true, r.Now())
+	}
 	enabled := map[string]bool{}
 	for name, detector := range r.Cfg.Detectors {
 		enabled[name] = detector.Enabled
 	}
+	if previous.Hash != "" && previous.Hash == current.Hash {
+		r.Log.Debug("no-op netmap update detected")
+		// Time-based detectors still run so thresholds are crossed on time.
+		events, err := r.Diff.Tick(ctx, current, r.Cfg.DetectorOrder, enabled)
+		if err != nil {
+			return res, err
+		}
+		if len(events) > 0 {
+			if err := r.process(ctx, r.Severity.Classify(events), dryRun, &res); err != nil {
+				return res, err
+			}
+		}
+		if err := r.commitDetectorState(); err != nil {
+			return res, err
+		}
+		r.observeInventory(current)
+		r.markSnapshotSaved()
+		return res, nil
+	}
+
 	events, err := r.Diff.Diff(ctx, previous, current, r.Cfg.DetectorOrder, enabled)
 	if err != nil {
 		return res, err
 	}
-	res.Events = events
+	if previous.Hash == "" && r.Cfg.Bootstrap != config.BootstrapFull && r.Cfg.Bootstrap != "" {
+		detected := len(events)
+		events = bootstrapEvents(r.Cfg.Bootstrap, current, events, r.Now().UTC())
+		r.Log.Info("no stored snapshot, capturing baseline",
+			zap.String("bootstrap", r.Cfg.Bootstrap),
+			zap.Int("peer_count", len(current.Peers)),
+			zap.Int("detected", detected),
+			zap.Int("sent", len(events)),
+		)
+	}
+	if previous.Hash != "" && r.Cfg.Catchup.Threshold > 0 && away >= r.Cfg.Catchup.Threshold && len(events) > 0 {
+		r.Log.Info("sentinel was away, sending catch-up summary",
+			zap.Duration("downtime", away),
+			zap.Int("changes", len(events)),
+		)
+		now := r.Now().UTC()
+		events = []event.Event{catchupEvent(previous, current, events, now.Add(-away), now)}
+	}
+	if err := r.process(ctx, r.Severity.Classify(events), dryRun, &res); err != nil {
+		return res, err
+	}
+	if err := r.commitDetectorState(); err != nil {
+		return res, err
+	}
+
+	if err := r.State.SaveSnapshot(current); err != nil {
+		if r.Metrics != nil {
+			r.Metrics.StateStoreErrorsTotal.Inc()
+		}
+		return res, fmt.Errorf("save snapshot: %w", err)
+	}
+	r.observeInventory(current)
+	r.markSnapshotSaved()
+
+	return res, nil
+}
+
+// commitDetectorState persists the detector state staged during a cycle
+// whose events were delivered.
+func (r *Runner) commitDetectorState() error {
+	if r.DetectorState == nil {
+		return nil
+	}
+	if err := r.DetectorState.Commit(); err != nil {
+		if r.Metrics != nil {
+			r.Metrics.StateStoreErrorsTotal.Inc()
+		}
+		return fmt.Errorf("commit detector state: %w", err)
+	}
+	return nil
+}
+
+// process logs and counts detected events, applies policy and sends the
+// resulting batches, accumulating counts into res.
+func (r *Runner) process(ctx context.Context, events []event.Event, dryRun bool, res *CycleResult) error {
+	res.Events = append(res.Events, events...)
 	if len(events) > 0 {
 		r.Log.Info("netmap diffs detected", zap.Int("events", len(events)))
 		for _, evt := range events {
@@ -192,9 +366,9 @@ func (r *Runner) RunOnce(ctx context.Context, dryRun bool) (CycleResult, error)
 		}
 	}
 
-	policyResult, err := r.Policy.Apply(events)
+	policyResult, err := r.applyPolicy(ctx, events)
 	if err != nil {
-		return res, fmt.Errorf("apply policy: %w", err)
+		return fmt.Errorf("apply policy: %w", err)
 	}
 	r.Log.Debug("policy evaluation complete",
 		zap.Int("events_in", len(events)),
@@ -206,30 +380,37 @@ func (r *Runner) RunOnce(ctx context.Context, dryRun bool) (CycleResult, error)
 			r.Metrics.NotificationsSuppressed.WithLabelValues(sup.Reason).Inc()
 		}
 	}
-	res.SuppressedCount = len(policyResult.Suppressed)
+	res.SuppressedCount += len(policyResult.Suppressed)
 
 	for _, batch := range policyResult.Batches {
 		notifyResult, err := r.Notifier.Notify(ctx, batch, dryRun)
 		if err != nil {
-			return res, fmt.Errorf("notify: %w", err)
+			return fmt.Errorf("notify: %w", err)
 		}
 		res.SentCount += notifyResult.Sent
 		res.DryRunCount += notifyResult.DryRun
-		for i := 0; i < notifyResult.Sent; i++ {
-			if r.Metrics != nil {
-				r.Metrics.NotificationsSentTotal.WithLabelValues("webhook").Inc()
-			}
-		}
 	}
+	return nil
+}
 
-	if err := r.State.SaveSnapshot(current); err != nil {
-		if r.Metrics != nil {
-			r.Metrics.StateStoreErrorsTotal.Inc()
-		}
-		return res, fmt.Errorf("save snapshot: %w", err)
+func (r *Runner) poll(ctx context.Context) (source.Netmap, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "source.Poll")
+	nm, err := r.Source.Poll(ctx)
+	if err == nil {
+		span.SetAttributes(tracing.AttrPeerCount.Int(len(nm.Peers)))
 	}
+	tracing.EndSpan(span, err)
+	return nm, err
+}
 
-	return res, nil
+func (r *Runner) applyPolicy(ctx context.Context, events []event.Event) (policy.Result, error) {
+	_, span := tracing.Tracer().Start(ctx, "policy.Apply", trace.WithAttributes(tracing.EventAttributes(events)...))
+	res, err := r.Policy.Apply(events)
+	if err == nil {
+		span.SetAttributes(tracing.AttrSuppressed.Int(len(res.Suppressed)))
+	}
+	tracing.EndSpan(span, err)
+	return res, err
 }
 
 func jitter(max time.Duration) time.Duration {
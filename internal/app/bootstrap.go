@@ -0,0 +1,56 @@
+package app
+
+import (
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/config"
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+// bootstrapEvents replaces the events of the first cycle without a stored
+// snapshot according to mode. Only the peer.added and peer.online events
+// presence and peer_changes report for every peer against the empty snapshot
+// are held back; events of stateful and time-based detectors, such as a key
+// already inside a key_expiry threshold, are sent in every mode because their
+// state is committed with the cycle.
+func bootstrapEvents(mode string, current snapshot.Snapshot, events []event.Event, now time.Time) []event.Event {
+	if mode != config.BootstrapSilent && mode != config.BootstrapSummary {
+		return events
+	}
+	kept := make([]event.Event, 0, len(events))
+	for _, evt := range events {
+		if evt.EventType != event.TypePeerAdded && evt.EventType != event.TypePeerOnline {
+			kept = append(kept, evt)
+		}
+	}
+	if mode == config.BootstrapSummary {
+		kept = append([]event.Event{baselineEvent(current, len(events)-len(kept), now)}, kept...)
+	}
+	return kept
+}
+
+// baselineEvent summarizes the inventory of the first recorded snapshot.
+func baselineEvent(s snapshot.Snapshot, heldBack int, now time.Time) event.Event {
+	online, routers := 0, 0
+	tags := map[string]int{}
+	for _, p := range s.Peers {
+		if p.Online {
+			online++
+		}
+		if len(p.Routes) > 0 || len(p.AdvertisedRoutes) > 0 {
+			routers++
+		}
+		for _, tag := range p.Tags {
+			tags[tag]++
+		}
+	}
+	return event.NewSentinelEvent(event.TypeSentinelBaselineCaptured, "self", "", s.Hash, map[string]any{
+		"peers":          len(s.Peers),
+		"online":         online,
+		"offline":        len(s.Peers) - online,
+		"subnet_routers": routers,
+		"tags":           tags,
+		"held_back":      heldBack,
+	}, now)
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+// catchupEvent groups the events of the first diff after downtime into one
+// sentinel.catchup event, so changes that happened while Sentinel was away
+// since awaySince are not sent as if they had just happened.
+func catchupEvent(previous, current snapshot.Snapshot, events []event.Event, awaySince, now time.Time) event.Event {
+	byType := map[string][]string{}
+	for _, evt := range events {
+		subject := evt.SubjectID
+		if name, ok := evt.Payload["name"].(string); ok && name != "" {
+			subject = name
+		}
+		byType[evt.EventType] = append(byType[evt.EventType], subject)
+	}
+	types := make([]string, 0, len(byType))
+	for eventType := range byType {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+	changes := make([]map[string]any, 0, len(types))
+	for _, eventType := range types {
+		subjects := byType[eventType]
+		sort.Strings(subjects)
+		changes = append(changes, map[string]any{
+			"event_type": eventType,
+			"count":      len(subjects),
+			"subjects":   subjects,
+		})
+	}
+	return event.NewSentinelEvent(event.TypeSentinelCatchup, "self", previous.Hash, current.Hash, map[string]any{
+		"since":    awaySince.UTC().Format(time.RFC3339),
+		"downtime": now.Sub(awaySince).Round(time.Second).String(),
+		"total":    len(events),
+		"changes":  changes,
+	}, now)
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/onboarding"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+// Reasons Sentinel reports its own node as degraded.
+const (
+	ConnectivityReasonDaemonState = "daemon_state"
+	ConnectivityReasonIPNError    = "ipn_error"
+	ConnectivityReasonNotJoined   = "not_joined"
+)
+
+// daemonStateRunning is the ipn.State of a healthy tailscaled backend.
+const daemonStateRunning = "Running"
+
+// connectivity tracks Sentinel's own health across cycles. While degraded,
+// the runner neither diffs nor saves snapshots, so peers that only look
+// offline or removed from Sentinel's side produce no events, and the first
+// healthy snapshot is diffed against the last healthy one.
+type connectivity struct {
+	// seenHealthy is set after the first healthy cycle, so startup does not
+	// report a loss before the node is up.
+	seenHealthy bool
+	degraded    bool
+	reason      string
+	since       time.Time
+}
+
+// selfHealth reports why Sentinel's own node is degraded, if it is. An empty
+// daemon state is treated as healthy for sources that do not report one.
+func selfHealth(s snapshot.Snapshot, enrollment onboarding.EnrollmentManager) (string, bool) {
+	if enrollment != nil && !enrollment.LastStatus().Joined() {
+		return ConnectivityReasonNotJoined, true
+	}
+	if state := strings.TrimSpace(s.DaemonState); state != "" && state != daemonStateRunning {
+		return ConnectivityReasonDaemonState, true
+	}
+	if strings.TrimSpace(s.LastErrorText) != "" {
+		return ConnectivityReasonIPNError, true
+	}
+	return "", false
+}
+
+// observe records the health of current and returns the
+// sentinel.connectivity.lost or .restored event for a transition.
+func (c *connectivity) observe(previous, current snapshot.Snapshot, enrollment onboarding.EnrollmentManager, now time.Time) []event.Event {
+	reason, degraded := selfHealth(current, enrollment)
+	switch {
+	case degraded && !c.degraded:
+		c.degraded, c.reason, c.since = true, reason, now
+		if !c.seenHealthy {
+			return nil
+		}
+		evt := event.NewSentinelEvent(event.TypeSentinelConnectivityLost, "self", previous.Hash, current.Hash, map[string]any{
+			"reason":       reason,
+			"daemon_state": current.DaemonState,
+			"last_error":   current.LastErrorText,
+		}, now)
+		evt.EventID = event.DeriveScopedEventID(evt, now.Format(time.RFC3339Nano))
+		return []event.Event{evt}
+	case !degraded && c.degraded:
+		wasSeen := c.seenHealthy
+		c.degraded, c.seenHealthy = false, true
+		if !wasSeen {
+			return nil
+		}
+		evt := event.NewSentinelEvent(event.TypeSentinelConnectivityRestored, "self", previous.Hash, current.Hash, map[string]any{
+			"reason":     c.reason,
+			"lost_since": c.since.Format(time.RFC3339),
+			"duration":   now.Sub(c.since).Round(time.Second).String(),
+		}, now)
+		evt.EventID = event.DeriveScopedEventID(evt, c.since.Format(time.RFC3339Nano))
+		return []event.Event{evt}
+	case !degraded:
+		c.seenHealthy = true
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// livenessInterval is how often the runner records that it is running and
+// can see the tailnet. It bounds how much a restart overstates downtime.
+const livenessInterval = time.Minute
+
+// liveness tracks when Sentinel last watched the tailnet, independently of
+// snapshots: in realtime mode a quiet IPN bus can leave the stored snapshot
+// hours old while Sentinel is running.
+type liveness struct {
+	mu sync.Mutex
+	// loaded is set once the last run recorded by an earlier process has
+	// been read into previous, before anything overwrites it.
+	loaded   bool
+	previous time.Time
+	// checked is set after the first healthy cycle has measured downtime
+	// against previous.
+	checked bool
+	// watching is set while the last cycle was healthy, so the heartbeat
+	// does not record time Sentinel could not see the tailnet.
+	watching bool
+	recorded time.Time
+}
+
+// loadLiveness reads the last run of an earlier process once.
+func (r *Runner) loadLiveness() {
+	if r.live.loaded {
+		return
+	}
+	r.live.loaded = true
+	previous, err := r.State.LoadLastRun()
+	if err != nil {
+		r.Log.Warn("failed to load last run time", zap.Error(err))
+		return
+	}
+	r.live.previous = previous
+}
+
+// awayFor returns how long Sentinel could not watch the tailnet before this
+// healthy cycle: the time since an earlier process last ran on the first
+// cycle, or the length of a connectivity outage that just ended. It is zero
+// otherwise, however long the netmap stayed unchanged.
+func (r *Runner) awayFor(now, lostSince time.Time, restored bool) time.Duration {
+	r.live.mu.Lock()
+	defer r.live.mu.Unlock()
+	r.loadLiveness()
+	var away time.Duration
+	if !r.live.checked && !r.live.previous.IsZero() {
+		away = now.Sub(r.live.previous)
+	}
+	r.live.checked = true
+	if restored && now.Sub(lostSince) > away {
+		away = now.Sub(lostSince)
+	}
+	return away
+}
+
+// setWatching records whether the last cycle could see the tailnet and, when
+// it could, notes that Sentinel is running.
+func (r *Runner) setWatching(watching bool, now time.Time) {
+	r.live.mu.Lock()
+	r.live.watching = watching
+	r.live.mu.Unlock()
+	if watching {
+		r.recordLiveness(now, false)
+	}
+}
+
+// recordLiveness persists now as the last run, at most once per
+// livenessInterval unless force is set.
+func (r *Runner) recordLiveness(now time.Time, force bool) {
+	r.live.mu.Lock()
+	defer r.live.mu.Unlock()
+	r.loadLiveness()
+	if !r.live.watching || (!force && !r.live.recorded.IsZero() && now.Sub(r.live.recorded) < livenessInterval) {
+		return
+	}
+	if err := r.State.SaveLastRun(now); err != nil {
+		if r.Metrics != nil {
+			r.Metrics.StateStoreErrorsTotal.Inc()
+		}
+		r.Log.Warn("failed to record last run time", zap.Error(err))
+		return
+	}
+	r.live.recorded = now
+}
+
+// heartbeat records liveness every livenessInterval while the source blocks
+// waiting for changes, until ctx is done.
+func (r *Runner) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(livenessInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.recordLiveness(r.Now(), true)
+		}
+	}
+}
@@ -16,6 +16,12 @@ import (
 	"github.com/jaxxstorm/sentinel/internal/policy"
 	"github.com/jaxxstorm/sentinel/internal/source"
 	"github.com/jaxxstorm/sentinel/internal/state"
+	"github.com/jaxxstorm/sentinel/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
@@ -31,6 +37,22 @@ func (f *fakeSink) Send(context.Context, notify.Notification) error {
 	return nil
 }
 
+// flakySink fails its next failures sends, then succeeds.
+type flakySink struct {
+	failures int
+	sent     int
+}
+
+func (f *flakySink) Name() string { return "webhook-primary" }
+func (f *flakySink) Send(context.Context, notify.Notification) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("sink unavailable")
+	}
+	f.sent++
+	return nil
+}
+
 type fakeEnrollmentManager struct {
 	ensure func(context.Context) (onboarding.Status, error)
 	last   onboarding.Status
@@ -253,3 +275,446 @@ func TestRunOnceEnrollmentCompleteLogsOnlyOnStatusChange(t *testing.T) {
 		}
 	}
 }
+
+func TestRunnerReadyAfterEnrollmentAndFirstSnapshot(t *testing.T) {
+	cfg := config.Default()
+	configurePresenceOnly(&cfg)
+	store := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+	em := &fakeEnrollmentManager{}
+	r := NewRunner(
+		cfg,
+		source.NewStaticSource(source.Netmap{Peers: []source.Peer{{ID: "peer1", Name: "peer1", Online: true}}}),
+		diff.NewEngine([]diff.Detector{diff.NewPresenceDetector()}),
+		policy.NewEngine(policy.Config{BatchSize: 1}),
+		notify.New(notify.Config{}, store, nil),
+		store,
+		nil,
+		zap.NewNop(),
+		em,
+	)
+	if err := r.Ready(); err == nil {
+		t.Fatal("expected runner to be unready before first cycle")
+	}
+	if _, err := r.RunOnce(context.Background(), true); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Ready(); err != nil {
+		t.Fatalf("expected runner to be ready after first snapshot, got %v", err)
+	}
+
+	em.ensure = func(context.Context) (onboarding.Status, error) {
+		return onboarding.Status{State: onboarding.StateAuthFailed}, errors.New("enrollment failed")
+	}
+	if _, err := r.RunOnce(context.Background(), true); err == nil {
+		t.Fatal("expected enrollment error")
+	}
+	if err := r.Ready(); err == nil {
+		t.Fatal("expected runner to be unready after enrollment failure")
+	}
+}
+
+func TestRunOnceTracesPipelineStages(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	cfg := config.Default()
+	configurePresenceOnly(&cfg)
+	store := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+	nsink := &fakeSink{}
+	r := NewRunner(
+		cfg,
+		source.NewStaticSource(source.Netmap{Peers: []source.Peer{{ID: "peer1", Name: "peer1", Online: true}}}),
+		diff.NewEngine([]diff.Detector{diff.NewPresenceDetector()}),
+		policy.NewEngine(policy.Config{BatchSize: 10}),
+		notify.New(notify.Config{Routes: []notify.Route{{EventTypes: []string{"*"}, Sinks: []string{"webhook-primary"}}}}, store, []notify.Sink{nsink}),
+		store,
+		nil,
+		zap.NewNop(),
+		nil,
+	)
+	res, err := r.RunOnce(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) == 0 || nsink.sent == 0 {
+		t.Fatalf("expected events to be delivered, got events=%d sent=%d", len(res.Events), nsink.sent)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	root, ok := spans["sentinel.RunOnce"]
+	if !ok {
+		t.Fatalf("expected sentinel.RunOnce span, got %v", spans)
+	}
+	for _, name := range []string{"source.Poll", "snapshot.Normalize", "diff.Detect", "policy.Apply", "notify.Send"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Fatalf("expected %s span", name)
+		}
+		if span.SpanContext().TraceID() != root.SpanContext().TraceID() {
+			t.Fatalf("expected %s span in the RunOnce trace", name)
+		}
+	}
+	send := spans["notify.Send"]
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range send.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if got := attrs[tracing.AttrEventID].AsString(); got != res.Events[0].EventID {
+		t.Fatalf("expected notify.Send event id %q, got %q", res.Events[0].EventID, got)
+	}
+	if got := attrs[tracing.AttrIdempotencyKey].AsString(); got != event.DeriveIdempotencyKey(res.Events[0]) {
+		t.Fatalf("expected notify.Send idempotency key, got %q", got)
+	}
+	if got := attrs[tracing.AttrSink].AsString(); got != "webhook-primary" {
+		t.Fatalf("expected notify.Send sink attribute, got %q", got)
+	}
+}
+
+func TestRunOnceRunsTickDetectorsOnUnchangedSnapshot(t *testing.T) {
+	cfg := config.Default()
+	cfg.DetectorOrder = []string{"key_expiry"}
+	cfg.Detectors = map[string]config.Detector{"key_expiry": {Enabled: false}}
+	cfg.Notifier.Routes = []config.RouteConfig{{EventTypes: []string{"*"}, Sinks: []string{"webhook-primary"}}}
+
+	expiry := time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)
+	src := source.NewStaticSource(source.Netmap{Peers: []source.Peer{{ID: "server1", Name: "server1", Online: true, KeyExpiry: expiry}}})
+	store := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+	nsink := &fakeSink{}
+	n := notify.New(notify.Config{Routes: []notify.Route{{EventTypes: []string{"*"}, Sinks: []string{"webhook-primary"}}}, IdempotencyKeyTTL: time.Hour}, store, []notify.Sink{nsink})
+	r := NewRunner(
+		cfg,
+		src,
+		diff.NewEngine([]diff.Detector{diff.NewKeyExpiryDetector([]time.Duration{24 * time.Hour}, store)}),
+		policy.NewEngine(policy.Config{BatchSize: 10}),
+		n,
+		store,
+		nil,
+		zap.NewNop(),
+		nil,
+	)
+
+	if _, err := r.RunOnce(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	r.Cfg.Detectors["key_expiry"] = config.Detector{Enabled: true}
+	res, err := r.RunOnce(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) != 1 || res.Events[0].EventType != event.TypePeerKeyExpiryApproaching {
+		t.Fatalf("expected key expiry event on unchanged snapshot, got %#v", res.Events)
+	}
+	if nsink.sent != 1 {
+		t.Fatalf("expected one notification, got %d", nsink.sent)
+	}
+
+	res, err = r.RunOnce(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) != 0 {
+		t.Fatalf("expected threshold to fire once, got %#v", res.Events)
+	}
+}
+
+func TestRunOnceHoldsBackDetectionWhileConnectivityDegraded(t *testing.T) {
+	cfg := config.Default()
+	configurePresenceOnly(&cfg)
+	peers := func(online bool) []source.Peer {
+		return []source.Peer{{ID: "peer1", Name: "peer1", Online: online}, {ID: "peer2", Name: "peer2", Online: true}}
+	}
+	src := source.NewSequenceSource([]source.Netmap{
+		{DaemonState: "Running", Peers: peers(true)},
+		{DaemonState: "Starting"},
+		{DaemonState: "Running", ErrorMessage: "control unreachable", Peers: peers(false)},
+		{DaemonState: "Running", Peers: peers(false)},
+	})
+	store := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+	n := notify.New(notify.Config{IdempotencyKeyTTL: time.Hour}, store, nil)
+	r := NewRunner(
+		cfg,
+		src,
+		diff.NewEngine([]diff.Detector{diff.NewPresenceDetector()}),
+		policy.NewEngine(policy.Config{BatchSize: 10}),
+		n,
+		store,
+		nil,
+		zap.NewNop(),
+		nil,
+	)
+	ctx := context.Background()
+
+	if _, err := r.RunOnce(ctx, true); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := r.RunOnce(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) != 1 || res.Events[0].EventType != event.TypeSentinelConnectivityLost || res.Events[0].Payload["reason"] != ConnectivityReasonDaemonState {
+		t.Fatalf("expected only connectivity lost, got %#v", res.Events)
+	}
+
+	res, err = r.RunOnce(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) != 0 {
+		t.Fatalf("expected no events while still degraded, got %#v", res.Events)
+	}
+
+	res, err = r.RunOnce(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) != 2 {
+		t.Fatalf("expected restored plus reconciled presence, got %#v", res.Events)
+	}
+	if res.Events[0].EventType != event.TypeSentinelConnectivityRestored || res.Events[0].Payload["reason"] != ConnectivityReasonDaemonState {
+		t.Fatalf("expected connectivity restored first, got %#v", res.Events[0])
+	}
+	if res.Events[1].EventType != event.TypePeerOffline || res.Events[1].SubjectID != "peer1" {
+		t.Fatalf("expected only peer1 offline after reconciling, got %#v", res.Events[1])
+	}
+}
+
+func TestRunOnceStartupWhileDegradedIsQuiet(t *testing.T) {
+	cfg := config.Default()
+	configurePresenceOnly(&cfg)
+	src := source.NewSequenceSource([]source.Netmap{
+		{DaemonState: "NoState"},
+		{DaemonState: "Running", Peers: []source.Peer{{ID: "peer1", Name: "peer1", Online: true}}},
+	})
+	store := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+	n := notify.New(notify.Config{IdempotencyKeyTTL: time.Hour}, store, nil)
+	r := NewRunner(cfg, src, diff.NewEngine([]diff.Detector{diff.NewPresenceDetector()}), policy.NewEngine(policy.Config{BatchSize: 10}), n, store, nil, zap.NewNop(), nil)
+
+	res, err := r.RunOnce(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) != 0 {
+		t.Fatalf("expected no events before the node is up, got %#v", res.Events)
+	}
+	res, err = r.RunOnce(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, evt := range res.Events {
+		if evt.SubjectType == event.SubjectSentinel {
+			t.Fatalf("expected no connectivity events on startup, got %#v", res.Events)
+		}
+	}
+}
+
+func TestRunOnceBootstrapModes(t *testing.T) {
+	peers := []source.Peer{
+		{ID: "peer1", Name: "peer1", Online: true, Tags: []string{"tag:server"}, Routes: []string{"10.0.0.0/24"}},
+		{ID: "peer2", Name: "peer2", Online: false, Tags: []string{"tag:server"}},
+		{ID: "peer3", Name: "peer3", Online: true},
+	}
+	tests := []struct {
+		mode   string
+		expect func(t *testing.T, events []event.Event)
+	}{
+		{mode: config.BootstrapFull, expect: func(t *testing.T, events []event.Event) {
+			if len(events) == 0 {
+				t.Fatal("expected full bootstrap to report every peer")
+			}
+		}},
+		{mode: config.BootstrapSilent, expect: func(t *testing.T, events []event.Event) {
+			if len(events) != 0 {
+				t.Fatalf("expected silent bootstrap to emit nothing, got %#v", events)
+			}
+		}},
+		{mode: config.BootstrapSummary, expect: func(t *testing.T, events []event.Event) {
+			if len(events) != 1 || events[0].EventType != event.TypeSentinelBaselineCaptured {
+				t.Fatalf("expected one baseline event, got %#v", events)
+			}
+			p := events[0].Payload
+			if p["peers"] != 3 || p["online"] != 2 || p["offline"] != 1 || p["subnet_routers"] != 1 {
+				t.Fatalf("unexpected baseline counts: %#v", p)
+			}
+			if tags := p["tags"].(map[string]int); tags["tag:server"] != 2 {
+				t.Fatalf("unexpected tag counts: %#v", tags)
+			}
+			if held, _ := p["held_back"].(int); held == 0 {
+				t.Fatalf("expected held back events to be counted, got %#v", p)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Bootstrap = tt.mode
+			cfg.DetectorOrder = []string{"presence", "peer_changes"}
+			changed := append([]source.Peer(nil), peers...)
+			changed[1].Online = true
+			src := source.NewSequenceSource([]source.Netmap{{Peers: peers}, {Peers: changed}})
+			store := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+			n := notify.New(notify.Config{IdempotencyKeyTTL: time.Hour}, store, nil)
+			r := NewRunner(cfg, src, diff.NewEngine([]diff.Detector{diff.NewPresenceDetector(), diff.NewPeerChangeDetector()}), policy.NewEngine(policy.Config{BatchSize: 10}), n, store, nil, zap.NewNop(), nil)
+
+			res, err := r.RunOnce(context.Background(), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.expect(t, res.Events)
+
+			// Changes after the baseline are reported in every mode.
+			res, err = r.RunOnce(context.Background(), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Events) != 1 || res.Events[0].EventType != event.TypePeerOnline || res.Events[0].SubjectID != "peer2" {
+				t.Fatalf("expected peer2 online after baseline, got %#v", res.Events)
+			}
+		})
+	}
+}
+
+func TestRunOnceBootstrapSilentStillReportsCrossedKeyExpiry(t *testing.T) {
+	cfg := config.Default()
+	cfg.Bootstrap = config.BootstrapSilent
+	cfg.DetectorOrder = []string{"presence", "peer_changes", "key_expiry"}
+	cfg.Detectors = map[string]config.Detector{"presence": {Enabled: true}, "peer_changes": {Enabled: true}, "key_expiry": {Enabled: true}}
+
+	expiry := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	src := source.NewStaticSource(source.Netmap{Peers: []source.Peer{
+		{ID: "server1", Name: "server1", Online: true, KeyExpiry: expiry},
+		{ID: "laptop1", Name: "laptop1", Online: true},
+	}})
+	store := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+	detectorState := diff.NewStagedStateStore(store)
+	n := notify.New(notify.Config{IdempotencyKeyTTL: time.Hour}, store, nil)
+	engine := diff.NewEngine([]diff.Detector{
+		diff.NewPresenceDetector(),
+		diff.NewPeerChangeDetector(),
+		diff.NewKeyExpiryDetector([]time.Duration{7 * 24 * time.Hour, 48 * time.Hour}, detectorState),
+	})
+	r := NewRunner(cfg, src, engine, policy.NewEngine(policy.Config{BatchSize: 10}), n, store, nil, zap.NewNop(), nil)
+	r.DetectorState = detectorState
+
+	res, err := r.RunOnce(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) != 1 || res.Events[0].EventType != event.TypePeerKeyExpiryApproaching || res.Events[0].SubjectID != "server1" {
+		t.Fatalf("expected only the crossed key expiry threshold on a silent bootstrap, got %#v", res.Events)
+	}
+
+	res, err = r.RunOnce(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) != 0 {
+		t.Fatalf("expected the reported threshold to fire once, got %#v", res.Events)
+	}
+}
+
+func TestRunOnceSendsCatchupSummaryAfterDowntime(t *testing.T) {
+	cfg := config.Default()
+	cfg.DetectorOrder = []string{"presence", "peer_changes"}
+	cfg.Catchup.Threshold = time.Hour
+	src := source.NewSequenceSource([]source.Netmap{
+		{Peers: []source.Peer{{ID: "peer1", Name: "web", Online: true}, {ID: "peer2", Name: "db", Online: true}}},
+		{Peers: []source.Peer{{ID: "peer1", Name: "web", Online: false}, {ID: "peer2", Name: "db", Online: false}, {ID: "peer3", Name: "cache", Online: true}}},
+		{Peers: []source.Peer{{ID: "peer1", Name: "web", Online: true}, {ID: "peer2", Name: "db", Online: false}, {ID: "peer3", Name: "cache", Online: true}}},
+	})
+	store := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+	n := notify.New(notify.Config{IdempotencyKeyTTL: time.Hour}, store, nil)
+	newRunner := func() *Runner {
+		return NewRunner(cfg, src, diff.NewEngine([]diff.Detector{diff.NewPresenceDetector(), diff.NewPeerChangeDetector()}), policy.NewEngine(policy.Config{BatchSize: 10}), n, store, nil, zap.NewNop(), nil)
+	}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := newRunner()
+	r.Now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := r.RunOnce(ctx, true); err != nil {
+		t.Fatal(err)
+	}
+	// The heartbeat keeps the last run current while the netmap is quiet.
+	now = now.Add(40 * time.Minute)
+	r.recordLiveness(now, true)
+
+	// Sentinel restarts three hours later.
+	now = now.Add(3 * time.Hour)
+	r = newRunner()
+	r.Now = func() time.Time { return now }
+	res, err := r.RunOnce(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) != 1 || res.Events[0].EventType != event.TypeSentinelCatchup {
+		t.Fatalf("expected one catch-up event, got %#v", res.Events)
+	}
+	p := res.Events[0].Payload
+	if p["downtime"] != "3h0m0s" || p["total"] != 4 || p["since"] != "2026-01-01T00:40:00Z" {
+		t.Fatalf("unexpected catch-up payload: %#v", p)
+	}
+	changes := p["changes"].([]map[string]any)
+	if len(changes) != 3 || changes[0]["event_type"] != event.TypePeerAdded || changes[1]["event_type"] != event.TypePeerOffline || changes[2]["event_type"] != event.TypePeerOnline {
+		t.Fatalf("expected changes grouped by type, got %#v", changes)
+	}
+	if subjects := changes[1]["subjects"].([]string); changes[1]["count"] != 2 || subjects[0] != "db" || subjects[1] != "web" {
+		t.Fatalf("unexpected offline group: %#v", changes[1])
+	}
+
+	// A change after a long quiet period in the same process is a realtime
+	// alert, not a catch-up.
+	now = now.Add(2 * time.Hour)
+	res, err = r.RunOnce(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) != 1 || res.Events[0].EventType != event.TypePeerOnline {
+		t.Fatalf("expected realtime alerts while running, got %#v", res.Events)
+	}
+}
+
+func TestRunOnceReemitsThresholdAlertAfterSinkFailure(t *testing.T) {
+	cfg := config.Default()
+	cfg.DetectorOrder = []string{"key_expiry"}
+	cfg.Detectors = map[string]config.Detector{"key_expiry": {Enabled: true}}
+
+	expiry := time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)
+	src := source.NewStaticSource(source.Netmap{Peers: []source.Peer{{ID: "server1", Name: "server1", Online: true, KeyExpiry: expiry}}})
+	store := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+	detectorState := diff.NewStagedStateStore(store)
+	nsink := &flakySink{failures: 1}
+	n := notify.New(notify.Config{Routes: []notify.Route{{EventTypes: []string{"*"}, Sinks: []string{"webhook-primary"}}}, IdempotencyKeyTTL: time.Hour}, store, []notify.Sink{nsink})
+	r := NewRunner(cfg, src, diff.NewEngine([]diff.Detector{diff.NewKeyExpiryDetector([]time.Duration{24 * time.Hour}, detectorState)}), policy.NewEngine(policy.Config{BatchSize: 10}), n, store, nil, zap.NewNop(), nil)
+	r.DetectorState = detectorState
+
+	if _, err := r.RunOnce(context.Background(), false); err == nil {
+		t.Fatal("expected the failing sink to fail the cycle")
+	}
+	if raw, err := store.LoadDetectorState("key_expiry"); err != nil || len(raw) != 0 {
+		t.Fatalf("expected no fired threshold stored for an undelivered alert, got %s, %v", raw, err)
+	}
+
+	res, err := r.RunOnce(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) != 1 || res.Events[0].EventType != event.TypePeerKeyExpiryApproaching {
+		t.Fatalf("expected the alert to be emitted again, got %#v", res.Events)
+	}
+	if nsink.sent != 1 {
+		t.Fatalf("expected one delivered notification, got %d", nsink.sent)
+	}
+
+	res, err = r.RunOnce(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) != 0 {
+		t.Fatalf("expected the delivered threshold to fire once, got %#v", res.Events)
+	}
+}
@@ -15,6 +15,7 @@ func newDiffCmd(opts *GlobalOptions) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			defer deps.flushTracing()
 			var resEvents string
 			err = runOnceWithTimeout(context.Background(), func(ctx context.Context) error {
 				res, err := deps.runner.RunOnce(ctx, true)
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/inventory"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+func newDriftCmd(opts *GlobalOptions) *cobra.Command {
+	var format string
+	var inventoryPath string
+	cmd := &cobra.Command{
+		Use:   "drift",
+		Short: "Compare the current tailnet with the declared inventory",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("--format must be table or json")
+			}
+			deps, err := buildRuntime(opts)
+			if err != nil {
+				return err
+			}
+			defer deps.flushTracing()
+			inv := deps.inventory
+			switch {
+			case inventoryPath != "":
+				if inv, err = inventory.Load(inventoryPath); err != nil {
+					return err
+				}
+			case strings.TrimSpace(deps.detectors.Drift.Inventory) == "":
+				return fmt.Errorf("no inventory configured: set detectors.drift.inventory or --inventory")
+			}
+			if deps.enrollment != nil {
+				if _, err := deps.enrollment.EnsureEnrolled(context.Background()); err != nil {
+					return err
+				}
+			}
+			var current snapshot.Snapshot
+			err = runOnceWithTimeout(context.Background(), func(ctx context.Context) error {
+				nm, err := deps.source.Poll(ctx)
+				if err != nil {
+					return err
+				}
+				current = snapshot.Normalize(nm, time.Now().UTC())
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			return writeDriftReport(cmd.OutOrStdout(), inventory.Compare(inv, current), format)
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table|json")
+	cmd.Flags().StringVar(&inventoryPath, "inventory", "", "Inventory file to compare against (overrides detectors.drift.inventory)")
+	return cmd
+}
+
+func writeDriftReport(w io.Writer, findings []inventory.Finding, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(findings)
+	}
+	if len(findings) == 0 {
+		_, err := fmt.Fprintln(w, "No drift detected")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DEVICE\tKIND\tPEER\tDETAIL")
+	for _, f := range findings {
+		peer := f.PeerID
+		if peer == "" {
+			peer = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Device, f.Kind, peer, driftDetail(f))
+	}
+	return tw.Flush()
+}
+
+func driftDetail(f inventory.Finding) string {
+	switch f.Kind {
+	case inventory.KindMissingDevice:
+		return f.Reason
+	case inventory.KindUnexpectedDevice:
+		return "not in inventory"
+	}
+	parts := make([]string, 0, 2)
+	if len(f.Missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(f.Missing, ","))
+	}
+	if len(f.Extra) > 0 {
+		parts = append(parts, "extra "+strings.Join(f.Extra, ","))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jaxxstorm/sentinel/internal/inventory"
+)
+
+func TestWriteDriftReportTable(t *testing.T) {
+	findings := []inventory.Finding{
+		{Kind: inventory.KindMissingDevice, Device: "db-1", Reason: inventory.ReasonAbsent},
+		{Kind: inventory.KindTagsMismatch, Device: "router-1", PeerID: "n1", Missing: []string{"tag:router"}, Extra: []string{"tag:old"}},
+	}
+	var out bytes.Buffer
+	if err := writeDriftReport(&out, findings, "table"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "DEVICE") {
+		t.Fatalf("unexpected table: %q", out.String())
+	}
+	if !strings.Contains(lines[1], "missing_device") || !strings.Contains(lines[1], "absent") {
+		t.Fatalf("unexpected missing row: %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "missing tag:router; extra tag:old") {
+		t.Fatalf("unexpected mismatch row: %q", lines[2])
+	}
+
+	out.Reset()
+	if err := writeDriftReport(&out, nil, "table"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out.String()) != "No drift detected" {
+		t.Fatalf("unexpected empty report: %q", out.String())
+	}
+}
+
+func TestWriteDriftReportJSON(t *testing.T) {
+	var out bytes.Buffer
+	findings := []inventory.Finding{{Kind: inventory.KindUnexpectedDevice, Device: "stray", PeerID: "n9"}}
+	if err := writeDriftReport(&out, findings, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid json %q: %v", out.String(), err)
+	}
+	if len(decoded) != 1 || decoded[0]["kind"] != "unexpected_device" || decoded[0]["peer_id"] != "n9" {
+		t.Fatalf("unexpected json report: %v", decoded)
+	}
+}
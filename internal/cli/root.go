@@ -16,6 +16,7 @@ type GlobalOptions struct {
 	TailscaleStateDir         string
 	TailscaleLoginTimeout     time.Duration
 	TailscaleFallbackOverride bool
+	ListenAddr                string
 }
 
 func NewRootCommand() *cobra.Command {
@@ -37,8 +38,11 @@ func NewRootCommand() *cobra.Command {
 	cmd.AddCommand(newRunCmd(opts))
 	cmd.AddCommand(newStatusCmd(opts))
 	cmd.AddCommand(newDiffCmd(opts))
+	cmd.AddCommand(newDriftCmd(opts))
+	cmd.AddCommand(newVersionsCmd(opts))
 	cmd.AddCommand(newDumpNetmapCmd(opts))
 	cmd.AddCommand(newTestNotifyCmd(opts))
+	cmd.AddCommand(newSchemaCmd(opts))
 	cmd.AddCommand(newValidateConfigCmd(opts))
 	cmd.AddCommand(newVersionCmd(opts))
 	return cmd
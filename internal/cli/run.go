@@ -23,6 +23,7 @@ func newRunCmd(opts *GlobalOptions) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			defer deps.flushTracing()
 			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 			defer stop()
 			deps.runner.Log.Info("running sentinel", zap.String("version", version.Current().Version))
@@ -31,6 +32,7 @@ func newRunCmd(opts *GlobalOptions) *cobra.Command {
 	}
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Detect diffs but do not send notifications")
 	cmd.Flags().BoolVar(&once, "once", false, "Run a single poll/diff cycle and exit")
+	cmd.Flags().StringVar(&opts.ListenAddr, "listen-addr", "", "Address for the metrics and health HTTP server (for example :9090)")
 	return cmd
 }
 
@@ -45,6 +47,17 @@ func executeRun(ctx context.Context, deps *runtimeDeps, once, dryRun bool) error
 		}
 		return err
 	}
+	if deps.server != nil {
+		ln, err := deps.server.Listen()
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := deps.server.Serve(ctx, ln); err != nil {
+				deps.runner.Log.Error("http server stopped", zap.Error(err))
+			}
+		}()
+	}
 	err := deps.runner.Run(ctx, false, dryRun)
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return nil
@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/spf13/cobra"
+)
+
+func newSchemaCmd(_ *GlobalOptions) *cobra.Command {
+	var schemaVersion string
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema for emitted events",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			doc, err := event.JSONSchema(schemaVersion)
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(doc)
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&schemaVersion, "schema-version", event.SchemaVersionV2, "Event schema version to print (v1|v2)")
+	return cmd
+}
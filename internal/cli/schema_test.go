@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestSchemaCommandPrintsV2ByDefault(t *testing.T) {
+	cmd := newSchemaCmd(&GlobalOptions{})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	if err := cmd.RunE(cmd, nil); err != nil {
+		t.Fatalf("unexpected error running schema command: %v", err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("expected JSON schema output, got %q: %v", out.String(), err)
+	}
+	if doc["$id"] != "https://github.com/jaxxstorm/sentinel/schema/event.v2.json" {
+		t.Fatalf("expected v2 schema id, got %#v", doc["$id"])
+	}
+}
+
+func TestSchemaCommandRejectsUnknownVersion(t *testing.T) {
+	cmd := newSchemaCmd(&GlobalOptions{})
+	cmd.SetOut(&bytes.Buffer{})
+	if err := cmd.Flags().Set("schema-version", "v9"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.RunE(cmd, nil); err == nil {
+		t.Fatal("expected error for unknown schema version")
+	}
+}
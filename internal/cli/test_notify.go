@@ -18,6 +18,7 @@ func newTestNotifyCmd(opts *GlobalOptions) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			defer deps.flushTracing()
 			evt := event.NewPresenceEvent(event.TypePeerOnline, "test-peer", "before", "after", map[string]any{"name": "test-peer"}, time.Now())
 			result, err := deps.notifier.Notify(context.Background(), []event.Event{evt}, dryRun)
 			if err != nil {
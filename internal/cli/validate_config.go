@@ -14,7 +14,11 @@ func newValidateConfigCmd(opts *GlobalOptions) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			if err := config.Validate(cfg); err != nil {
+			builtin, err := validateConfig(cfg)
+			if err != nil {
+				return err
+			}
+			if _, err := loadInventory(builtin.Drift); err != nil {
 				return err
 			}
 			printLine("configuration valid")
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jaxxstorm/sentinel/internal/clientversion"
+	"github.com/jaxxstorm/sentinel/internal/config"
+	"github.com/jaxxstorm/sentinel/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func newVersionsCmd(opts *GlobalOptions) *cobra.Command {
+	var format string
+	var minVersion string
+	var maxMinorBehind int
+	cmd := &cobra.Command{
+		Use:   "versions",
+		Short: "Report the Tailscale client version distribution from the latest snapshot",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("--format must be table or json")
+			}
+			cfg, err := config.Load(opts.ConfigPath)
+			if err != nil {
+				return err
+			}
+			detector := cfg.Detectors["client_version"]
+			detector.Options = maps.Clone(detector.Options)
+			if detector.Options == nil {
+				detector.Options = map[string]any{}
+			}
+			if cmd.Flags().Changed("min-version") {
+				detector.Options["min_version"] = minVersion
+			}
+			if cmd.Flags().Changed("max-minor-behind") {
+				detector.Options["max_minor_behind"] = maxMinorBehind
+			}
+			cfg.Detectors["client_version"] = detector
+			builtin, err := validateConfig(cfg)
+			if err != nil {
+				return err
+			}
+			current, err := state.NewFileStore(cfg.State.Path).LoadSnapshot()
+			if errors.Is(err, state.ErrNoSnapshot) || errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("no snapshot recorded in %s yet: run sentinel first", cfg.State.Path)
+			}
+			if err != nil {
+				return err
+			}
+			return writeVersionsReport(cmd.OutOrStdout(), clientversion.Summarize(current, clientVersionPolicy(builtin.ClientVersion)), format)
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table|json")
+	cmd.Flags().StringVar(&minVersion, "min-version", "", "Minimum acceptable client version (overrides detectors.client_version.min_version)")
+	cmd.Flags().IntVar(&maxMinorBehind, "max-minor-behind", 0, "Minor versions a client may trail the newest stable version (overrides detectors.client_version.max_minor_behind)")
+	return cmd
+}
+
+func writeVersionsReport(w io.Writer, report clientversion.Report, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tPEERS\tSTATUS")
+	for _, entry := range report.Versions {
+		status := "ok"
+		if entry.Outdated {
+			status = "outdated (" + entry.Reason + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", entry.Version, entry.Peers, status)
+	}
+	if len(report.Unknown) > 0 {
+		fmt.Fprintf(tw, "unknown\t%d\t-\n", len(report.Unknown))
+	}
+	return tw.Flush()
+}
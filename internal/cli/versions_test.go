@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jaxxstorm/sentinel/internal/clientversion"
+)
+
+func TestWriteVersionsReportTable(t *testing.T) {
+	report := clientversion.Report{
+		Newest: "1.82.0",
+		Versions: []clientversion.Entry{
+			{Version: "1.82.0", Peers: 2, Devices: []string{"db", "web"}},
+			{Version: "1.76.1", Peers: 1, Outdated: true, Reason: clientversion.ReasonBelowMinimum, Devices: []string{"laptop"}},
+		},
+		Unknown: []string{"printer"},
+	}
+	var out bytes.Buffer
+	if err := writeVersionsReport(&out, report, "table"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "VERSION") {
+		t.Fatalf("unexpected table: %q", out.String())
+	}
+	if !strings.Contains(lines[1], "ok") || !strings.Contains(lines[2], "outdated (below_minimum)") || !strings.HasPrefix(lines[3], "unknown") {
+		t.Fatalf("unexpected rows: %q", out.String())
+	}
+}
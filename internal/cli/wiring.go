@@ -3,34 +3,52 @@ package cli
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/jaxxstorm/sentinel/internal/app"
+	"github.com/jaxxstorm/sentinel/internal/clientversion"
 	"github.com/jaxxstorm/sentinel/internal/config"
 	"github.com/jaxxstorm/sentinel/internal/diff"
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/inventory"
 	"github.com/jaxxstorm/sentinel/internal/logging"
 	"github.com/jaxxstorm/sentinel/internal/metrics"
 	"github.com/jaxxstorm/sentinel/internal/notify"
 	"github.com/jaxxstorm/sentinel/internal/onboarding"
 	"github.com/jaxxstorm/sentinel/internal/output"
 	"github.com/jaxxstorm/sentinel/internal/policy"
+	"github.com/jaxxstorm/sentinel/internal/server"
+	"github.com/jaxxstorm/sentinel/internal/severity"
 	"github.com/jaxxstorm/sentinel/internal/source"
 	"github.com/jaxxstorm/sentinel/internal/state"
+	"github.com/jaxxstorm/sentinel/internal/tracing"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"tailscale.com/client/tailscale/apitype"
 	"tailscale.com/tsnet"
 )
 
 type runtimeDeps struct {
-	cfg        config.Config
+	cfg config.Config
+	// detectors are the decoded options of the built-in detectors.
+	detectors  config.DetectorOptions
 	runner     *app.Runner
 	renderer   *output.Renderer
 	source     source.NetmapSource
 	notifier   *notify.Notifier
 	enrollment onboarding.EnrollmentManager
+	server     *server.Server
+	// inventory is the declared inventory for drift, empty when
+	// detectors.drift.inventory is not set.
+	inventory inventory.Inventory
+	// shutdownTracing flushes pending spans; callers run it before exiting.
+	shutdownTracing tracing.ShutdownFunc
 }
 
 func buildRuntime(opts *GlobalOptions) (*runtimeDeps, error) {
@@ -59,6 +77,9 @@ func buildRuntime(opts *GlobalOptions) (*runtimeDeps, error) {
 	if opts.TailscaleFallbackOverride {
 		cfg.TSNet.AllowInteractiveFallback = true
 	}
+	if opts.ListenAddr != "" {
+		cfg.Server.ListenAddr = strings.TrimSpace(opts.ListenAddr)
+	}
 	authKey, sourceName := onboarding.ResolveAuthKey(
 		opts.TailscaleAuthKey,
 		os.Getenv("SENTINEL_TAILSCALE_AUTH_KEY"),
@@ -96,7 +117,8 @@ func buildRuntime(opts *GlobalOptions) (*runtimeDeps, error) {
 		cfg.TSNet.CredentialMode = "none"
 		cfg.TSNet.CredentialSource = "none"
 	}
-	if err := config.Validate(cfg); err != nil {
+	builtin, err := validateConfig(cfg)
+	if err != nil {
 		return nil, err
 	}
 
@@ -109,19 +131,18 @@ func buildRuntime(opts *GlobalOptions) (*runtimeDeps, error) {
 		return nil, err
 	}
 	sentinelLogger := logging.WithSource(logger, logging.LogSourceSentinel)
-	st := state.NewFileStore(cfg.State.Path)
-	detectors := []diff.Detector{
-		diff.NewPresenceDetector(),
-		diff.NewPeerChangeDetector(),
-		diff.NewRuntimeDetector(),
-	}
-	engine := diff.NewEngine(detectors)
-	policyEngine := policy.NewEngine(policy.Config{
-		DebounceWindow:    cfg.Policy.DebounceWindow,
-		SuppressionWindow: cfg.Policy.SuppressionWindow,
-		RateLimitPerMin:   cfg.Policy.RateLimitPerMin,
-		BatchSize:         cfg.Policy.BatchSize,
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Enabled:     cfg.Tracing.Enabled,
+		Endpoint:    cfg.Tracing.Endpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
 	})
+	if err != nil {
+		return nil, err
+	}
+	st := state.NewFileStore(cfg.State.Path)
+	// Detector state is committed by the runner once a cycle is delivered.
+	detectorState := diff.NewStagedStateStore(st)
 
 	const defaultSinkName = "stdout-debug"
 	sinks := make([]notify.Sink, 0, len(cfg.Notifier.Sinks))
@@ -129,6 +150,11 @@ func buildRuntime(opts *GlobalOptions) (*runtimeDeps, error) {
 	defaultSinkPresent := false
 	for _, sinkCfg := range cfg.Notifier.Sinks {
 		sinkType := strings.ToLower(strings.TrimSpace(sinkCfg.Type))
+		sinkOpts := []notify.SinkOption{
+			notify.WithSchemaVersion(strings.TrimSpace(sinkCfg.SchemaVersion)),
+			notify.WithFormat(strings.ToLower(strings.TrimSpace(sinkCfg.Format))),
+			notify.WithCloudEvents(strings.ToLower(strings.TrimSpace(sinkCfg.CloudEventsMode)), strings.TrimSpace(sinkCfg.Source)),
+		}
 		switch sinkType {
 		case "", "webhook":
 			url := strings.TrimSpace(sinkCfg.URL)
@@ -136,7 +162,7 @@ func buildRuntime(opts *GlobalOptions) (*runtimeDeps, error) {
 				sentinelLogger.Warn("skipping sink with empty/unresolved URL", zap.String("sink", sinkCfg.Name))
 				continue
 			}
-			sink := notify.NewWebhookSink(sinkCfg.Name, url, logging.WithSource(logger, logging.LogSourceSink))
+			sink := notify.NewWebhookSink(sinkCfg.Name, url, logging.WithSource(logger, logging.LogSourceSink), sinkOpts...)
 			sinks = append(sinks, sink)
 			availableSinks[sink.Name()] = struct{}{}
 		case "stdout", "debug":
@@ -144,7 +170,7 @@ func buildRuntime(opts *GlobalOptions) (*runtimeDeps, error) {
 			if name == "" {
 				name = defaultSinkName
 			}
-			sink := notify.NewStdoutSink(name, os.Stdout)
+			sink := notify.NewStdoutSink(name, os.Stdout, sinkOpts...)
 			sinks = append(sinks, sink)
 			availableSinks[sink.Name()] = struct{}{}
 			if sink.Name() == defaultSinkName {
@@ -155,7 +181,7 @@ func buildRuntime(opts *GlobalOptions) (*runtimeDeps, error) {
 			if url == "" || strings.Contains(url, "${") {
 				return nil, fmt.Errorf("discord sink %q requires a non-empty webhook url", sinkCfg.Name)
 			}
-			sink := notify.NewDiscordSink(sinkCfg.Name, url, logging.WithSource(logger, logging.LogSourceSink))
+			sink := notify.NewDiscordSink(sinkCfg.Name, url, logging.WithSource(logger, logging.LogSourceSink), sinkOpts...)
 			sinks = append(sinks, sink)
 			availableSinks[sink.Name()] = struct{}{}
 		default:
@@ -198,7 +224,84 @@ func buildRuntime(opts *GlobalOptions) (*runtimeDeps, error) {
 			Sinks:      []string{defaultSinkName},
 		})
 	}
-	notifier := notify.New(notify.Config{Routes: routes, IdempotencyKeyTTL: cfg.Notifier.IdempotencyKeyTTL}, st, sinks)
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	m := metrics.New(registry)
+	notifier := notify.New(notify.Config{
+		Routes:            routes,
+		IdempotencyKeyTTL: cfg.Notifier.IdempotencyKeyTTL,
+		Metrics:           m,
+	}, st, sinks)
+	classifier := severity.NewClassifier(severityRulesFromConfig(cfg.Severity))
+	flapping := diff.NewFlappingDetector(builtin.Flapping.Window, builtin.Flapping.Transitions, detectorState)
+	massOffline := diff.NewMassOfflineDetector(builtin.MassOffline.Window, builtin.MassOffline.MinPeers, builtin.MassOffline.TagPercentages, detectorState,
+		diff.WithMassOfflineRouted(func(evt event.Event) bool {
+			return notifier.Routed(classifier.Classify([]event.Event{evt})[0])
+		}),
+	)
+	ignoreHostinfo := builtin.PeerChanges.IgnoreHostinfoFields
+	if detectorRuns(cfg, "client_version") {
+		// client_version reports version changes; don't repeat them in
+		// peer.hostinfo.changed.
+		ignoreHostinfo = append(slices.Clone(ignoreHostinfo), diff.HostinfoIPNVersion)
+	}
+	detectors := []diff.Detector{
+		diff.NewPresenceDetector(diff.WithIgnoreTags(builtin.Presence.IgnoreTags...)),
+		diff.NewPeerChangeDetector(
+			diff.WithPeerChanges(builtin.PeerChanges.Changes...),
+			diff.WithIgnoreHostinfoFields(ignoreHostinfo...),
+		),
+		diff.NewRuntimeDetector(diff.WithRuntimeWatch(builtin.Runtime.Watch...)),
+		diff.NewKeyExpiryDetector(builtin.KeyExpiry.Thresholds, detectorState),
+		flapping,
+		diff.NewProlongedOfflineDetector(builtin.ProlongedOffline.Threshold, builtin.ProlongedOffline.TagThresholds, detectorState),
+		diff.NewRouteOverlapDetector(routeHAGroups(builtin.RouteOverlap.HAPairs)),
+		diff.NewRouteRedundancyDetector(builtin.RouteRedundancy.CriticalPrefixes),
+	}
+	compliance, err := diff.NewComplianceDetector(complianceRules(cfg.Compliance.Rules), detectorState, diff.WithComplianceLogger(sentinelLogger))
+	if err != nil {
+		return nil, err
+	}
+	inv, err := loadInventory(builtin.Drift)
+	if err != nil {
+		return nil, err
+	}
+	detectors = append(detectors, compliance, diff.NewDriftDetector(inv), massOffline, diff.NewClientVersionDetector(clientVersionPolicy(builtin.ClientVersion)), diff.NewSelfDetector(builtin.Self.Thresholds, detectorState))
+	for _, custom := range cfg.CustomDetectors {
+		d, err := diff.NewExpressionDetector(diff.ExpressionDetectorConfig{
+			Name:        custom.Name,
+			EventType:   custom.EventType,
+			Severity:    custom.Severity,
+			Tags:        custom.Subject.Tags,
+			DeviceNames: custom.Subject.DeviceNames,
+			Expression:  custom.Expression,
+			Log:         sentinelLogger,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("detectors.custom %q: %w", custom.Name, err)
+		}
+		detectors = append(detectors, d)
+	}
+	for _, process := range cfg.ProcessDetectors {
+		detectors = append(detectors, diff.NewProcessDetector(diff.ProcessDetectorConfig{
+			Name:       process.Name,
+			Command:    process.Command,
+			Args:       process.Args,
+			Timeout:    process.Timeout,
+			EventTypes: process.EventTypes,
+		}, sentinelLogger))
+	}
+	engine := diff.NewEngine(detectors)
+	policyEngine := policy.NewEngine(policy.Config{
+		DebounceWindow:    cfg.Policy.DebounceWindow,
+		SuppressionWindow: cfg.Policy.SuppressionWindow,
+		RateLimitPerMin:   cfg.Policy.RateLimitPerMin,
+		BatchSize:         cfg.Policy.BatchSize,
+		Suppressors:       []policy.Suppressor{flapping, massOffline},
+	})
 
 	ts := &tsnet.Server{
 		Hostname:      cfg.TSNet.Hostname,
@@ -240,25 +343,110 @@ func buildRuntime(opts *GlobalOptions) (*runtimeDeps, error) {
 		LoginTimeout:             cfg.TSNet.LoginTimeout,
 	}, onboarding.NewTSNetProvider(ts), sentinelLogger)
 
-	m := metrics.New(prometheus.NewRegistry())
 	r := app.NewRunner(cfg, src, engine, policyEngine, notifier, st, m, sentinelLogger, enrollment)
+	r.DetectorState = detectorState
+	r.Severity = classifier
+
+	var srv *server.Server
+	if cfg.Server.ListenAddr != "" {
+		serverCfg := server.Config{
+			ListenAddr: cfg.Server.ListenAddr,
+			Gatherer:   registry,
+			Ready:      r.Ready,
+			Logger:     sentinelLogger,
+		}
+		if cfg.Server.Tailnet.Enabled {
+			serverCfg = withTailnet(serverCfg, cfg.Server, ts, tailnetWhoIs(ts))
+		}
+		srv = server.New(serverCfg)
+	}
 
 	return &runtimeDeps{
 		cfg:        cfg,
+		detectors:  builtin,
 		runner:     r,
 		renderer:   output.NewRenderer(cfg.Output.NoColor),
 		source:     src,
 		notifier:   notifier,
 		enrollment: enrollment,
+		server:     srv,
+		inventory:  inv,
+
+		shutdownTracing: shutdownTracing,
 	}, nil
 }
 
+// tailnetListen is the part of the tsnet server the HTTP server binds on.
+type tailnetListen interface {
+	Listen(network, addr string) (net.Listener, error)
+	ListenTLS(network, addr string) (net.Listener, error)
+}
+
+// withTailnet binds serverCfg on the tailnet and only admits the callers
+// cfg.Tailnet allows, as identified by whoIs.
+func withTailnet(serverCfg server.Config, cfg config.ServerConfig, ts tailnetListen, whoIs server.WhoIsFunc) server.Config {
+	serverCfg.Listen = tailnetListener(ts, cfg)
+	serverCfg.TailnetAuth = &server.TailnetAuth{
+		WhoIs:        whoIs,
+		AllowedTags:  cfg.Tailnet.AllowedTags,
+		AllowedUsers: cfg.Tailnet.AllowedUsers,
+	}
+	return serverCfg
+}
+
+func tailnetListener(ts tailnetListen, cfg config.ServerConfig) server.ListenFunc {
+	return func() (net.Listener, error) {
+		if cfg.Tailnet.TLS {
+			return ts.ListenTLS("tcp", cfg.ListenAddr)
+		}
+		return ts.Listen("tcp", cfg.ListenAddr)
+	}
+}
+
+func tailnetWhoIs(ts *tsnet.Server) server.WhoIsFunc {
+	return func(ctx context.Context, remoteAddr string) (*apitype.WhoIsResponse, error) {
+		client, err := ts.LocalClient()
+		if err != nil {
+			return nil, fmt.Errorf("create local client: %w", err)
+		}
+		return client.WhoIs(ctx, remoteAddr)
+	}
+}
+
+// flushTracing exports any spans still buffered when a command exits.
+func (d *runtimeDeps) flushTracing() {
+	if d.shutdownTracing == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.shutdownTracing(ctx); err != nil && d.runner != nil {
+		d.runner.Log.Warn("trace export shutdown failed", zap.Error(err))
+	}
+}
+
 func runOnceWithTimeout(ctx context.Context, fn func(context.Context) error) error {
 	cctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 	return fn(cctx)
 }
 
+func severityRulesFromConfig(cfg config.SeverityConfig) []severity.Rule {
+	rules := make([]severity.Rule, 0, len(cfg.Rules))
+	for _, r := range cfg.Rules {
+		rules = append(rules, severity.Rule{
+			Name:        strings.TrimSpace(r.Name),
+			EventTypes:  normalizedFilterValues(r.EventTypes),
+			Tags:        normalizedFilterValues(r.Tags),
+			Owners:      normalizedFilterValues(r.Owners),
+			DeviceNames: normalizedFilterValues(r.DeviceNames),
+			Payload:     r.Payload,
+			Severity:    strings.ToLower(strings.TrimSpace(r.Severity)),
+		})
+	}
+	return rules
+}
+
 func routeFiltersFromConfig(r config.RouteConfig) notify.RouteFilters {
 	filters := notify.RouteFilters{
 		Include: notify.NotificationFilter{
@@ -310,3 +498,85 @@ func normalizedFilterValues(values []string) []string {
 func printLine(format string, args ...any) {
 	_, _ = fmt.Fprintf(os.Stdout, format+"\n", args...)
 }
+
+// validateConfig validates cfg and the detector options that name diff
+// kinds, which the config package does not know, and returns the decoded
+// built-in detector options.
+func validateConfig(cfg config.Config) (config.DetectorOptions, error) {
+	if err := config.Validate(cfg); err != nil {
+		return config.DetectorOptions{}, err
+	}
+	builtin, err := cfg.BuiltinOptions()
+	if err != nil {
+		return config.DetectorOptions{}, err
+	}
+	for _, check := range []struct {
+		field        string
+		values, kind []string
+	}{
+		{"detectors.peer_changes.changes", builtin.PeerChanges.Changes, diff.PeerChanges},
+		{"detectors.peer_changes.ignore_hostinfo_fields", builtin.PeerChanges.IgnoreHostinfoFields, diff.HostinfoFields},
+		{"detectors.runtime.watch", builtin.Runtime.Watch, diff.RuntimeWatches},
+	} {
+		for i, raw := range check.values {
+			if !slices.Contains(check.kind, strings.ToLower(strings.TrimSpace(raw))) {
+				return config.DetectorOptions{}, fmt.Errorf("%s[%d] must be one of %s", check.field, i, strings.Join(check.kind, ", "))
+			}
+		}
+	}
+	return builtin, nil
+}
+
+// loadInventory reads detectors.drift.inventory, returning an empty
+// inventory when it is not set.
+func loadInventory(opts config.DriftOptions) (inventory.Inventory, error) {
+	path := strings.TrimSpace(opts.Inventory)
+	if path == "" {
+		return inventory.Inventory{}, nil
+	}
+	inv, err := inventory.Load(path)
+	if err != nil {
+		return inventory.Inventory{}, fmt.Errorf("detectors.drift.inventory: %w", err)
+	}
+	return inv, nil
+}
+
+func routeHAGroups(pairs []config.HAPairConfig) []diff.RouteHAGroup {
+	groups := make([]diff.RouteHAGroup, 0, len(pairs))
+	for _, pair := range pairs {
+		groups = append(groups, diff.RouteHAGroup{Tags: pair.Tags, Names: pair.Names})
+	}
+	return groups
+}
+
+// detectorRuns reports whether the engine runs the built-in detector name:
+// it is listed in detector_order and not disabled.
+func detectorRuns(cfg config.Config, name string) bool {
+	d, ok := cfg.Detectors[name]
+	return slices.Contains(cfg.DetectorOrder, name) && (!ok || d.Enabled)
+}
+
+// clientVersionPolicy builds the outdated-client policy of the
+// client_version detector. min_version is checked by config.Validate.
+func clientVersionPolicy(opts config.ClientVersionOptions) clientversion.Policy {
+	policy := clientversion.Policy{MaxMinorBehind: opts.MaxMinorBehind}
+	if minimum, ok := clientversion.Parse(opts.MinVersion); ok {
+		policy.Minimum, policy.HasMinimum = minimum, true
+	}
+	return policy
+}
+
+func complianceRules(rules []config.ComplianceRuleConfig) []diff.ComplianceRule {
+	out := make([]diff.ComplianceRule, 0, len(rules))
+	for _, rule := range rules {
+		out = append(out, diff.ComplianceRule{
+			Name:        rule.Name,
+			Description: rule.Description,
+			Severity:    rule.Severity,
+			Tags:        rule.Subject.Tags,
+			DeviceNames: rule.Subject.DeviceNames,
+			Expression:  rule.Expression,
+		})
+	}
+	return out
+}
@@ -2,14 +2,22 @@ package cli
 
 import (
 	"context"
+	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/jaxxstorm/sentinel/internal/config"
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/server"
 	"github.com/jaxxstorm/sentinel/internal/source"
+	"tailscale.com/client/tailscale/apitype"
+	"tailscale.com/tailcfg"
 )
 
 func TestBuildRuntimeAddsDefaultStdoutSinkWhenNotifierConfigEmpty(t *testing.T) {
@@ -223,3 +231,239 @@ func TestRouteFiltersFromConfigUsesExplicitFiltersWhenConfigured(t *testing.T) {
 		t.Fatalf("expected exclude events to map, got %#v", filters.Exclude.Events)
 	}
 }
+
+func TestBuildRuntimeConfiguresHTTPServerOnlyWhenListenAddrSet(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "sentinel.yaml")
+	cfg := "state:\n  path: " + filepath.ToSlash(filepath.Join(t.TempDir(), "state.json")) + "\n"
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	deps, err := buildRuntime(&GlobalOptions{ConfigPath: cfgPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deps.server != nil {
+		t.Fatal("expected no http server without listen addr")
+	}
+
+	deps, err = buildRuntime(&GlobalOptions{ConfigPath: cfgPath, ListenAddr: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deps.server == nil {
+		t.Fatal("expected http server when --listen-addr is set")
+	}
+	if deps.server.ListenAddr() != "127.0.0.1:0" {
+		t.Fatalf("unexpected listen addr %q", deps.server.ListenAddr())
+	}
+}
+
+func TestBuildRuntimeClassifiesSeverityBeforeRouting(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "sentinel.yaml")
+	cfg := "state:\n  path: " + filepath.ToSlash(filepath.Join(t.TempDir(), "state.json")) +
+		"\nseverity:\n  rules:\n    - event_types: [\"peer.online\"]\n      tags: [\"tag:prod\"]\n      severity: critical\n" +
+		"notifier:\n  sinks:\n    - name: paging\n      type: stdout\n  routes:\n    - event_types: [\"*\"]\n      severities: [\"critical\"]\n      sinks: [\"paging\"]\n"
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	deps, err := buildRuntime(&GlobalOptions{ConfigPath: cfgPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps.runner.Source = source.NewStaticSource(source.Netmap{Peers: []source.Peer{
+		{ID: "prod1", Name: "prod1", Online: true, Tags: []string{"tag:prod"}},
+		{ID: "dev1", Name: "dev1", Online: true},
+	}})
+	deps.runner.Enrollment = nil
+
+	res, err := deps.runner.RunOnce(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.DryRunCount != 1 {
+		t.Fatalf("expected only the critical event to route, got %d", res.DryRunCount)
+	}
+	for _, evt := range res.Events {
+		want := "info"
+		if evt.SubjectID == "prod1" && evt.EventType == "peer.online" {
+			want = "critical"
+		}
+		if evt.Severity != want {
+			t.Fatalf("expected %s severity %q, got %q", evt.SubjectID, want, evt.Severity)
+		}
+	}
+}
+
+func TestValidateConfigChecksDetectorKinds(t *testing.T) {
+	cfg := config.Default()
+	cfg.Detectors["peer_changes"] = config.Detector{Enabled: true, Options: map[string]any{"changes": []any{"routes", "hostinfo"}, "ignore_hostinfo_fields": []any{"os_version"}}}
+	cfg.Detectors["runtime"] = config.Detector{Enabled: true, Options: map[string]any{"watch": []any{"shields_up"}}}
+	builtin, err := validateConfig(cfg)
+	if err != nil {
+		t.Fatalf("expected detector kinds to validate, got %v", err)
+	}
+	if len(builtin.PeerChanges.Changes) != 2 || len(builtin.Runtime.Watch) != 1 {
+		t.Fatalf("unexpected decoded options: %+v", builtin)
+	}
+
+	tests := []struct {
+		name     string
+		detector string
+		options  map[string]any
+		want     string
+	}{
+		{name: "unknown change", detector: "peer_changes", options: map[string]any{"changes": []any{"owners"}}, want: "detectors.peer_changes.changes[0] must be one of routes, tags"},
+		{name: "unknown watch", detector: "runtime", options: map[string]any{"watch": []any{"hostname"}}, want: "detectors.runtime.watch[0] must be one of daemon_state"},
+		{name: "unknown hostinfo field", detector: "peer_changes", options: map[string]any{"ignore_hostinfo_fields": []any{"hostname"}}, want: "detectors.peer_changes.ignore_hostinfo_fields[0] must be one of os, os_version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Detectors[tt.detector] = config.Detector{Enabled: true, Options: tt.options}
+			_, err := validateConfig(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+type fakeTailnet struct {
+	tls bool
+}
+
+func (f *fakeTailnet) Listen(network, _ string) (net.Listener, error) {
+	return net.Listen(network, "127.0.0.1:0")
+}
+
+func (f *fakeTailnet) ListenTLS(network, _ string) (net.Listener, error) {
+	f.tls = true
+	return net.Listen(network, "127.0.0.1:0")
+}
+
+func TestWithTailnetRejectsCallersNotAllowed(t *testing.T) {
+	cfg := config.ServerConfig{
+		ListenAddr: ":9090",
+		Tailnet:    config.ServerTailnetConfig{Enabled: true, AllowedTags: []string{"tag:monitoring"}},
+	}
+	var mu sync.Mutex
+	callers := map[string]*apitype.WhoIsResponse{}
+	whoIs := func(_ context.Context, remoteAddr string) (*apitype.WhoIsResponse, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		resp, ok := callers[remoteAddr]
+		if !ok {
+			return nil, errors.New("peer not found")
+		}
+		return resp, nil
+	}
+	ts := &fakeTailnet{}
+	srv := server.New(withTailnet(server.Config{ListenAddr: cfg.ListenAddr}, cfg, ts, whoIs))
+
+	ln, err := srv.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.tls {
+		t.Fatal("expected a plain tailnet listener when server.tailnet.tls is false")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+
+	get := func(tags ...string) int {
+		t.Helper()
+		client := &http.Client{Transport: &http.Transport{
+			DisableKeepAlives: true,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+				if err == nil && tags != nil {
+					mu.Lock()
+					callers[conn.LocalAddr().String()] = &apitype.WhoIsResponse{
+						Node: &tailcfg.Node{ComputedName: "caller", Tags: tags},
+					}
+					mu.Unlock()
+				}
+				return conn, err
+			},
+		}}
+		resp, err := client.Get("http://" + ln.Addr().String() + "/healthz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := get("tag:monitoring"); code != http.StatusOK {
+		t.Fatalf("expected allowed tag to be served, got %d", code)
+	}
+	if code := get("tag:other"); code != http.StatusForbidden {
+		t.Fatalf("expected tag outside allowed_tags to be forbidden, got %d", code)
+	}
+	if code := get(); code != http.StatusForbidden {
+		t.Fatalf("expected caller unknown to WhoIs to be forbidden, got %d", code)
+	}
+}
+
+func TestTailnetListenerUsesTLSWhenConfigured(t *testing.T) {
+	ts := &fakeTailnet{}
+	ln, err := tailnetListener(ts, config.ServerConfig{Tailnet: config.ServerTailnetConfig{Enabled: true, TLS: true}})()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if !ts.tls {
+		t.Fatal("expected a TLS tailnet listener when server.tailnet.tls is true")
+	}
+}
+
+func TestBuildRuntimeReportsClientUpgradeOnce(t *testing.T) {
+	tests := map[string]struct {
+		detectors string
+		want      string
+	}{
+		"client_version enabled":  {want: event.TypePeerClientVersionChanged},
+		"client_version disabled": {detectors: "detectors:\n  client_version:\n    enabled: false\n", want: event.TypePeerHostinfoChanged},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfgPath := filepath.Join(t.TempDir(), "sentinel.yaml")
+			cfg := "state:\n  path: " + filepath.ToSlash(filepath.Join(t.TempDir(), "state.json")) + "\n" + tt.detectors
+			if err := os.WriteFile(cfgPath, []byte(cfg), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			deps, err := buildRuntime(&GlobalOptions{ConfigPath: cfgPath})
+			if err != nil {
+				t.Fatal(err)
+			}
+			peer := func(version string) source.Peer {
+				return source.Peer{ID: "peer1", Name: "peer1", Online: true, Hostinfo: &source.Hostinfo{OS: "linux", IPNVersion: version}}
+			}
+			deps.runner.Source = source.NewSequenceSource([]source.Netmap{
+				{Peers: []source.Peer{peer("1.80.0")}},
+				{Peers: []source.Peer{peer("1.82.0")}},
+			})
+			deps.runner.Enrollment = nil
+
+			if _, err := deps.runner.RunOnce(context.Background(), true); err != nil {
+				t.Fatal(err)
+			}
+			res, err := deps.runner.RunOnce(context.Background(), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Events) != 1 || res.Events[0].EventType != tt.want {
+				t.Fatalf("expected one %s for the upgrade, got %#v", tt.want, res.Events)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// Package clientversion parses Tailscale client versions and checks peers
+// against a minimum version policy.
+package clientversion
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+// Reasons a peer is outdated.
+const (
+	ReasonBelowMinimum = "below_minimum"
+	ReasonBehindNewest = "behind_newest"
+)
+
+// Version is a Tailscale client release, without build suffixes.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// Parse reads the release from a client version such as "1.80.2" or
+// "1.80.2-t1a2b3c4d5-g6e7f8a9b0". A missing patch is zero.
+func Parse(raw string) (Version, bool) {
+	raw = strings.TrimPrefix(strings.TrimSpace(raw), "v")
+	if i := strings.IndexAny(raw, "-+ "); i >= 0 {
+		raw = raw[:i]
+	}
+	parts := strings.Split(raw, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Version{}, false
+	}
+	nums := [3]int{}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, false
+		}
+		nums[i] = n
+	}
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}, true
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or 1 as v is older than, equal to or newer than o.
+func (v Version) Compare(o Version) int {
+	for _, d := range [3]int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		switch {
+		case d < 0:
+			return -1
+		case d > 0:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Stable reports whether v is a stable release. Tailscale ships stable
+// releases on even minor versions and unstable builds on odd ones.
+func (v Version) Stable() bool {
+	return v.Minor%2 == 0
+}
+
+// MinorsBehind returns how many minor versions v trails newest. It reports
+// false when v is on an older major release, whose minor versions do not
+// compare; MajorsBehind measures those.
+func (v Version) MinorsBehind(newest Version) (int, bool) {
+	switch {
+	case v.Major < newest.Major:
+		return 0, false
+	case v.Major > newest.Major || v.Minor >= newest.Minor:
+		return 0, true
+	}
+	return newest.Minor - v.Minor, true
+}
+
+// MajorsBehind returns how many major versions v trails newest.
+func (v Version) MajorsBehind(newest Version) int {
+	return max(newest.Major-v.Major, 0)
+}
+
+// Of returns the parsed client version of p, from its Hostinfo.
+func Of(p snapshot.Peer) (Version, bool) {
+	if p.Hostinfo == nil {
+		return Version{}, false
+	}
+	return Parse(p.Hostinfo.IPNVersion)
+}
+
+// Newest returns the newest stable client version among the peers of s.
+// Unstable builds are skipped, so a single peer on an unstable track does
+// not put every stable peer behind.
+func Newest(s snapshot.Snapshot) (Version, bool) {
+	var newest Version
+	found := false
+	for _, p := range s.Peers {
+		if v, ok := Of(p); ok && v.Stable() && (!found || v.Compare(newest) > 0) {
+			newest, found = v, true
+		}
+	}
+	return newest, found
+}
+
+// Policy decides whether a client version is outdated. A zero Policy
+// reports nothing.
+type Policy struct {
+	// Minimum is the oldest acceptable version, when HasMinimum is set.
+	Minimum    Version
+	HasMinimum bool
+	// MaxMinorBehind is how many minor versions a peer may trail the
+	// newest version in the tailnet. Zero disables the check.
+	MaxMinorBehind int
+}
+
+// Outdated reports why v is outdated given the newest version seen.
+func (p Policy) Outdated(v, newest Version) (string, bool) {
+	if p.HasMinimum && v.Compare(p.Minimum) < 0 {
+		return ReasonBelowMinimum, true
+	}
+	if p.MaxMinorBehind > 0 {
+		// A peer on an older major release is behind by any minor count.
+		if minors, ok := v.MinorsBehind(newest); !ok || minors > p.MaxMinorBehind {
+			return ReasonBehindNewest, true
+		}
+	}
+	return "", false
+}
+
+// Report is the client version distribution of a snapshot.
+type Report struct {
+	Newest   string  `json:"newest,omitempty"`
+	Minimum  string  `json:"minimum,omitempty"`
+	Versions []Entry `json:"versions"`
+	// Unknown lists the peers whose client version is not reported.
+	Unknown []string `json:"unknown"`
+}
+
+// Entry is one client version and the peers running it.
+type Entry struct {
+	Version  string   `json:"version"`
+	Peers    int      `json:"peers"`
+	Outdated bool     `json:"outdated"`
+	Reason   string   `json:"reason,omitempty"`
+	Devices  []string `json:"devices"`
+}
+
+// Summarize groups the peers of s by client version, newest first, and
+// marks the versions p considers outdated.
+func Summarize(s snapshot.Snapshot, p Policy) Report {
+	out := Report{Versions: []Entry{}, Unknown: []string{}}
+	if p.HasMinimum {
+		out.Minimum = p.Minimum.String()
+	}
+	newest, ok := Newest(s)
+	if ok {
+		out.Newest = newest.String()
+	}
+	byVersion := map[Version][]string{}
+	for _, peer := range s.Peers {
+		v, ok := Of(peer)
+		if !ok {
+			out.Unknown = append(out.Unknown, peer.Name)
+			continue
+		}
+		byVersion[v] = append(byVersion[v], peer.Name)
+	}
+	for v, devices := range byVersion {
+		sort.Strings(devices)
+		reason, outdated := p.Outdated(v, newest)
+		out.Versions = append(out.Versions, Entry{
+			Version:  v.String(),
+			Peers:    len(devices),
+			Outdated: outdated,
+			Reason:   reason,
+			Devices:  devices,
+		})
+	}
+	sort.Slice(out.Versions, func(i, j int) bool {
+		a, _ := Parse(out.Versions[i].Version)
+		b, _ := Parse(out.Versions[j].Version)
+		return a.Compare(b) > 0
+	})
+	sort.Strings(out.Unknown)
+	return out
+}
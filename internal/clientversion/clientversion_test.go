@@ -0,0 +1,106 @@
+package clientversion
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Version
+		ok   bool
+	}{
+		{in: "1.80.2", want: Version{1, 80, 2}, ok: true},
+		{in: "1.82.0-t1a2b3c4d5-g6e7f8a9b0", want: Version{1, 82, 0}, ok: true},
+		{in: "v1.78", want: Version{1, 78, 0}, ok: true},
+		{in: "", ok: false},
+		{in: "unknown", ok: false},
+		{in: "1.x.0", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := Parse(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Fatalf("Parse(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPolicyOutdated(t *testing.T) {
+	newest := Version{1, 82, 0}
+	policy := Policy{Minimum: Version{1, 78, 1}, HasMinimum: true, MaxMinorBehind: 2}
+	tests := []struct {
+		v      Version
+		reason string
+	}{
+		{v: Version{1, 78, 0}, reason: ReasonBelowMinimum},
+		{v: Version{1, 78, 1}, reason: ReasonBehindNewest},
+		{v: Version{1, 80, 4}, reason: ""},
+		{v: Version{1, 82, 0}, reason: ""},
+		{v: Version{0, 98, 0}, reason: ReasonBelowMinimum},
+	}
+	for _, tt := range tests {
+		reason, outdated := policy.Outdated(tt.v, newest)
+		if reason != tt.reason || outdated != (tt.reason != "") {
+			t.Fatalf("Outdated(%s) = %q, %v; want %q", tt.v, reason, outdated, tt.reason)
+		}
+	}
+	if reason, _ := (Policy{MaxMinorBehind: 2}).Outdated(Version{0, 98, 0}, newest); reason != ReasonBehindNewest {
+		t.Fatalf("expected an older major release to be behind newest, got %q", reason)
+	}
+	if _, outdated := (Policy{}).Outdated(Version{1, 2, 0}, newest); outdated {
+		t.Fatal("expected a zero policy to report nothing")
+	}
+}
+
+func TestNewestSkipsUnstableBuilds(t *testing.T) {
+	peer := func(name, version string) snapshot.Peer {
+		return snapshot.Peer{ID: name, Name: name, Hostinfo: &snapshot.Hostinfo{IPNVersion: version}}
+	}
+	s := snapshot.Snapshot{Peers: []snapshot.Peer{
+		peer("canary", "1.83.12-tabc"),
+		peer("web", "1.82.0"),
+		peer("laptop", "1.80.4"),
+	}}
+	newest, ok := Newest(s)
+	if !ok || newest != (Version{1, 82, 0}) {
+		t.Fatalf("expected newest stable 1.82.0, got %v, %v", newest, ok)
+	}
+	if reason, outdated := (Policy{MaxMinorBehind: 1}).Outdated(Version{1, 83, 12}, newest); outdated {
+		t.Fatalf("expected an unstable build ahead of stable not to be outdated, got %q", reason)
+	}
+	if _, ok := Newest(snapshot.Snapshot{Peers: []snapshot.Peer{peer("canary", "1.83.12")}}); ok {
+		t.Fatal("expected no newest version when only unstable builds are seen")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	peer := func(name, version string) snapshot.Peer {
+		p := snapshot.Peer{ID: name, Name: name}
+		if version != "" {
+			p.Hostinfo = &snapshot.Hostinfo{IPNVersion: version}
+		}
+		return p
+	}
+	s := snapshot.Snapshot{Peers: []snapshot.Peer{
+		peer("web", "1.82.0-tabc"),
+		peer("db", "1.82.0-tdef"),
+		peer("laptop", "1.76.1"),
+		peer("printer", ""),
+	}}
+	got := Summarize(s, Policy{Minimum: Version{1, 80, 0}, HasMinimum: true})
+	want := Report{
+		Newest:  "1.82.0",
+		Minimum: "1.80.0",
+		Versions: []Entry{
+			{Version: "1.82.0", Peers: 2, Devices: []string{"db", "web"}},
+			{Version: "1.76.1", Peers: 1, Outdated: true, Reason: ReasonBelowMinimum, Devices: []string{"laptop"}},
+		},
+		Unknown: []string{"printer"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected report:\n got %#v\nwant %#v", got, want)
+	}
+}
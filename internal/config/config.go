@@ -3,15 +3,20 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/netip"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/expr"
 	"github.com/spf13/viper"
 )
 
@@ -21,6 +26,8 @@ type Config struct {
 	PollBackoffMin time.Duration       `mapstructure:"poll_backoff_min" json:"poll_backoff_min"`
 	PollBackoffMax time.Duration       `mapstructure:"poll_backoff_max" json:"poll_backoff_max"`
 	Source         SourceConfig        `mapstructure:"source" json:"source"`
+	Bootstrap      string              `mapstructure:"bootstrap" json:"bootstrap"`
+	Catchup        CatchupConfig       `mapstructure:"catchup" json:"catchup"`
 	Detectors      map[string]Detector `mapstructure:"detectors" json:"detectors"`
 	DetectorOrder  []string            `mapstructure:"detector_order" json:"detector_order"`
 	Policy         PolicyConfig        `mapstructure:"policy" json:"policy"`
@@ -28,10 +35,120 @@ type Config struct {
 	State          StateConfig         `mapstructure:"state" json:"state"`
 	Output         OutputConfig        `mapstructure:"output" json:"output"`
 	TSNet          TSNetConfig         `mapstructure:"tsnet" json:"tsnet"`
+	Server         ServerConfig        `mapstructure:"server" json:"server"`
+	Tracing        TracingConfig       `mapstructure:"tracing" json:"tracing"`
+	Metrics        MetricsConfig       `mapstructure:"metrics" json:"metrics"`
+	Severity       SeverityConfig      `mapstructure:"severity" json:"severity"`
+	Compliance     ComplianceConfig    `mapstructure:"compliance" json:"compliance"`
+	// CustomDetectors are the expression detectors configured under
+	// detectors.custom. They are split out of Detectors at load time.
+	CustomDetectors []CustomDetectorConfig `mapstructure:"-" json:"custom_detectors,omitempty"`
+	// ProcessDetectors are the external detectors configured under
+	// detectors.process.
+	ProcessDetectors []ProcessDetectorConfig `mapstructure:"-" json:"process_detectors,omitempty"`
 }
 
+// Detector configures a built-in detector. Options holds the keys set
+// besides enabled; BuiltinOptions decodes them into the detector's own
+// options type.
 type Detector struct {
-	Enabled bool `mapstructure:"enabled" json:"enabled"`
+	Enabled bool           `mapstructure:"enabled" json:"enabled"`
+	Options map[string]any `mapstructure:",remain" json:"options,omitempty"`
+}
+
+// HAPairConfig matches peers that form an intentional high-availability
+// group by tag or by device name glob.
+type HAPairConfig struct {
+	Tags  []string `mapstructure:"tags" json:"tags,omitempty"`
+	Names []string `mapstructure:"names" json:"names,omitempty"`
+}
+
+// UnmarshalJSON decodes a detector the way config files are decoded, so
+// structured env overrides accept duration strings such as "15m".
+func (d *Detector) UnmarshalJSON(b []byte) error {
+	var raw map[string]any
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	var out Detector
+	if err := decodeWithDurations(raw, &out); err != nil {
+		return err
+	}
+	*d = out
+	return nil
+}
+
+// decodeWithDurations decodes raw into out the way viper does, accepting
+// duration strings.
+func decodeWithDurations(raw any, out any) error {
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.StringToTimeDurationHookFunc(),
+		Result:     out,
+	})
+	if err != nil {
+		return err
+	}
+	return dec.Decode(raw)
+}
+
+// CustomDetectorConfig is a user-defined detector that emits EventType for
+// peers whose before and after state satisfies Expression.
+type CustomDetectorConfig struct {
+	Name       string            `mapstructure:"name" json:"name"`
+	EventType  string            `mapstructure:"event_type" json:"event_type"`
+	Severity   string            `mapstructure:"severity" json:"severity,omitempty"`
+	Subject    PeerSubjectConfig `mapstructure:"subject" json:"subject"`
+	Expression string            `mapstructure:"expression" json:"expression"`
+}
+
+// PeerSubjectConfig narrows which peers a custom detector or compliance
+// rule evaluates.
+type PeerSubjectConfig struct {
+	Tags        []string `mapstructure:"tags" json:"tags,omitempty"`
+	DeviceNames []string `mapstructure:"device_names" json:"device_names,omitempty"`
+}
+
+// ProcessDetectorConfig is an external detector: Command runs on every diff
+// cycle, reads {before, after} snapshot JSON on stdin and writes
+// newline-delimited events of the listed EventTypes to stdout.
+type ProcessDetectorConfig struct {
+	Name       string        `mapstructure:"name" json:"name"`
+	Command    string        `mapstructure:"command" json:"command"`
+	Args       []string      `mapstructure:"args" json:"args,omitempty"`
+	Timeout    time.Duration `mapstructure:"timeout" json:"timeout,omitempty"`
+	EventTypes []string      `mapstructure:"event_types" json:"event_types"`
+}
+
+// UnmarshalJSON accepts duration strings such as "5s" for timeout.
+func (p *ProcessDetectorConfig) UnmarshalJSON(b []byte) error {
+	var raw map[string]any
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	var out ProcessDetectorConfig
+	if err := decodeWithDurations(raw, &out); err != nil {
+		return err
+	}
+	*p = out
+	return nil
+}
+
+// Bootstrap modes control what Sentinel reports on the first cycle without
+// a stored snapshot.
+const (
+	// BootstrapSilent records the baseline without emitting events.
+	BootstrapSilent = "silent"
+	// BootstrapSummary emits one sentinel.baseline.captured event.
+	BootstrapSummary = "summary"
+	// BootstrapFull reports every peer as added and online.
+	BootstrapFull = "full"
+)
+
+// CatchupConfig controls the summary sent after downtime. When the stored
+// snapshot is at least Threshold old, the changes since are sent as one
+// sentinel.catchup event. Zero, the default, disables it.
+type CatchupConfig struct {
+	Threshold time.Duration `mapstructure:"threshold" json:"threshold"`
 }
 
 type SourceConfig struct {
@@ -82,6 +199,13 @@ type SinkConfig struct {
 	Name string `mapstructure:"name" json:"name"`
 	Type string `mapstructure:"type" json:"type"`
 	URL  string `mapstructure:"url" json:"url"`
+	// SchemaVersion selects the event wire format for this sink: v1 (default) or v2.
+	SchemaVersion string `mapstructure:"schema_version" json:"schema_version,omitempty"`
+	// Format selects the webhook body format: json (default) or cloudevents.
+	Format          string `mapstructure:"format" json:"format,omitempty"`
+	CloudEventsMode string `mapstructure:"cloudevents_mode" json:"cloudevents_mode,omitempty"`
+	// Source is the CloudEvents source URI reference.
+	Source string `mapstructure:"source" json:"source,omitempty"`
 }
 
 type StateConfig struct {
@@ -95,6 +219,59 @@ type OutputConfig struct {
 	NoColor   bool   `mapstructure:"no_color" json:"no_color"`
 }
 
+type ServerConfig struct {
+	ListenAddr string              `mapstructure:"listen_addr" json:"listen_addr"`
+	Tailnet    ServerTailnetConfig `mapstructure:"tailnet" json:"tailnet"`
+}
+
+type ServerTailnetConfig struct {
+	Enabled      bool     `mapstructure:"enabled" json:"enabled"`
+	TLS          bool     `mapstructure:"tls" json:"tls"`
+	AllowedTags  []string `mapstructure:"allowed_tags" json:"allowed_tags"`
+	AllowedUsers []string `mapstructure:"allowed_users" json:"allowed_users"`
+}
+
+type TracingConfig struct {
+	Enabled     bool    `mapstructure:"enabled" json:"enabled"`
+	Endpoint    string  `mapstructure:"endpoint" json:"endpoint"`
+	ServiceName string  `mapstructure:"service_name" json:"service_name"`
+	SampleRatio float64 `mapstructure:"sample_ratio" json:"sample_ratio"`
+}
+
+type SeverityConfig struct {
+	Rules []SeverityRuleConfig `mapstructure:"rules" json:"rules"`
+}
+
+type SeverityRuleConfig struct {
+	Name        string         `mapstructure:"name" json:"name"`
+	EventTypes  []string       `mapstructure:"event_types" json:"event_types"`
+	Tags        []string       `mapstructure:"tags" json:"tags"`
+	Owners      []string       `mapstructure:"owners" json:"owners"`
+	DeviceNames []string       `mapstructure:"device_names" json:"device_names"`
+	Payload     map[string]any `mapstructure:"payload" json:"payload"`
+	Severity    string         `mapstructure:"severity" json:"severity"`
+}
+
+// ComplianceConfig lists invariants the compliance detector checks against
+// every snapshot.
+type ComplianceConfig struct {
+	Rules []ComplianceRuleConfig `mapstructure:"rules" json:"rules"`
+}
+
+// ComplianceRuleConfig is an invariant: every peer selected by Subject must
+// satisfy Expression, which sees the peer as the variable peer.
+type ComplianceRuleConfig struct {
+	Name        string            `mapstructure:"name" json:"name"`
+	Description string            `mapstructure:"description" json:"description,omitempty"`
+	Severity    string            `mapstructure:"severity" json:"severity,omitempty"`
+	Subject     PeerSubjectConfig `mapstructure:"subject" json:"subject"`
+	Expression  string            `mapstructure:"expression" json:"expression"`
+}
+
+type MetricsConfig struct {
+	KeyExpiryHorizons []time.Duration `mapstructure:"key_expiry_horizons" json:"key_expiry_horizons"`
+}
+
 type TSNetConfig struct {
 	Hostname                 string        `mapstructure:"hostname" json:"hostname"`
 	StateDir                 string        `mapstructure:"state_dir" json:"state_dir"`
@@ -122,9 +299,13 @@ const (
 	envVarTSNetIDToken      = "SENTINEL_TSNET_ID_TOKEN"
 	envVarTSNetAudience     = "SENTINEL_TSNET_AUDIENCE"
 	envVarDetectors         = "SENTINEL_DETECTORS"
+	customDetectorsKey      = "custom"
+	processDetectorsKey     = "process"
 	envVarDetectorOrder     = "SENTINEL_DETECTOR_ORDER"
 	envVarNotifierSinks     = "SENTINEL_NOTIFIER_SINKS"
 	envVarNotifierRoutes    = "SENTINEL_NOTIFIER_ROUTES"
+	envVarSeverityRules     = "SENTINEL_SEVERITY_RULES"
+	envVarComplianceRules   = "SENTINEL_COMPLIANCE_RULES"
 
 	envVarNotifierRouteEventTypes       = "SENTINEL_NOTIFIER_ROUTE_EVENT_TYPES"
 	envVarNotifierRouteEventTypeLegacy  = "SENTINEL_NOTIFIER_ROUTE_EVENT_TYPE"
@@ -163,12 +344,23 @@ func Default() Config {
 		Source: SourceConfig{
 			Mode: "realtime",
 		},
+		Bootstrap: BootstrapFull,
 		Detectors: map[string]Detector{
-			"presence":     {Enabled: true},
-			"peer_changes": {Enabled: true},
-			"runtime":      {Enabled: true},
+			"presence":          {Enabled: true},
+			"peer_changes":      {Enabled: true},
+			"runtime":           {Enabled: true},
+			"key_expiry":        {Enabled: true},
+			"flapping":          {Enabled: false},
+			"prolonged_offline": {Enabled: true},
+			"route_overlap":     {Enabled: true},
+			"route_redundancy":  {Enabled: true},
+			"compliance":        {Enabled: true},
+			"drift":             {Enabled: true},
+			"mass_offline":      {Enabled: false},
+			"client_version":    {Enabled: true},
+			"self":              {Enabled: true},
 		},
-		DetectorOrder: []string{"presence", "peer_changes", "runtime"},
+		DetectorOrder: []string{"presence", "peer_changes", "runtime", "key_expiry", "flapping", "prolonged_offline", "route_overlap", "route_redundancy", "compliance", "drift", "mass_offline", "client_version", "self"},
 		Policy: PolicyConfig{
 			DebounceWindow:    3 * time.Second,
 			SuppressionWindow: 0,
@@ -198,6 +390,13 @@ func Default() Config {
 			AllowInteractiveFallback: false,
 			LoginTimeout:             5 * time.Minute,
 		},
+		Tracing: TracingConfig{
+			ServiceName: "sentinel",
+			SampleRatio: 1,
+		},
+		Metrics: MetricsConfig{
+			KeyExpiryHorizons: []time.Duration{24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour},
+		},
 	}
 }
 
@@ -207,6 +406,8 @@ func Load(path string) (Config, error) {
 		envVarDetectorOrder,
 		envVarNotifierSinks,
 		envVarNotifierRoutes,
+		envVarSeverityRules,
+		envVarComplianceRules,
 		envVarTSNetTags,
 		envVarTSNetClientSecret,
 		envVarTSNetClientID,
@@ -248,6 +449,8 @@ func Load(path string) (Config, error) {
 	v.SetDefault("poll_backoff_min", cfg.PollBackoffMin)
 	v.SetDefault("poll_backoff_max", cfg.PollBackoffMax)
 	v.SetDefault("source.mode", cfg.Source.Mode)
+	v.SetDefault("bootstrap", cfg.Bootstrap)
+	v.SetDefault("catchup.threshold", cfg.Catchup.Threshold)
 	v.SetDefault("detector_order", cfg.DetectorOrder)
 	v.SetDefault("output.log_format", cfg.Output.LogFormat)
 	v.SetDefault("output.log_level", cfg.Output.LogLevel)
@@ -257,11 +460,31 @@ func Load(path string) (Config, error) {
 	v.SetDefault("tsnet.login_mode", cfg.TSNet.LoginMode)
 	v.SetDefault("tsnet.allow_interactive_fallback", cfg.TSNet.AllowInteractiveFallback)
 	v.SetDefault("tsnet.login_timeout", cfg.TSNet.LoginTimeout)
+	v.SetDefault("server.listen_addr", cfg.Server.ListenAddr)
+	v.SetDefault("server.tailnet.enabled", cfg.Server.Tailnet.Enabled)
+	v.SetDefault("server.tailnet.tls", cfg.Server.Tailnet.TLS)
+	v.SetDefault("tracing.enabled", cfg.Tracing.Enabled)
+	v.SetDefault("tracing.endpoint", cfg.Tracing.Endpoint)
+	v.SetDefault("tracing.service_name", cfg.Tracing.ServiceName)
+	v.SetDefault("tracing.sample_ratio", cfg.Tracing.SampleRatio)
+	v.SetDefault("metrics.key_expiry_horizons", cfg.Metrics.KeyExpiryHorizons)
 	suppressStructuredEnvForViper(v)
+	var custom []CustomDetectorConfig
+	if err := extractDetectorList(v, customDetectorsKey, &custom); err != nil {
+		return cfg, err
+	}
+	var process []ProcessDetectorConfig
+	if err := extractDetectorList(v, processDetectorsKey, &process); err != nil {
+		return cfg, err
+	}
 
 	if err := v.Unmarshal(&cfg); err != nil {
 		return cfg, fmt.Errorf("unmarshal config: %w", err)
 	}
+	delete(cfg.Detectors, customDetectorsKey)
+	delete(cfg.Detectors, processDetectorsKey)
+	cfg.CustomDetectors = custom
+	cfg.ProcessDetectors = process
 	if err := applyStructuredEnvOverrides(&cfg); err != nil {
 		return cfg, err
 	}
@@ -445,14 +668,44 @@ func parseListFromAnyEnv(keys ...string) ([]string, bool, error) {
 	return nil, false, nil
 }
 
+// extractDetectorList decodes a list under detectors (custom, process) into
+// out and removes it from v, as its shape does not fit the Detector map the
+// rest of detectors uses.
+func extractDetectorList(v *viper.Viper, name string, out any) error {
+	key := "detectors." + name
+	raw := v.Get(key)
+	if raw == nil {
+		return nil
+	}
+	if err := decodeWithDurations(raw, out); err != nil {
+		return fmt.Errorf("decode %s: %w", key, err)
+	}
+	v.Set(key, map[string]any{})
+	return nil
+}
+
 func applyStructuredEnvOverrides(cfg *Config) error {
-	var detectors map[string]Detector
+	var detectors map[string]json.RawMessage
 	if present, err := decodeEnvJSON(envVarDetectors, &detectors); err != nil {
 		return err
 	} else if present {
-		cfg.Detectors = detectors
-		if cfg.Detectors == nil {
-			cfg.Detectors = map[string]Detector{}
+		cfg.Detectors = map[string]Detector{}
+		cfg.CustomDetectors = nil
+		cfg.ProcessDetectors = nil
+		for name, raw := range detectors {
+			var target any = new(Detector)
+			switch name {
+			case customDetectorsKey:
+				target = &cfg.CustomDetectors
+			case processDetectorsKey:
+				target = &cfg.ProcessDetectors
+			}
+			if err := json.Unmarshal(raw, target); err != nil {
+				return fmt.Errorf("parse %s: %w", envVarDetectors, err)
+			}
+			if detector, ok := target.(*Detector); ok {
+				cfg.Detectors[name] = *detector
+			}
 		}
 	}
 	var detectorOrder []string
@@ -473,6 +726,18 @@ func applyStructuredEnvOverrides(cfg *Config) error {
 	} else if present {
 		cfg.Notifier.Routes = routes
 	}
+	var severityRules []SeverityRuleConfig
+	if present, err := decodeEnvJSON(envVarSeverityRules, &severityRules); err != nil {
+		return err
+	} else if present {
+		cfg.Severity.Rules = severityRules
+	}
+	var complianceRules []ComplianceRuleConfig
+	if present, err := decodeEnvJSON(envVarComplianceRules, &complianceRules); err != nil {
+		return err
+	} else if present {
+		cfg.Compliance.Rules = complianceRules
+	}
 	return nil
 }
 
@@ -489,6 +754,12 @@ func suppressStructuredEnvForViper(v *viper.Viper) {
 	if _, ok := lookupNonEmptyEnv(envVarNotifierRoutes); ok {
 		v.Set("notifier.routes", []RouteConfig{})
 	}
+	if _, ok := lookupNonEmptyEnv(envVarSeverityRules); ok {
+		v.Set("severity.rules", []SeverityRuleConfig{})
+	}
+	if _, ok := lookupNonEmptyEnv(envVarComplianceRules); ok {
+		v.Set("compliance.rules", []ComplianceRuleConfig{})
+	}
 }
 
 func decodeEnvJSON(key string, target any) (bool, error) {
@@ -582,6 +853,11 @@ func applySensibleDefaults(cfg *Config) {
 		cfg.Source.Mode = def.Source.Mode
 	}
 
+	cfg.Bootstrap = strings.ToLower(strings.TrimSpace(cfg.Bootstrap))
+	if cfg.Bootstrap == "" {
+		cfg.Bootstrap = def.Bootstrap
+	}
+
 	cfg.State.Path = strings.TrimSpace(cfg.State.Path)
 	if cfg.State.Path == "" {
 		cfg.State.Path = def.State.Path
@@ -620,6 +896,45 @@ func applySensibleDefaults(cfg *Config) {
 	for i := range cfg.TSNet.AdvertiseTags {
 		cfg.TSNet.AdvertiseTags[i] = strings.TrimSpace(cfg.TSNet.AdvertiseTags[i])
 	}
+
+	cfg.Server.ListenAddr = strings.TrimSpace(cfg.Server.ListenAddr)
+
+	cfg.Tracing.Endpoint = strings.TrimSpace(cfg.Tracing.Endpoint)
+	cfg.Tracing.ServiceName = strings.TrimSpace(cfg.Tracing.ServiceName)
+	if cfg.Tracing.ServiceName == "" {
+		cfg.Tracing.ServiceName = def.Tracing.ServiceName
+	}
+
+	// Custom and process detectors run after the built-ins unless
+	// detector_order places them explicitly.
+	for i := range cfg.CustomDetectors {
+		custom := &cfg.CustomDetectors[i]
+		custom.Name = strings.TrimSpace(custom.Name)
+		custom.EventType = strings.TrimSpace(custom.EventType)
+		custom.Severity = strings.ToLower(strings.TrimSpace(custom.Severity))
+		appendDetectorOrder(cfg, custom.Name)
+	}
+	for i := range cfg.ProcessDetectors {
+		process := &cfg.ProcessDetectors[i]
+		process.Name = strings.TrimSpace(process.Name)
+		process.Command = strings.TrimSpace(process.Command)
+		for j := range process.EventTypes {
+			process.EventTypes[j] = strings.TrimSpace(process.EventTypes[j])
+		}
+		appendDetectorOrder(cfg, process.Name)
+	}
+	for i := range cfg.Compliance.Rules {
+		rule := &cfg.Compliance.Rules[i]
+		rule.Name = strings.TrimSpace(rule.Name)
+		rule.Description = strings.TrimSpace(rule.Description)
+		rule.Severity = strings.ToLower(strings.TrimSpace(rule.Severity))
+	}
+}
+
+func appendDetectorOrder(cfg *Config, name string) {
+	if name != "" && !slices.Contains(cfg.DetectorOrder, name) {
+		cfg.DetectorOrder = append(cfg.DetectorOrder, name)
+	}
 }
 
 func expandEnvPlaceholders(cfg *Config) {
@@ -641,11 +956,44 @@ func Validate(cfg Config) error {
 	if len(cfg.DetectorOrder) == 0 {
 		return fmt.Errorf("detector_order must not be empty")
 	}
+	// userNames and userTypes collect the detectors and event types defined
+	// by detectors.custom and detectors.process.
+	userNames := map[string]struct{}{}
+	userTypes := map[string]struct{}{}
+	for i, custom := range cfg.CustomDetectors {
+		if err := validateCustomDetector(i, custom, cfg.Detectors, userNames); err != nil {
+			return err
+		}
+		userNames[custom.Name] = struct{}{}
+		userTypes[custom.EventType] = struct{}{}
+	}
+	for i, process := range cfg.ProcessDetectors {
+		if err := validateProcessDetector(i, process, cfg.Detectors, userNames); err != nil {
+			return err
+		}
+		userNames[process.Name] = struct{}{}
+		for _, et := range process.EventTypes {
+			userTypes[et] = struct{}{}
+		}
+	}
+	isKnownType := func(eventType string) bool {
+		_, user := userTypes[eventType]
+		return user || event.IsKnownType(eventType)
+	}
 	for _, name := range cfg.DetectorOrder {
-		if _, ok := cfg.Detectors[name]; !ok {
+		_, builtin := cfg.Detectors[name]
+		_, user := userNames[name]
+		if !builtin && !user {
 			return fmt.Errorf("detector_order references unknown detector %q", name)
 		}
 	}
+	builtin, err := cfg.BuiltinOptions()
+	if err != nil {
+		return err
+	}
+	if err := builtin.validate(); err != nil {
+		return err
+	}
 	if cfg.State.Path == "" {
 		return fmt.Errorf("state.path is required")
 	}
@@ -700,6 +1048,58 @@ func Validate(cfg Config) error {
 	default:
 		return fmt.Errorf("source.mode must be realtime or poll")
 	}
+	switch strings.ToLower(strings.TrimSpace(cfg.Bootstrap)) {
+	case "", BootstrapSilent, BootstrapSummary, BootstrapFull:
+	default:
+		return fmt.Errorf("bootstrap must be silent, summary or full")
+	}
+	if cfg.Catchup.Threshold < 0 {
+		return fmt.Errorf("catchup.threshold must be >= 0")
+	}
+	if addr := strings.TrimSpace(cfg.Server.ListenAddr); addr != "" {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("server.listen_addr must be a host:port address")
+		}
+	}
+	if cfg.Server.Tailnet.Enabled && strings.TrimSpace(cfg.Server.ListenAddr) == "" {
+		return fmt.Errorf("server.listen_addr is required when server.tailnet.enabled is true")
+	}
+	for i, rawTag := range cfg.Server.Tailnet.AllowedTags {
+		if !advertiseTagPattern.MatchString(strings.TrimSpace(rawTag)) {
+			return fmt.Errorf("server.tailnet.allowed_tags[%d] must match tag:<name> format", i)
+		}
+	}
+	for i, rawUser := range cfg.Server.Tailnet.AllowedUsers {
+		if strings.TrimSpace(rawUser) == "" {
+			return fmt.Errorf("server.tailnet.allowed_users[%d] must not be empty", i)
+		}
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("tracing.sample_ratio must be between 0 and 1")
+	}
+	if endpoint := strings.TrimSpace(cfg.Tracing.Endpoint); endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("tracing.endpoint must be an http or https URL")
+		}
+	}
+	for i, horizon := range cfg.Metrics.KeyExpiryHorizons {
+		if horizon <= 0 {
+			return fmt.Errorf("metrics.key_expiry_horizons[%d] must be > 0", i)
+		}
+	}
+	for i, rule := range cfg.Severity.Rules {
+		if err := validateSeverityRule(i, rule, isKnownType); err != nil {
+			return err
+		}
+	}
+	complianceNames := map[string]struct{}{}
+	for i, rule := range cfg.Compliance.Rules {
+		if err := validateComplianceRule(i, rule, complianceNames); err != nil {
+			return err
+		}
+		complianceNames[rule.Name] = struct{}{}
+	}
 	for i, route := range cfg.Notifier.Routes {
 		if len(route.EventTypes) == 0 {
 			return fmt.Errorf("notifier.routes[%d].event_types must not be empty", i)
@@ -709,7 +1109,7 @@ func Validate(cfg Config) error {
 			if et == "" {
 				return fmt.Errorf("notifier.routes[%d].event_types[%d] must not be empty", i, j)
 			}
-			if et != "*" && !event.IsKnownType(et) {
+			if et != "*" && !isKnownType(et) {
 				return fmt.Errorf("notifier.routes[%d].event_types[%d] has unknown value %q", i, j, et)
 			}
 		}
@@ -719,10 +1119,10 @@ func Validate(cfg Config) error {
 		if err := validateRouteFilterConflicts(i, &route); err != nil {
 			return err
 		}
-		if err := validateNotificationFilter(i, "include", &route.Filters.Include); err != nil {
+		if err := validateNotificationFilter(i, "include", &route.Filters.Include, isKnownType); err != nil {
 			return err
 		}
-		if err := validateNotificationFilter(i, "exclude", &route.Filters.Exclude); err != nil {
+		if err := validateNotificationFilter(i, "exclude", &route.Filters.Exclude, isKnownType); err != nil {
 			return err
 		}
 	}
@@ -736,6 +1136,173 @@ func Validate(cfg Config) error {
 		if sinkType == "discord" && strings.TrimSpace(sink.URL) == "" {
 			return fmt.Errorf("notifier.sinks[%d].url is required for discord sink", i)
 		}
+		switch strings.TrimSpace(sink.SchemaVersion) {
+		case "", event.SchemaVersion, event.SchemaVersionV2:
+		default:
+			return fmt.Errorf("notifier.sinks[%d].schema_version must be %s or %s", i, event.SchemaVersion, event.SchemaVersionV2)
+		}
+		if err := validateSinkFormat(i, sinkType, sink); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateSinkFormat(index int, sinkType string, sink SinkConfig) error {
+	format := strings.ToLower(strings.TrimSpace(sink.Format))
+	switch format {
+	case "", "json":
+	case "cloudevents":
+		if sinkType != "" && sinkType != "webhook" {
+			return fmt.Errorf("notifier.sinks[%d].format cloudevents is only supported for webhook sinks", index)
+		}
+	default:
+		return fmt.Errorf("notifier.sinks[%d].format must be json or cloudevents", index)
+	}
+	if format != "cloudevents" {
+		if strings.TrimSpace(sink.CloudEventsMode) != "" {
+			return fmt.Errorf("notifier.sinks[%d].cloudevents_mode requires format cloudevents", index)
+		}
+		if strings.TrimSpace(sink.Source) != "" {
+			return fmt.Errorf("notifier.sinks[%d].source requires format cloudevents", index)
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(sink.CloudEventsMode)) {
+	case "", "structured", "binary":
+	default:
+		return fmt.Errorf("notifier.sinks[%d].cloudevents_mode must be structured or binary", index)
+	}
+	if source := strings.TrimSpace(sink.Source); source != "" {
+		if _, err := url.Parse(source); err != nil {
+			return fmt.Errorf("notifier.sinks[%d].source must be a URI reference: %w", index, err)
+		}
+	}
+	return nil
+}
+
+func validateSeverityRule(index int, rule SeverityRuleConfig, isKnownType func(string) bool) error {
+	sev := strings.ToLower(strings.TrimSpace(rule.Severity))
+	if !event.IsKnownSeverity(sev) {
+		return fmt.Errorf("severity.rules[%d].severity must be info, warning, or critical", index)
+	}
+	for j, raw := range rule.EventTypes {
+		et := strings.TrimSpace(raw)
+		if et == "" {
+			return fmt.Errorf("severity.rules[%d].event_types[%d] must not be empty", index, j)
+		}
+		if et != "*" && !isKnownType(et) {
+			return fmt.Errorf("severity.rules[%d].event_types[%d] has unknown value %q", index, j, et)
+		}
+	}
+	for j, raw := range rule.DeviceNames {
+		pattern := strings.TrimSpace(raw)
+		if pattern == "" {
+			return fmt.Errorf("severity.rules[%d].device_names[%d] must not be empty", index, j)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("severity.rules[%d].device_names[%d] has invalid glob pattern %q", index, j, pattern)
+		}
+	}
+	for key := range rule.Payload {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("severity.rules[%d].payload keys must not be empty", index)
+		}
+	}
+	return nil
+}
+
+func validateCustomDetector(index int, custom CustomDetectorConfig, builtins map[string]Detector, seen map[string]struct{}) error {
+	if err := validateUserDetectorName(fmt.Sprintf("detectors.custom[%d]", index), custom.Name, builtins, seen); err != nil {
+		return err
+	}
+	if custom.EventType == "" {
+		return fmt.Errorf("detectors.custom[%d].event_type is required", index)
+	}
+	if event.IsKnownType(custom.EventType) {
+		return fmt.Errorf("detectors.custom[%d].event_type %q is a built-in event type", index, custom.EventType)
+	}
+	if custom.Severity != "" && !event.IsKnownSeverity(custom.Severity) {
+		return fmt.Errorf("detectors.custom[%d].severity must be info, warning, or critical", index)
+	}
+	if err := validatePeerSubject(fmt.Sprintf("detectors.custom[%d].subject", index), custom.Subject); err != nil {
+		return err
+	}
+	if strings.TrimSpace(custom.Expression) == "" {
+		return fmt.Errorf("detectors.custom[%d].expression is required", index)
+	}
+	if _, err := expr.CompilePeerChange(custom.Expression); err != nil {
+		return fmt.Errorf("detectors.custom[%d].expression is invalid: %w", index, err)
+	}
+	return nil
+}
+
+func validateProcessDetector(index int, process ProcessDetectorConfig, builtins map[string]Detector, seen map[string]struct{}) error {
+	if err := validateUserDetectorName(fmt.Sprintf("detectors.process[%d]", index), process.Name, builtins, seen); err != nil {
+		return err
+	}
+	if process.Command == "" {
+		return fmt.Errorf("detectors.process[%d].command is required", index)
+	}
+	if process.Timeout < 0 {
+		return fmt.Errorf("detectors.process[%d].timeout must be >= 0", index)
+	}
+	if len(process.EventTypes) == 0 {
+		return fmt.Errorf("detectors.process[%d].event_types must not be empty", index)
+	}
+	for j, et := range process.EventTypes {
+		if et == "" || et == "*" {
+			return fmt.Errorf("detectors.process[%d].event_types[%d] must name an event type", index, j)
+		}
+	}
+	return nil
+}
+
+func validateComplianceRule(index int, rule ComplianceRuleConfig, seen map[string]struct{}) error {
+	if rule.Name == "" {
+		return fmt.Errorf("compliance.rules[%d].name is required", index)
+	}
+	if _, ok := seen[rule.Name]; ok {
+		return fmt.Errorf("compliance.rules[%d].name %q is duplicated", index, rule.Name)
+	}
+	if rule.Severity != "" && !event.IsKnownSeverity(rule.Severity) {
+		return fmt.Errorf("compliance.rules[%d].severity must be info, warning, or critical", index)
+	}
+	if err := validatePeerSubject(fmt.Sprintf("compliance.rules[%d].subject", index), rule.Subject); err != nil {
+		return err
+	}
+	if strings.TrimSpace(rule.Expression) == "" {
+		return fmt.Errorf("compliance.rules[%d].expression is required", index)
+	}
+	if _, err := expr.CompilePeer(rule.Expression); err != nil {
+		return fmt.Errorf("compliance.rules[%d].expression is invalid: %w", index, err)
+	}
+	return nil
+}
+
+func validatePeerSubject(field string, subject PeerSubjectConfig) error {
+	for j, raw := range subject.DeviceNames {
+		pattern := strings.TrimSpace(raw)
+		if pattern == "" {
+			return fmt.Errorf("%s.device_names[%d] must not be empty", field, j)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%s.device_names[%d] has invalid glob pattern %q", field, j, pattern)
+		}
+	}
+	return nil
+}
+
+// validateUserDetectorName checks a detectors.custom or detectors.process
+// name is set and unique among all detectors.
+func validateUserDetectorName(field, name string, builtins map[string]Detector, seen map[string]struct{}) error {
+	if name == "" {
+		return fmt.Errorf("%s.name is required", field)
+	}
+	if _, ok := builtins[name]; ok {
+		return fmt.Errorf("%s.name %q conflicts with a built-in detector", field, name)
+	}
+	if _, ok := seen[name]; ok {
+		return fmt.Errorf("%s.name %q is duplicated", field, name)
 	}
 	return nil
 }
@@ -753,7 +1320,7 @@ func validateRouteFilterConflicts(routeIndex int, route *RouteConfig) error {
 	return nil
 }
 
-func validateNotificationFilter(routeIndex int, filterName string, filter *NotificationFilterConfig) error {
+func validateNotificationFilter(routeIndex int, filterName string, filter *NotificationFilterConfig, isKnownType func(string) bool) error {
 	for j, raw := range filter.DeviceNames {
 		name := strings.TrimSpace(raw)
 		if name == "" {
@@ -787,7 +1354,7 @@ func validateNotificationFilter(routeIndex int, filterName string, filter *Notif
 		if eventType == "" {
 			return fmt.Errorf("notifier.routes[%d].filters.%s.events[%d] must not be empty", routeIndex, filterName, j)
 		}
-		if eventType != "*" && !event.IsKnownType(eventType) {
+		if eventType != "*" && !isKnownType(eventType) {
 			return fmt.Errorf("notifier.routes[%d].filters.%s.events[%d] has unknown value %q", routeIndex, filterName, j, eventType)
 		}
 	}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -808,3 +809,727 @@ func TestLoadIgnoresEmptyOAuthCredentialEnvOverrides(t *testing.T) {
 		t.Fatalf("expected file client_id to be preserved, got %q", cfg.TSNet.ClientID)
 	}
 }
+
+func TestValidateServerListenAddr(t *testing.T) {
+	cfg := Default()
+	cfg.Server.ListenAddr = ":9090"
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected listen addr to validate, got %v", err)
+	}
+
+	cfg.Server.ListenAddr = "9090"
+	err := Validate(cfg)
+	if err == nil {
+		t.Fatal("expected validation error for listen addr without port separator")
+	}
+	if !strings.Contains(err.Error(), "server.listen_addr") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadServerListenAddrFromEnv(t *testing.T) {
+	t.Setenv("SENTINEL_SERVER_LISTEN_ADDR", "127.0.0.1:9090")
+	t.Setenv("SENTINEL_STATE_PATH", filepath.Join(t.TempDir(), "state.json"))
+	cfg, err := Load(writeEmptyConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.ListenAddr != "127.0.0.1:9090" {
+		t.Fatalf("expected listen addr from env, got %q", cfg.Server.ListenAddr)
+	}
+}
+
+func writeEmptyConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sentinel.yaml")
+	if err := os.WriteFile(path, []byte("{}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidateServerTailnetRequiresListenAddr(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Tailnet.Enabled = true
+	err := Validate(cfg)
+	if err == nil {
+		t.Fatal("expected validation error for tailnet server without listen addr")
+	}
+	if !strings.Contains(err.Error(), "server.listen_addr is required") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg.Server.ListenAddr = ":9090"
+	cfg.Server.Tailnet.AllowedTags = []string{"monitoring"}
+	err = Validate(cfg)
+	if err == nil {
+		t.Fatal("expected validation error for malformed allowed tag")
+	}
+	if !strings.Contains(err.Error(), "server.tailnet.allowed_tags[0]") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg.Server.Tailnet.AllowedTags = []string{"tag:monitoring"}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected tailnet server config to validate, got %v", err)
+	}
+}
+
+func TestLoadTracingFromEnv(t *testing.T) {
+	t.Setenv("SENTINEL_TRACING_ENABLED", "true")
+	t.Setenv("SENTINEL_TRACING_ENDPOINT", "http://otel-collector:4318")
+	t.Setenv("SENTINEL_TRACING_SAMPLE_RATIO", "0.25")
+	t.Setenv("SENTINEL_STATE_PATH", filepath.Join(t.TempDir(), "state.json"))
+	cfg, err := Load(writeEmptyConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Tracing.Enabled || cfg.Tracing.Endpoint != "http://otel-collector:4318" {
+		t.Fatalf("expected tracing settings from env, got %+v", cfg.Tracing)
+	}
+	if cfg.Tracing.SampleRatio != 0.25 {
+		t.Fatalf("expected sample ratio 0.25, got %v", cfg.Tracing.SampleRatio)
+	}
+	if cfg.Tracing.ServiceName != "sentinel" {
+		t.Fatalf("expected default service name, got %q", cfg.Tracing.ServiceName)
+	}
+}
+
+func TestValidateTracing(t *testing.T) {
+	cfg := Default()
+	cfg.Tracing.SampleRatio = 1.5
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "tracing.sample_ratio") {
+		t.Fatalf("expected sample ratio validation error, got %v", err)
+	}
+
+	cfg = Default()
+	cfg.Tracing.Endpoint = "otel-collector:4318"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "tracing.endpoint") {
+		t.Fatalf("expected endpoint validation error, got %v", err)
+	}
+}
+
+func TestLoadMetricsKeyExpiryHorizons(t *testing.T) {
+	t.Setenv("SENTINEL_STATE_PATH", filepath.Join(t.TempDir(), "state.json"))
+	cfg, err := Load(writeEmptyConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Metrics.KeyExpiryHorizons) != 3 || cfg.Metrics.KeyExpiryHorizons[0] != 24*time.Hour {
+		t.Fatalf("expected default horizons, got %v", cfg.Metrics.KeyExpiryHorizons)
+	}
+
+	path := filepath.Join(t.TempDir(), "sentinel.yaml")
+	if err := os.WriteFile(path, []byte("metrics:\n  key_expiry_horizons: [\"12h\", \"72h\"]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{12 * time.Hour, 72 * time.Hour}; !reflect.DeepEqual(cfg.Metrics.KeyExpiryHorizons, want) {
+		t.Fatalf("expected horizons %v from file, got %v", want, cfg.Metrics.KeyExpiryHorizons)
+	}
+
+	t.Setenv("SENTINEL_METRICS_KEY_EXPIRY_HORIZONS", "1h,48h")
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{time.Hour, 48 * time.Hour}; !reflect.DeepEqual(cfg.Metrics.KeyExpiryHorizons, want) {
+		t.Fatalf("expected horizons %v from env, got %v", want, cfg.Metrics.KeyExpiryHorizons)
+	}
+}
+
+func TestValidateMetricsKeyExpiryHorizons(t *testing.T) {
+	cfg := Default()
+	cfg.Metrics.KeyExpiryHorizons = []time.Duration{24 * time.Hour, 0}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "metrics.key_expiry_horizons[1]") {
+		t.Fatalf("expected horizon validation error, got %v", err)
+	}
+}
+
+func TestValidateSeverityRules(t *testing.T) {
+	cfg := Default()
+	cfg.Severity.Rules = []SeverityRuleConfig{{EventTypes: []string{"peer.offline"}, Tags: []string{"tag:prod"}, Severity: "critical"}}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected valid severity rule, got %v", err)
+	}
+
+	cfg.Severity.Rules = []SeverityRuleConfig{{EventTypes: []string{"peer.offline"}, Severity: "page"}}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "severity.rules[0].severity") {
+		t.Fatalf("expected severity value error, got %v", err)
+	}
+
+	cfg.Severity.Rules = []SeverityRuleConfig{{EventTypes: []string{"peer.gone"}, Severity: "warning"}}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "severity.rules[0].event_types[0]") {
+		t.Fatalf("expected unknown event type error, got %v", err)
+	}
+
+	cfg.Severity.Rules = []SeverityRuleConfig{{DeviceNames: []string{"db-["}, Severity: "warning"}}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "severity.rules[0].device_names[0]") {
+		t.Fatalf("expected glob pattern error, got %v", err)
+	}
+}
+
+func TestLoadSeverityRulesFromFileAndEnv(t *testing.T) {
+	t.Setenv("SENTINEL_STATE_PATH", filepath.Join(t.TempDir(), "state.json"))
+	path := filepath.Join(t.TempDir(), "sentinel.yaml")
+	content := "severity:\n  rules:\n    - name: prod-offline\n      event_types: [\"peer.offline\"]\n      tags: [\"tag:prod\"]\n      payload:\n        online: false\n      severity: critical\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Severity.Rules) != 1 || cfg.Severity.Rules[0].Severity != "critical" {
+		t.Fatalf("expected severity rule from file, got %+v", cfg.Severity.Rules)
+	}
+	if got := cfg.Severity.Rules[0].Payload["online"]; got != false {
+		t.Fatalf("expected payload online=false, got %#v", got)
+	}
+
+	t.Setenv("SENTINEL_SEVERITY_RULES", `[{"event_types":["peer.key_expired"],"severity":"warning"}]`)
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Severity.Rules) != 1 || cfg.Severity.Rules[0].EventTypes[0] != "peer.key_expired" {
+		t.Fatalf("expected env severity rules to replace file rules, got %+v", cfg.Severity.Rules)
+	}
+}
+
+func TestValidateSinkSchemaVersion(t *testing.T) {
+	cfg := Default()
+	cfg.Notifier.Sinks = []SinkConfig{{Name: "hook", Type: "webhook", URL: "https://example.com", SchemaVersion: "v2"}}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected schema_version v2 to validate, got %v", err)
+	}
+	cfg.Notifier.Sinks[0].SchemaVersion = "v3"
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "notifier.sinks[0].schema_version must be v1 or v2") {
+		t.Fatalf("expected schema_version validation error, got %v", err)
+	}
+}
+
+func TestValidateSinkCloudEventsFormat(t *testing.T) {
+	cfg := Default()
+	cfg.Notifier.Sinks = []SinkConfig{{Name: "hook", Type: "webhook", URL: "https://example.com", Format: "cloudevents", CloudEventsMode: "binary", Source: "https://sentinel.example.com"}}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected cloudevents webhook sink to validate, got %v", err)
+	}
+
+	cases := map[string]SinkConfig{
+		"format must be json or cloudevents":                     {Type: "webhook", Format: "xml"},
+		"cloudevents_mode must be structured or binary":          {Type: "webhook", Format: "cloudevents", CloudEventsMode: "batch"},
+		"format cloudevents is only supported for webhook sinks": {Type: "stdout", Format: "cloudevents"},
+		"cloudevents_mode requires format cloudevents":           {Type: "webhook", CloudEventsMode: "binary"},
+		"source requires format cloudevents":                     {Type: "webhook", Format: "json", Source: "https://sentinel.example.com"},
+	}
+	for want, sink := range cases {
+		cfg := Default()
+		cfg.Notifier.Sinks = []SinkConfig{sink}
+		err := Validate(cfg)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q error, got %v", want, err)
+		}
+	}
+}
+
+func TestLoadKeyExpiryThresholds(t *testing.T) {
+	t.Setenv("SENTINEL_STATE_PATH", filepath.Join(t.TempDir(), "state.json"))
+	path := filepath.Join(t.TempDir(), "sentinel.yaml")
+	if err := os.WriteFile(path, []byte("detectors:\n  key_expiry:\n    enabled: true\n    thresholds: [\"336h\", \"48h\"]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtin, err := cfg.BuiltinOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{336 * time.Hour, 48 * time.Hour}; !reflect.DeepEqual(builtin.KeyExpiry.Thresholds, want) {
+		t.Fatalf("expected thresholds %v from file, got %v", want, builtin.KeyExpiry.Thresholds)
+	}
+
+	t.Setenv("SENTINEL_DETECTORS", `{"key_expiry":{"enabled":true,"thresholds":["12h"]}}`)
+	t.Setenv("SENTINEL_DETECTOR_ORDER", `["key_expiry"]`)
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if builtin, err = cfg.BuiltinOptions(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{12 * time.Hour}; !reflect.DeepEqual(builtin.KeyExpiry.Thresholds, want) {
+		t.Fatalf("expected thresholds %v from env, got %v", want, builtin.KeyExpiry.Thresholds)
+	}
+}
+
+func TestValidateKeyExpiryThresholds(t *testing.T) {
+	cfg := Default()
+	cfg.Detectors["key_expiry"] = Detector{Enabled: true, Options: map[string]any{"thresholds": []time.Duration{time.Hour, -time.Hour}}}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "detectors.key_expiry.thresholds[1]") {
+		t.Fatalf("expected threshold validation error, got %v", err)
+	}
+}
+
+func TestValidateFlappingOptions(t *testing.T) {
+	cfg := Default()
+	if cfg.Detectors["flapping"].Enabled {
+		t.Fatal("expected flapping to be disabled by default")
+	}
+	cfg.Detectors["flapping"] = Detector{Enabled: true, Options: map[string]any{"window": "-1m"}}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "detectors.flapping.window") {
+		t.Fatalf("expected window validation error, got %v", err)
+	}
+	cfg.Detectors["flapping"] = Detector{Enabled: true, Options: map[string]any{"transitions": -1}}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "detectors.flapping.transitions") {
+		t.Fatalf("expected transitions validation error, got %v", err)
+	}
+}
+
+func TestLoadProlongedOfflineFromEnvJSON(t *testing.T) {
+	t.Setenv("SENTINEL_STATE_PATH", filepath.Join(t.TempDir(), "state.json"))
+	t.Setenv("SENTINEL_DETECTORS", `{"prolonged_offline":{"enabled":true,"threshold":"30m","tag_thresholds":{"tag:server":"5m"}},"flapping":{"enabled":true,"window":"2m","transitions":3}}`)
+	t.Setenv("SENTINEL_DETECTOR_ORDER", `["prolonged_offline","flapping"]`)
+	cfg, err := Load(writeEmptyConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtin, err := cfg.BuiltinOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	offline := builtin.ProlongedOffline
+	if offline.Threshold != 30*time.Minute || offline.TagThresholds["tag:server"] != 5*time.Minute {
+		t.Fatalf("unexpected prolonged_offline options: %+v", offline)
+	}
+	if flapping := builtin.Flapping; flapping.Window != 2*time.Minute || flapping.Transitions != 3 {
+		t.Fatalf("unexpected flapping options: %+v", flapping)
+	}
+}
+
+func TestValidateProlongedOfflineTagThresholds(t *testing.T) {
+	cfg := Default()
+	cfg.Detectors["prolonged_offline"] = Detector{Enabled: true, Options: map[string]any{"tag_thresholds": map[string]any{"tag:server": "0s"}}}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), `detectors.prolonged_offline.tag_thresholds["tag:server"]`) {
+		t.Fatalf("expected tag threshold validation error, got %v", err)
+	}
+}
+
+func TestLoadCustomDetectorsFromFile(t *testing.T) {
+	t.Setenv("SENTINEL_STATE_PATH", filepath.Join(t.TempDir(), "state.json"))
+	path := filepath.Join(t.TempDir(), "sentinel.yaml")
+	content := `
+detectors:
+  presence:
+    enabled: true
+  custom:
+    - name: db_down
+      event_type: custom.db.down
+      severity: critical
+      subject:
+        tags: ["tag:db"]
+      expression: 'before.online && !after.online'
+notifier:
+  routes:
+    - event_types: ["custom.db.down"]
+      sinks: ["stdout-debug"]
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg.Detectors["custom"]; ok {
+		t.Fatal("expected detectors.custom to be split out of detectors")
+	}
+	if len(cfg.CustomDetectors) != 1 {
+		t.Fatalf("expected one custom detector, got %+v", cfg.CustomDetectors)
+	}
+	custom := cfg.CustomDetectors[0]
+	if custom.Name != "db_down" || custom.EventType != "custom.db.down" || custom.Severity != "critical" || !reflect.DeepEqual(custom.Subject.Tags, []string{"tag:db"}) {
+		t.Fatalf("unexpected custom detector: %+v", custom)
+	}
+	if last := cfg.DetectorOrder[len(cfg.DetectorOrder)-1]; last != "db_down" {
+		t.Fatalf("expected custom detector appended to detector_order, got %v", cfg.DetectorOrder)
+	}
+}
+
+func TestLoadCustomDetectorsFromEnvJSON(t *testing.T) {
+	t.Setenv("SENTINEL_STATE_PATH", filepath.Join(t.TempDir(), "state.json"))
+	t.Setenv("SENTINEL_DETECTORS", `{"presence":{"enabled":true},"custom":[{"name":"os_change","event_type":"custom.os.changed","expression":"after.meta.os != before.meta.os"}]}`)
+	t.Setenv("SENTINEL_DETECTOR_ORDER", `["os_change","presence"]`)
+	cfg, err := Load(writeEmptyConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.CustomDetectors) != 1 || cfg.CustomDetectors[0].Name != "os_change" {
+		t.Fatalf("unexpected custom detectors: %+v", cfg.CustomDetectors)
+	}
+	if want := []string{"os_change", "presence"}; !reflect.DeepEqual(cfg.DetectorOrder, want) {
+		t.Fatalf("expected detector_order %v, got %v", want, cfg.DetectorOrder)
+	}
+}
+
+func TestValidateCustomDetectors(t *testing.T) {
+	valid := CustomDetectorConfig{Name: "db_down", EventType: "custom.db.down", Expression: "!after.online"}
+	cases := []struct {
+		mutate func(*CustomDetectorConfig)
+		want   string
+	}{
+		{func(c *CustomDetectorConfig) { c.Name = "" }, "detectors.custom[0].name is required"},
+		{func(c *CustomDetectorConfig) { c.Name = "presence" }, "conflicts with a built-in detector"},
+		{func(c *CustomDetectorConfig) { c.EventType = "peer.offline" }, "is a built-in event type"},
+		{func(c *CustomDetectorConfig) { c.Severity = "loud" }, "detectors.custom[0].severity"},
+		{func(c *CustomDetectorConfig) { c.Subject.DeviceNames = []string{"["} }, "invalid glob pattern"},
+		{func(c *CustomDetectorConfig) { c.Expression = "after.online &&" }, "detectors.custom[0].expression is invalid"},
+	}
+	for _, tc := range cases {
+		cfg := Default()
+		custom := valid
+		tc.mutate(&custom)
+		cfg.CustomDetectors = []CustomDetectorConfig{custom}
+		if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("expected error containing %q, got %v", tc.want, err)
+		}
+	}
+
+	cfg := Default()
+	cfg.CustomDetectors = []CustomDetectorConfig{valid, valid}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "is duplicated") {
+		t.Fatalf("expected duplicate name error, got %v", err)
+	}
+}
+
+func TestLoadProcessDetectors(t *testing.T) {
+	t.Setenv("SENTINEL_STATE_PATH", filepath.Join(t.TempDir(), "state.json"))
+	path := filepath.Join(t.TempDir(), "sentinel.yaml")
+	content := `
+detectors:
+  presence:
+    enabled: true
+  process:
+    - name: history
+      command: /usr/local/bin/history-detector
+      args: ["--window", "7d"]
+      timeout: 5s
+      event_types: ["custom.history.anomaly"]
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg.Detectors["process"]; ok {
+		t.Fatal("expected detectors.process to be split out of detectors")
+	}
+	want := []ProcessDetectorConfig{{
+		Name:       "history",
+		Command:    "/usr/local/bin/history-detector",
+		Args:       []string{"--window", "7d"},
+		Timeout:    5 * time.Second,
+		EventTypes: []string{"custom.history.anomaly"},
+	}}
+	if !reflect.DeepEqual(cfg.ProcessDetectors, want) {
+		t.Fatalf("expected %+v, got %+v", want, cfg.ProcessDetectors)
+	}
+	if last := cfg.DetectorOrder[len(cfg.DetectorOrder)-1]; last != "history" {
+		t.Fatalf("expected process detector appended to detector_order, got %v", cfg.DetectorOrder)
+	}
+
+	t.Setenv("SENTINEL_DETECTORS", `{"presence":{"enabled":true},"process":[{"name":"env","command":"/bin/true","timeout":"2s","event_types":["custom.env"]}]}`)
+	t.Setenv("SENTINEL_DETECTOR_ORDER", `["presence"]`)
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.ProcessDetectors) != 1 || cfg.ProcessDetectors[0].Timeout != 2*time.Second {
+		t.Fatalf("unexpected process detectors from env: %+v", cfg.ProcessDetectors)
+	}
+	if want := []string{"presence", "env"}; !reflect.DeepEqual(cfg.DetectorOrder, want) {
+		t.Fatalf("expected detector_order %v, got %v", want, cfg.DetectorOrder)
+	}
+}
+
+func TestValidateProcessDetectors(t *testing.T) {
+	valid := ProcessDetectorConfig{Name: "history", Command: "/bin/true", EventTypes: []string{"custom.history"}}
+	cases := []struct {
+		mutate func(*ProcessDetectorConfig)
+		want   string
+	}{
+		{func(p *ProcessDetectorConfig) { p.Name = "runtime" }, "detectors.process[0].name \"runtime\" conflicts"},
+		{func(p *ProcessDetectorConfig) { p.Command = "" }, "detectors.process[0].command is required"},
+		{func(p *ProcessDetectorConfig) { p.Timeout = -time.Second }, "detectors.process[0].timeout"},
+		{func(p *ProcessDetectorConfig) { p.EventTypes = nil }, "detectors.process[0].event_types must not be empty"},
+		{func(p *ProcessDetectorConfig) { p.EventTypes = []string{"*"} }, "detectors.process[0].event_types[0]"},
+	}
+	for _, tc := range cases {
+		cfg := Default()
+		process := valid
+		tc.mutate(&process)
+		cfg.ProcessDetectors = []ProcessDetectorConfig{process}
+		if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("expected error containing %q, got %v", tc.want, err)
+		}
+	}
+
+	cfg := Default()
+	cfg.ProcessDetectors = []ProcessDetectorConfig{valid}
+	cfg.Notifier.Routes = []RouteConfig{{EventTypes: []string{"custom.history"}, Sinks: []string{"stdout-debug"}}}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected process event types to be routable, got %v", err)
+	}
+}
+
+func TestValidateRouteOverlapHAPairs(t *testing.T) {
+	cfg := Default()
+	cfg.Detectors["route_overlap"] = Detector{Enabled: true, Options: map[string]any{"ha_pairs": []any{map[string]any{}}}}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "detectors.route_overlap.ha_pairs[0] must set tags or names") {
+		t.Fatalf("expected empty ha_pairs validation error, got %v", err)
+	}
+	cfg.Detectors["route_overlap"] = Detector{Enabled: true, Options: map[string]any{"ha_pairs": []any{map[string]any{"names": []any{"["}}}}}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "detectors.route_overlap.ha_pairs[0].names[0]") {
+		t.Fatalf("expected ha_pairs glob validation error, got %v", err)
+	}
+	cfg.Detectors["route_overlap"] = Detector{Enabled: true, Options: map[string]any{"ha_pairs": []any{map[string]any{"tags": []any{"tag:router"}}, map[string]any{"names": []any{"router-*"}}}}}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected valid ha_pairs, got %v", err)
+	}
+}
+
+func TestValidateRouteRedundancyCriticalPrefixes(t *testing.T) {
+	cfg := Default()
+	cfg.Detectors["route_redundancy"] = Detector{Enabled: true, Options: map[string]any{"critical_prefixes": []any{"10.20.0.0/16", "10.30.0.0"}}}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "detectors.route_redundancy.critical_prefixes[1]") {
+		t.Fatalf("expected critical prefix validation error, got %v", err)
+	}
+}
+
+func TestLoadComplianceRulesFromFileAndEnv(t *testing.T) {
+	t.Setenv("SENTINEL_STATE_PATH", filepath.Join(t.TempDir(), "state.json"))
+	path := filepath.Join(t.TempDir(), "sentinel.yaml")
+	content := `
+compliance:
+  rules:
+    - name: prod-authorized
+      description: Production peers must be machine-authorized
+      severity: Critical
+      subject:
+        tags: ["tag:prod"]
+      expression: 'peer.machine_authorized'
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Compliance.Rules) != 1 {
+		t.Fatalf("expected one compliance rule, got %+v", cfg.Compliance.Rules)
+	}
+	rule := cfg.Compliance.Rules[0]
+	if rule.Name != "prod-authorized" || rule.Severity != "critical" || !reflect.DeepEqual(rule.Subject.Tags, []string{"tag:prod"}) {
+		t.Fatalf("unexpected compliance rule: %+v", rule)
+	}
+
+	t.Setenv("SENTINEL_COMPLIANCE_RULES", `[{"name":"exit-tagged","expression":"!(\"0.0.0.0/0\" in peer.advertised_routes) || \"tag:exit\" in peer.tags"}]`)
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Compliance.Rules) != 1 || cfg.Compliance.Rules[0].Name != "exit-tagged" {
+		t.Fatalf("expected env rules to replace file rules, got %+v", cfg.Compliance.Rules)
+	}
+}
+
+func TestValidateComplianceRules(t *testing.T) {
+	valid := ComplianceRuleConfig{Name: "prod-authorized", Expression: "peer.machine_authorized"}
+	cases := []struct {
+		mutate func(*ComplianceRuleConfig)
+		want   string
+	}{
+		{func(r *ComplianceRuleConfig) { r.Name = "" }, "compliance.rules[0].name is required"},
+		{func(r *ComplianceRuleConfig) { r.Severity = "loud" }, "compliance.rules[0].severity"},
+		{func(r *ComplianceRuleConfig) { r.Subject.DeviceNames = []string{"["} }, "compliance.rules[0].subject.device_names[0] has invalid glob pattern"},
+		{func(r *ComplianceRuleConfig) { r.Expression = "" }, "compliance.rules[0].expression is required"},
+		{func(r *ComplianceRuleConfig) { r.Expression = "peer.online &&" }, "compliance.rules[0].expression is invalid"},
+	}
+	for _, tc := range cases {
+		cfg := Default()
+		rule := valid
+		tc.mutate(&rule)
+		cfg.Compliance.Rules = []ComplianceRuleConfig{rule}
+		if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("expected error containing %q, got %v", tc.want, err)
+		}
+	}
+
+	cfg := Default()
+	cfg.Compliance.Rules = []ComplianceRuleConfig{valid, valid}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "is duplicated") {
+		t.Fatalf("expected duplicate name error, got %v", err)
+	}
+}
+
+func TestValidateMassOfflineThresholds(t *testing.T) {
+	cfg := Default()
+	builtin, err := cfg.BuiltinOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Detectors["mass_offline"].Enabled || builtin.MassOffline.MinPeers != 10 {
+		t.Fatalf("unexpected mass_offline defaults: %+v", builtin.MassOffline)
+	}
+	cfg.Detectors["mass_offline"] = Detector{Enabled: true, Options: map[string]any{"min_peers": -1}}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "detectors.mass_offline.min_peers") {
+		t.Fatalf("expected min_peers error, got %v", err)
+	}
+	cfg.Detectors["mass_offline"] = Detector{Enabled: true, Options: map[string]any{"tag_percentages": map[string]any{"tag:office": 150}}}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), `detectors.mass_offline.tag_percentages["tag:office"]`) {
+		t.Fatalf("expected tag_percentages error, got %v", err)
+	}
+}
+
+func TestValidateBootstrapMode(t *testing.T) {
+	cfg := Default()
+	if cfg.Bootstrap != BootstrapFull {
+		t.Fatalf("expected default bootstrap full, got %q", cfg.Bootstrap)
+	}
+	for _, mode := range []string{BootstrapSilent, BootstrapSummary, BootstrapFull} {
+		cfg.Bootstrap = mode
+		if err := Validate(cfg); err != nil {
+			t.Fatalf("expected bootstrap %q to validate, got %v", mode, err)
+		}
+	}
+	cfg.Bootstrap = "quiet"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "bootstrap must be silent, summary or full") {
+		t.Fatalf("expected bootstrap validation error, got %v", err)
+	}
+}
+
+func TestLoadBootstrapFromEnv(t *testing.T) {
+	t.Setenv("SENTINEL_BOOTSTRAP", "Summary")
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Bootstrap != BootstrapSummary {
+		t.Fatalf("expected bootstrap summary from env, got %q", cfg.Bootstrap)
+	}
+}
+
+func TestValidateCatchupThreshold(t *testing.T) {
+	cfg := Default()
+	if cfg.Catchup.Threshold != 0 {
+		t.Fatalf("expected catch-up to be disabled by default, got threshold %s", cfg.Catchup.Threshold)
+	}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected zero catchup threshold to disable catch-up, got %v", err)
+	}
+	cfg.Catchup.Threshold = time.Hour
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected 1h catchup threshold to validate, got %v", err)
+	}
+	cfg.Catchup.Threshold = -time.Minute
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "catchup.threshold must be >= 0") {
+		t.Fatalf("expected catchup threshold validation error, got %v", err)
+	}
+}
+
+func TestValidateDetectorOptions(t *testing.T) {
+	cfg := Default()
+	cfg.Detectors["presence"] = Detector{Enabled: true, Options: map[string]any{"ignore_tags": []any{"tag:ci"}}}
+	cfg.Detectors["peer_changes"] = Detector{Enabled: true, Options: map[string]any{"changes": []any{"routes", "hostinfo"}, "ignore_hostinfo_fields": []any{"os_version"}}}
+	cfg.Detectors["runtime"] = Detector{Enabled: true, Options: map[string]any{"watch": []any{"shields_up"}}}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected detector options to validate, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		detector string
+		in       Detector
+		want     string
+	}{
+		{name: "foreign option", detector: "presence", in: Detector{Enabled: true, Options: map[string]any{"window": "1m"}}, want: "detectors.presence has invalid keys: window"},
+		{name: "foreign zero option", detector: "presence", in: Detector{Enabled: true, Options: map[string]any{"transitions": 0}}, want: "detectors.presence has invalid keys: transitions"},
+		{name: "option of another detector", detector: "self", in: Detector{Enabled: true, Options: map[string]any{"window": "1m"}}, want: "detectors.self has invalid keys: window"},
+		{name: "option without options", detector: "compliance", in: Detector{Enabled: true, Options: map[string]any{"thresholds": []any{}}}, want: "detectors.compliance has invalid keys: thresholds"},
+		{name: "bad type", detector: "flapping", in: Detector{Enabled: true, Options: map[string]any{"window": "soon"}}, want: "detectors.flapping.window "},
+		{name: "bad tag", detector: "presence", in: Detector{Options: map[string]any{"ignore_tags": []any{"ci"}}}, want: "detectors.presence.ignore_tags[0] must match tag:<name> format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Detectors[tt.detector] = tt.in
+			err := Validate(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadDetectorOptionsFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(`
+detectors:
+  presence:
+    enabled: true
+    ignore_tags: [tag:ci]
+  peer_changes:
+    enabled: true
+    changes: [routes, tags]
+  runtime:
+    enabled: true
+    watch: [daemon_state]
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtin, err := cfg.BuiltinOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := builtin.Presence.IgnoreTags; len(got) != 1 || got[0] != "tag:ci" {
+		t.Fatalf("unexpected presence ignore_tags: %#v", got)
+	}
+	if got := builtin.PeerChanges.Changes; len(got) != 2 {
+		t.Fatalf("unexpected peer_changes changes: %#v", got)
+	}
+	if got := builtin.Runtime.Watch; len(got) != 1 || got[0] != "daemon_state" {
+		t.Fatalf("unexpected runtime watch: %#v", got)
+	}
+	if err := Validate(cfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateClientVersionPolicy(t *testing.T) {
+	cfg := Default()
+	cfg.Detectors["client_version"] = Detector{Enabled: true, Options: map[string]any{"min_version": "1.80.2", "max_minor_behind": 4}}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected client_version policy to validate, got %v", err)
+	}
+	cfg.Detectors["client_version"] = Detector{Enabled: true, Options: map[string]any{"min_version": "latest"}}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "detectors.client_version.min_version must be a version") {
+		t.Fatalf("expected min_version validation error, got %v", err)
+	}
+	cfg.Detectors["client_version"] = Detector{Enabled: true, Options: map[string]any{"max_minor_behind": -1}}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "detectors.client_version.max_minor_behind must be >= 0") {
+		t.Fatalf("expected max_minor_behind validation error, got %v", err)
+	}
+}
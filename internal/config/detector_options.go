@@ -0,0 +1,254 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/jaxxstorm/sentinel/internal/clientversion"
+)
+
+// PresenceOptions configures the presence detector.
+type PresenceOptions struct {
+	// IgnoreTags are the tags of peers presence does not report, such as
+	// tagged ephemeral nodes.
+	IgnoreTags []string `mapstructure:"ignore_tags"`
+}
+
+// PeerChangesOptions configures the peer_changes detector.
+type PeerChangesOptions struct {
+	// Changes are the peer changes reported; empty reports all.
+	Changes []string `mapstructure:"changes"`
+	// IgnoreHostinfoFields are the Hostinfo fields not diffed.
+	IgnoreHostinfoFields []string `mapstructure:"ignore_hostinfo_fields"`
+}
+
+// RuntimeOptions configures the runtime detector.
+type RuntimeOptions struct {
+	// Watch are the local settings watched; empty watches all.
+	Watch []string `mapstructure:"watch"`
+}
+
+// KeyExpiryOptions configures the key_expiry and self detectors.
+type KeyExpiryOptions struct {
+	// Thresholds are the lead times at which to warn before a key expires.
+	Thresholds []time.Duration `mapstructure:"thresholds"`
+}
+
+// FlappingOptions configures the flapping detector: a peer with at least
+// Transitions online/offline changes within Window is flapping.
+type FlappingOptions struct {
+	Window      time.Duration `mapstructure:"window"`
+	Transitions int           `mapstructure:"transitions"`
+}
+
+// ProlongedOfflineOptions configures the prolonged_offline detector.
+type ProlongedOfflineOptions struct {
+	// Threshold is how long a peer must stay offline before the detector
+	// fires. TagThresholds override it for tagged peers.
+	Threshold     time.Duration            `mapstructure:"threshold"`
+	TagThresholds map[string]time.Duration `mapstructure:"tag_thresholds"`
+}
+
+// RouteOverlapOptions configures the route_overlap detector.
+type RouteOverlapOptions struct {
+	// HAPairs declare peers allowed to advertise overlapping routes.
+	HAPairs []HAPairConfig `mapstructure:"ha_pairs"`
+}
+
+// RouteRedundancyOptions configures the route_redundancy detector.
+type RouteRedundancyOptions struct {
+	// CriticalPrefixes are the subnets watched for lost router redundancy.
+	CriticalPrefixes []string `mapstructure:"critical_prefixes"`
+}
+
+// DriftOptions configures the drift detector.
+type DriftOptions struct {
+	// Inventory is the path of the declared inventory file snapshots are
+	// compared against.
+	Inventory string `mapstructure:"inventory"`
+}
+
+// MassOfflineOptions configures the mass_offline detector: at least
+// MinPeers peers, or TagPercentages of a tag's peers, going offline within
+// Window is a mass outage.
+type MassOfflineOptions struct {
+	Window         time.Duration  `mapstructure:"window"`
+	MinPeers       int            `mapstructure:"min_peers"`
+	TagPercentages map[string]int `mapstructure:"tag_percentages"`
+}
+
+// ClientVersionOptions configures the client_version detector: peers below
+// MinVersion, or more than MaxMinorBehind minor versions behind the newest
+// client in the tailnet, are outdated.
+type ClientVersionOptions struct {
+	MinVersion     string `mapstructure:"min_version"`
+	MaxMinorBehind int    `mapstructure:"max_minor_behind"`
+}
+
+// DetectorOptions holds the options of every built-in detector, decoded
+// from Detector.Options over their defaults.
+type DetectorOptions struct {
+	Presence         PresenceOptions
+	PeerChanges      PeerChangesOptions
+	Runtime          RuntimeOptions
+	KeyExpiry        KeyExpiryOptions
+	Flapping         FlappingOptions
+	ProlongedOffline ProlongedOfflineOptions
+	RouteOverlap     RouteOverlapOptions
+	RouteRedundancy  RouteRedundancyOptions
+	Drift            DriftOptions
+	MassOffline      MassOfflineOptions
+	ClientVersion    ClientVersionOptions
+	Self             KeyExpiryOptions
+}
+
+func defaultDetectorOptions() DetectorOptions {
+	return DetectorOptions{
+		KeyExpiry:        KeyExpiryOptions{Thresholds: []time.Duration{30 * 24 * time.Hour, 7 * 24 * time.Hour, 24 * time.Hour}},
+		Flapping:         FlappingOptions{Window: 10 * time.Minute, Transitions: 4},
+		ProlongedOffline: ProlongedOfflineOptions{Threshold: 15 * time.Minute},
+		MassOffline:      MassOfflineOptions{Window: 5 * time.Minute, MinPeers: 10},
+		Self:             KeyExpiryOptions{Thresholds: []time.Duration{30 * 24 * time.Hour, 7 * 24 * time.Hour, 24 * time.Hour}},
+	}
+}
+
+// targets maps each built-in detector to its options in o.
+// Detectors without options, such as compliance, map to an empty struct so
+// any option set on them is rejected.
+func (o *DetectorOptions) targets() map[string]any {
+	return map[string]any{
+		"presence":          &o.Presence,
+		"peer_changes":      &o.PeerChanges,
+		"runtime":           &o.Runtime,
+		"key_expiry":        &o.KeyExpiry,
+		"flapping":          &o.Flapping,
+		"prolonged_offline": &o.ProlongedOffline,
+		"route_overlap":     &o.RouteOverlap,
+		"route_redundancy":  &o.RouteRedundancy,
+		"compliance":        &struct{}{},
+		"drift":             &o.Drift,
+		"mass_offline":      &o.MassOffline,
+		"client_version":    &o.ClientVersion,
+		"self":              &o.Self,
+	}
+}
+
+// BuiltinOptions decodes the options of each built-in detector into its own
+// options type over the defaults. An option the detector does not accept is
+// an error.
+func (c Config) BuiltinOptions() (DetectorOptions, error) {
+	opts := defaultDetectorOptions()
+	targets := opts.targets()
+	for _, name := range slices.Sorted(maps.Keys(targets)) {
+		if err := decodeDetectorOptions(c.Detectors[name].Options, targets[name]); err != nil {
+			return DetectorOptions{}, detectorOptionsError(name, targets[name], err)
+		}
+	}
+	return opts, nil
+}
+
+// decodeDetectorOptions decodes raw into out the way config files are
+// decoded. Options set in raw replace the defaults in out, and keys out does
+// not declare are an error.
+func decodeDetectorOptions(raw map[string]any, out any) error {
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		WeaklyTypedInput: true,
+		ErrorUnused:      true,
+		ZeroFields:       true,
+		Result:           out,
+	})
+	if err != nil {
+		return err
+	}
+	return dec.Decode(raw)
+}
+
+// detectorOptionsError names the option of detector name that failed to
+// decode into target, such as "detectors.presence has invalid keys: window".
+func detectorOptionsError(name string, target any, err error) error {
+	var decodeErr *mapstructure.DecodeError
+	if !errors.As(err, &decodeErr) {
+		return fmt.Errorf("detectors.%s: %w", name, err)
+	}
+	field := "detectors." + name
+	if option := decodeErr.Name(); option != strings.TrimPrefix(fmt.Sprintf("%T", target), "*") {
+		field += "." + option
+	}
+	return fmt.Errorf("%s %w", field, decodeErr.Unwrap())
+}
+
+func (o DetectorOptions) validate() error {
+	for i, tag := range o.Presence.IgnoreTags {
+		if !advertiseTagPattern.MatchString(strings.TrimSpace(tag)) {
+			return fmt.Errorf("detectors.presence.ignore_tags[%d] must match tag:<name> format", i)
+		}
+	}
+	for name, thresholds := range map[string][]time.Duration{"key_expiry": o.KeyExpiry.Thresholds, "self": o.Self.Thresholds} {
+		for i, threshold := range thresholds {
+			if threshold <= 0 {
+				return fmt.Errorf("detectors.%s.thresholds[%d] must be > 0", name, i)
+			}
+		}
+	}
+	if o.Flapping.Window < 0 {
+		return fmt.Errorf("detectors.flapping.window must be >= 0")
+	}
+	if o.Flapping.Transitions < 0 {
+		return fmt.Errorf("detectors.flapping.transitions must be >= 0")
+	}
+	if o.ProlongedOffline.Threshold < 0 {
+		return fmt.Errorf("detectors.prolonged_offline.threshold must be >= 0")
+	}
+	for tag, threshold := range o.ProlongedOffline.TagThresholds {
+		if threshold <= 0 {
+			return fmt.Errorf("detectors.prolonged_offline.tag_thresholds[%q] must be > 0", tag)
+		}
+	}
+	for i, pair := range o.RouteOverlap.HAPairs {
+		if len(pair.Tags) == 0 && len(pair.Names) == 0 {
+			return fmt.Errorf("detectors.route_overlap.ha_pairs[%d] must set tags or names", i)
+		}
+		for j, raw := range pair.Names {
+			pattern := strings.TrimSpace(raw)
+			if _, err := path.Match(pattern, ""); pattern == "" || err != nil {
+				return fmt.Errorf("detectors.route_overlap.ha_pairs[%d].names[%d] has invalid glob pattern %q", i, j, pattern)
+			}
+		}
+	}
+	for i, raw := range o.RouteRedundancy.CriticalPrefixes {
+		if _, err := netip.ParsePrefix(strings.TrimSpace(raw)); err != nil {
+			return fmt.Errorf("detectors.route_redundancy.critical_prefixes[%d] must be a CIDR prefix", i)
+		}
+	}
+	if o.MassOffline.Window < 0 {
+		return fmt.Errorf("detectors.mass_offline.window must be >= 0")
+	}
+	if o.MassOffline.MinPeers < 0 {
+		return fmt.Errorf("detectors.mass_offline.min_peers must be >= 0")
+	}
+	for tag, percent := range o.MassOffline.TagPercentages {
+		if percent <= 0 || percent > 100 {
+			return fmt.Errorf("detectors.mass_offline.tag_percentages[%q] must be between 1 and 100", tag)
+		}
+	}
+	if raw := strings.TrimSpace(o.ClientVersion.MinVersion); raw != "" {
+		if _, ok := clientversion.Parse(raw); !ok {
+			return fmt.Errorf("detectors.client_version.min_version must be a version such as 1.80.0")
+		}
+	}
+	if o.ClientVersion.MaxMinorBehind < 0 {
+		return fmt.Errorf("detectors.client_version.max_minor_behind must be >= 0")
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package diff
+
+import (
+	"context"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/clientversion"
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+// ClientVersionDetector tracks the Tailscale client version of each peer. It
+// emits peer.client_version.changed when a peer's version changes, and
+// peer.client_outdated when a peer first falls foul of the policy, either
+// by being below the minimum or by trailing the newest version in the
+// tailnet by too many minor versions. A peer that stays outdated on the
+// same version stays quiet.
+type ClientVersionDetector struct {
+	policy clientversion.Policy
+	now    func() time.Time
+}
+
+func NewClientVersionDetector(policy clientversion.Policy) *ClientVersionDetector {
+	return &ClientVersionDetector{policy: policy, now: time.Now}
+}
+
+func (d *ClientVersionDetector) Name() string { return "client_version" }
+
+func (d *ClientVersionDetector) Detect(_ context.Context, before, after snapshot.Snapshot) ([]event.Event, error) {
+	prev := snapshot.IndexByPeerID(before)
+	next := snapshot.IndexByPeerID(after)
+	result := make([]event.Event, 0)
+
+	prevOutdated := map[string]string{}
+	if before.Hash != "" {
+		prevOutdated = d.outdated(before)
+	}
+	newest, _ := clientversion.Newest(after)
+	for _, id := range sortedPeerIDs(next) {
+		p := next[id]
+		v, ok := clientversion.Of(p)
+		if !ok {
+			continue
+		}
+		if old, exists := prev[id]; exists {
+			if ov, ok := clientversion.Of(old); ok && ov != v {
+				result = append(result, event.NewPeerEvent(
+					event.TypePeerClientVersionChanged,
+					id,
+					before.Hash,
+					after.Hash,
+					mergePayload(deviceIdentityPayload(p), map[string]any{
+						"before_version": ov.String(),
+						"after_version":  v.String(),
+					}),
+					d.now(),
+				))
+			}
+		}
+		reason, outdated := d.policy.Outdated(v, newest)
+		if !outdated || prevOutdated[id] == outdatedKey(v, reason) {
+			continue
+		}
+		payload := mergePayload(deviceIdentityPayload(p), map[string]any{
+			"version": v.String(),
+			"reason":  reason,
+			"newest":  newest.String(),
+		})
+		// Minor versions only compare within a major release.
+		if minors, ok := v.MinorsBehind(newest); ok {
+			payload["minors_behind"] = minors
+		} else {
+			payload["majors_behind"] = v.MajorsBehind(newest)
+		}
+		if d.policy.HasMinimum {
+			payload["minimum"] = d.policy.Minimum.String()
+		}
+		evt := event.NewPeerEvent(event.TypePeerClientOutdated, id, before.Hash, after.Hash, payload, d.now())
+		// A peer can become outdated again on another version, so scope IDs
+		// to the version and reason.
+		evt.EventID = event.DeriveScopedEventID(evt, outdatedKey(v, reason))
+		result = append(result, evt)
+	}
+	return result, nil
+}
+
+// outdated maps the IDs of the outdated peers of s to their outdatedKey.
+func (d *ClientVersionDetector) outdated(s snapshot.Snapshot) map[string]string {
+	out := map[string]string{}
+	newest, ok := clientversion.Newest(s)
+	if !ok {
+		return out
+	}
+	for _, p := range s.Peers {
+		v, ok := clientversion.Of(p)
+		if !ok {
+			continue
+		}
+		if reason, outdated := d.policy.Outdated(v, newest); outdated {
+			out[p.ID] = outdatedKey(v, reason)
+		}
+	}
+	return out
+}
+
+func outdatedKey(v clientversion.Version, reason string) string {
+	return v.String() + "|" + reason
+}
@@ -0,0 +1,103 @@
+package diff
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jaxxstorm/sentinel/internal/clientversion"
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+func versionPeer(id, version string) snapshot.Peer {
+	return snapshot.Peer{ID: id, Name: id, Hostinfo: &snapshot.Hostinfo{IPNVersion: version}}
+}
+
+func TestClientVersionDetectorReportsVersionChanges(t *testing.T) {
+	d := NewClientVersionDetector(clientversion.Policy{})
+	before := snapshot.Snapshot{Hash: "before", Peers: []snapshot.Peer{versionPeer("web", "1.80.2-tabc"), versionPeer("db", "1.80.2-tabc")}}
+	after := snapshot.Snapshot{Hash: "after", Peers: []snapshot.Peer{versionPeer("web", "1.82.0-tdef"), versionPeer("db", "1.80.2-tghi")}}
+
+	events, err := d.Detect(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypePeerClientVersionChanged || events[0].SubjectID != "web" {
+		t.Fatalf("expected one version change for web, got %#v", events)
+	}
+	if events[0].Payload["before_version"] != "1.80.2" || events[0].Payload["after_version"] != "1.82.0" {
+		t.Fatalf("unexpected payload: %#v", events[0].Payload)
+	}
+}
+
+func TestClientVersionDetectorReportsMajorsBehindForOlderMajor(t *testing.T) {
+	d := NewClientVersionDetector(clientversion.Policy{MaxMinorBehind: 2})
+	after := snapshot.Snapshot{Hash: "after", Peers: []snapshot.Peer{versionPeer("web", "2.0.0"), versionPeer("laptop", "1.82.0")}}
+
+	events, err := d.Detect(context.Background(), snapshot.Snapshot{}, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypePeerClientOutdated || events[0].SubjectID != "laptop" {
+		t.Fatalf("expected laptop outdated, got %#v", events)
+	}
+	p := events[0].Payload
+	if _, ok := p["minors_behind"]; ok || p["majors_behind"] != 1 {
+		t.Fatalf("expected majors_behind without minors_behind, got %#v", p)
+	}
+	v2 := event.ToV2(events[0]).Payload.(event.PeerClientOutdatedPayload)
+	if v2.MinorsBehind != nil || v2.MajorsBehind != 1 {
+		t.Fatalf("unexpected v2 payload: %#v", v2)
+	}
+}
+
+func TestClientVersionDetectorReportsOutdatedOnce(t *testing.T) {
+	d := NewClientVersionDetector(clientversion.Policy{MaxMinorBehind: 2})
+	before := snapshot.Snapshot{Hash: "before", Peers: []snapshot.Peer{versionPeer("web", "1.80.0"), versionPeer("laptop", "1.78.0")}}
+	// web upgrading to 1.82 leaves laptop 4 minor versions behind.
+	after := snapshot.Snapshot{Hash: "after", Peers: []snapshot.Peer{versionPeer("web", "1.82.0"), versionPeer("laptop", "1.78.0")}}
+
+	events, err := d.Detect(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outdated []event.Event
+	for _, evt := range events {
+		if evt.EventType == event.TypePeerClientOutdated {
+			outdated = append(outdated, evt)
+		}
+	}
+	if len(outdated) != 1 || outdated[0].SubjectID != "laptop" {
+		t.Fatalf("expected laptop outdated, got %#v", events)
+	}
+	p := outdated[0].Payload
+	if p["reason"] != clientversion.ReasonBehindNewest || p["newest"] != "1.82.0" || p["minors_behind"] != 4 {
+		t.Fatalf("unexpected payload: %#v", p)
+	}
+	if _, ok := p["minimum"]; ok {
+		t.Fatalf("expected no minimum without one configured: %#v", p)
+	}
+
+	after2 := after
+	after2.Hash = "after2"
+	events, err = d.Detect(context.Background(), after, after2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected a peer that stays outdated to stay quiet, got %#v", events)
+	}
+}
+
+func TestClientVersionDetectorReportsBelowMinimumOnStartup(t *testing.T) {
+	d := NewClientVersionDetector(clientversion.Policy{Minimum: clientversion.Version{Major: 1, Minor: 80, Patch: 1}, HasMinimum: true})
+	after := snapshot.Snapshot{Hash: "after", Peers: []snapshot.Peer{versionPeer("web", "1.80.0"), versionPeer("db", "1.80.1"), {ID: "printer"}}}
+
+	events, err := d.Detect(context.Background(), snapshot.Snapshot{}, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].SubjectID != "web" || events[0].Payload["minimum"] != "1.80.1" {
+		t.Fatalf("expected web below minimum, got %#v", events)
+	}
+}
@@ -0,0 +1,232 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/expr"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+	"go.uber.org/zap"
+)
+
+// Reasons reported by compliance.resolved.
+const (
+	ComplianceResolvedPassing     = "passing"
+	ComplianceResolvedPeerRemoved = "peer_removed"
+	ComplianceResolvedOutOfScope  = "out_of_scope"
+)
+
+// ComplianceRule is an invariant every selected peer must satisfy:
+// Expression is evaluated with the peer bound to peer and must be true.
+type ComplianceRule struct {
+	Name        string
+	Description string
+	Severity    string
+	Tags        []string
+	DeviceNames []string
+	Expression  string
+}
+
+// ComplianceDetector evaluates compliance rules against every snapshot. It
+// emits compliance.violation when a rule starts failing for a peer and
+// compliance.resolved when it passes again or the peer leaves the rule's
+// scope. Open violations are persisted so restarts neither repeat nor lose
+// them.
+type ComplianceDetector struct {
+	rules    []compiledComplianceRule
+	store    DetectorStateStore
+	state    complianceState
+	warnings evalWarnings
+	now      func() time.Time
+}
+
+// ComplianceOption customizes a ComplianceDetector.
+type ComplianceOption func(*ComplianceDetector)
+
+// WithComplianceLogger sets the logger warned the first time a rule fails to
+// evaluate. Without it the warning is discarded.
+func WithComplianceLogger(log *zap.Logger) ComplianceOption {
+	return func(d *ComplianceDetector) {
+		d.warnings = newEvalWarnings(log)
+	}
+}
+
+type compiledComplianceRule struct {
+	ComplianceRule
+	selector peerSelector
+	program  *expr.PeerProgram
+}
+
+// complianceState maps rule name to the peers currently violating it.
+type complianceState map[string]map[string]complianceRecord
+
+type complianceRecord struct {
+	Since time.Time `json:"since"`
+}
+
+// NewComplianceDetector compiles rules. When store is nil, open violations
+// are only kept in memory.
+func NewComplianceDetector(rules []ComplianceRule, store DetectorStateStore, opts ...ComplianceOption) (*ComplianceDetector, error) {
+	compiled := make([]compiledComplianceRule, 0, len(rules))
+	for _, rule := range rules {
+		program, err := expr.CompilePeer(rule.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("compliance rule %q: %w", rule.Name, err)
+		}
+		if strings.TrimSpace(rule.Severity) == "" {
+			rule.Severity = event.SeverityInfo
+		}
+		compiled = append(compiled, compiledComplianceRule{
+			ComplianceRule: rule,
+			selector:       peerSelector{tags: rule.Tags, deviceNames: rule.DeviceNames},
+			program:        program,
+		})
+	}
+	d := &ComplianceDetector{
+		rules:    compiled,
+		store:    store,
+		state:    complianceState{},
+		warnings: newEvalWarnings(nil),
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d, nil
+}
+
+func (d *ComplianceDetector) Name() string { return "compliance" }
+
+func (d *ComplianceDetector) Detect(_ context.Context, before, after snapshot.Snapshot) ([]event.Event, error) {
+	return d.check(before, after)
+}
+
+// Tick re-evaluates the current snapshot so rules added to the config are
+// checked without waiting for a netmap change.
+func (d *ComplianceDetector) Tick(_ context.Context, current snapshot.Snapshot) ([]event.Event, error) {
+	return d.check(current, current)
+}
+
+func (d *ComplianceDetector) check(before, after snapshot.Snapshot) ([]event.Event, error) {
+	prev, err := d.load()
+	if err != nil {
+		return nil, err
+	}
+	now := d.now().UTC()
+	old := snapshot.IndexByPeerID(before)
+	peers := snapshot.IndexByPeerID(after)
+	ids := sortedPeerIDs(peers)
+	next := complianceState{}
+	result := make([]event.Event, 0)
+
+	for _, rule := range d.rules {
+		open := prev[rule.Name]
+		nextOpen := map[string]complianceRecord{}
+		for _, id := range ids {
+			p := peers[id]
+			if !rule.selector.selects(p) {
+				continue
+			}
+			rec, violating := open[id]
+			ok, err := rule.program.Match(p)
+			if err != nil {
+				// Keep the previous verdict when the rule cannot be
+				// evaluated for this peer.
+				d.warnings.warn(d.Name(), rule.Expression, p, err, zap.String("rule", rule.Name))
+				if violating {
+					nextOpen[id] = rec
+				}
+				continue
+			}
+			switch {
+			case !ok && !violating:
+				rec = complianceRecord{Since: now}
+				result = append(result, d.event(event.TypeComplianceViolation, rule, p, before.Hash, after.Hash, rec, now, map[string]any{}))
+				nextOpen[id] = rec
+			case !ok:
+				nextOpen[id] = rec
+			case violating:
+				result = append(result, d.resolved(rule, p, before.Hash, after.Hash, rec, now, ComplianceResolvedPassing))
+			}
+		}
+		// Violations whose peer left the tailnet or the rule's scope.
+		for _, id := range sortedRecordIDs(open) {
+			if _, kept := nextOpen[id]; kept {
+				continue
+			}
+			p, exists := peers[id]
+			if exists && rule.selector.selects(p) {
+				continue
+			}
+			reason := ComplianceResolvedOutOfScope
+			if !exists {
+				reason = ComplianceResolvedPeerRemoved
+				if p, exists = old[id]; !exists {
+					p = snapshot.Peer{ID: id}
+				}
+			}
+			result = append(result, d.resolved(rule, p, before.Hash, after.Hash, open[id], now, reason))
+		}
+		if len(nextOpen) > 0 {
+			next[rule.Name] = nextOpen
+		}
+	}
+
+	if err := d.save(prev, next); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (d *ComplianceDetector) resolved(rule compiledComplianceRule, p snapshot.Peer, beforeHash, afterHash string, rec complianceRecord, now time.Time, reason string) event.Event {
+	evt := d.event(event.TypeComplianceResolved, rule, p, beforeHash, afterHash, rec, now, map[string]any{
+		"reason":         reason,
+		"violated_since": rec.Since.Format(time.RFC3339),
+	})
+	evt.Severity = event.SeverityInfo
+	return evt
+}
+
+func (d *ComplianceDetector) event(eventType string, rule compiledComplianceRule, p snapshot.Peer, beforeHash, afterHash string, rec complianceRecord, now time.Time, extras map[string]any) event.Event {
+	payload := mergePayload(deviceIdentityPayload(p), extras)
+	payload["peer_id"] = p.ID
+	payload["rule"] = rule.Name
+	if rule.Description != "" {
+		payload["description"] = rule.Description
+	}
+	evt := event.NewComplianceEvent(eventType, rule.Name+"/"+p.ID, beforeHash, afterHash, payload, now)
+	evt.Severity = rule.Severity
+	// A rule can fail, pass and fail again against the same snapshot
+	// hashes when Tick re-evaluates it, so scope IDs to the violation.
+	evt.EventID = event.DeriveScopedEventID(evt, rec.Since.Format(time.RFC3339Nano))
+	return evt
+}
+
+func sortedRecordIDs(m map[string]complianceRecord) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (d *ComplianceDetector) load() (complianceState, error) {
+	if d.store == nil {
+		return d.state, nil
+	}
+	out := complianceState{}
+	if err := loadDetectorState(d.store, d.Name(), &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (d *ComplianceDetector) save(prev, next complianceState) error {
+	d.state = next
+	return saveDetectorState(d.store, d.Name(), prev, next)
+}
@@ -0,0 +1,140 @@
+package diff
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestComplianceDetectorOpensAndResolvesViolations(t *testing.T) {
+	start := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	store := memoryDetectorStore{}
+	rules := []ComplianceRule{
+		{Name: "prod-authorized", Description: "prod peers must be authorized", Severity: event.SeverityCritical, Tags: []string{"tag:prod"}, Expression: "peer.machine_authorized"},
+		{Name: "exit-tagged", Expression: `!("0.0.0.0/0" in peer.advertised_routes) || "tag:exit" in peer.tags`},
+	}
+	newDetector := func(now time.Time) *ComplianceDetector {
+		d, err := NewComplianceDetector(rules, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.now = func() time.Time { return now }
+		return d
+	}
+	ctx := context.Background()
+
+	s1 := snapshot.Snapshot{Hash: "h1", Peers: []snapshot.Peer{
+		{ID: "db-1", Name: "db-1", Tags: []string{"tag:prod"}},
+		{ID: "laptop", Name: "laptop", AdvertisedRoutes: []string{"0.0.0.0/0"}},
+		{ID: "web-1", Name: "web-1", Tags: []string{"tag:prod"}, MachineAuthorized: true},
+	}}
+	events, err := newDetector(start).Detect(ctx, snapshot.Snapshot{}, s1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected two violations, got %#v", events)
+	}
+	if events[0].EventType != event.TypeComplianceViolation || events[0].SubjectType != event.SubjectCompliance || events[0].SubjectID != "prod-authorized/db-1" {
+		t.Fatalf("unexpected first violation: %#v", events[0])
+	}
+	if events[0].Severity != event.SeverityCritical || events[0].Payload["description"] != "prod peers must be authorized" || events[0].Payload["name"] != "db-1" {
+		t.Fatalf("unexpected violation details: %#v", events[0])
+	}
+	if events[1].SubjectID != "exit-tagged/laptop" || events[1].Severity != event.SeverityInfo {
+		t.Fatalf("unexpected second violation: %#v", events[1])
+	}
+
+	// A restart with the same snapshot must not repeat open violations.
+	if events, _ := newDetector(start.Add(time.Minute)).Tick(ctx, s1); len(events) != 0 {
+		t.Fatalf("expected persisted violations to stay quiet, got %#v", events)
+	}
+
+	s2 := snapshot.Snapshot{Hash: "h2", Peers: []snapshot.Peer{
+		{ID: "db-1", Name: "db-1", Tags: []string{"tag:prod"}, MachineAuthorized: true},
+		{ID: "web-1", Name: "web-1", Tags: []string{"tag:prod"}, MachineAuthorized: true},
+	}}
+	events, err = newDetector(start.Add(time.Hour)).Detect(ctx, s1, s2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected two resolutions, got %#v", events)
+	}
+	if events[0].EventType != event.TypeComplianceResolved || events[0].Payload["reason"] != ComplianceResolvedPassing || events[0].Payload["violated_since"] != "2026-04-01T09:00:00Z" {
+		t.Fatalf("unexpected resolution: %#v", events[0])
+	}
+	if events[0].Severity != event.SeverityInfo {
+		t.Fatalf("expected resolutions to be info, got %q", events[0].Severity)
+	}
+	if events[1].SubjectID != "exit-tagged/laptop" || events[1].Payload["reason"] != ComplianceResolvedPeerRemoved || events[1].Payload["name"] != "laptop" {
+		t.Fatalf("unexpected removal resolution: %#v", events[1])
+	}
+	if len(store["compliance"]) == 0 || string(store["compliance"]) != "{}" {
+		t.Fatalf("expected no open violations persisted, got %s", store["compliance"])
+	}
+}
+
+func TestComplianceDetectorResolvesPeersLeavingScope(t *testing.T) {
+	d, err := NewComplianceDetector([]ComplianceRule{{Name: "prod-authorized", Tags: []string{"tag:prod"}, Expression: "peer.machine_authorized"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	s1 := snapshot.Snapshot{Hash: "h1", Peers: []snapshot.Peer{{ID: "db-1", Tags: []string{"tag:prod"}}}}
+	s2 := snapshot.Snapshot{Hash: "h2", Peers: []snapshot.Peer{{ID: "db-1", Tags: []string{"tag:dev"}}}}
+	if events, _ := d.Detect(ctx, snapshot.Snapshot{}, s1); len(events) != 1 {
+		t.Fatalf("expected violation, got %#v", events)
+	}
+	events, err := d.Detect(ctx, s1, s2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Payload["reason"] != ComplianceResolvedOutOfScope {
+		t.Fatalf("expected out_of_scope resolution, got %#v", events)
+	}
+}
+
+func TestNewComplianceDetectorRejectsInvalidExpression(t *testing.T) {
+	if _, err := NewComplianceDetector([]ComplianceRule{{Name: "bad", Expression: "peer.online &&"}}, nil); err == nil {
+		t.Fatal("expected compile error")
+	}
+}
+
+func TestComplianceDetectorWarnsOnceAndKeepsVerdictOnEvalError(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	rules := []ComplianceRule{{Name: "linux-only", Expression: `peer.meta.os == "linux"`}}
+	d, err := NewComplianceDetector(rules, nil, WithComplianceLogger(zap.New(core)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	windows := snapshot.Snapshot{Hash: "h1", Peers: []snapshot.Peer{{ID: "1", Name: "app-1", Meta: map[string]string{"os": "windows"}}}}
+	if events, err := d.Detect(ctx, snapshot.Snapshot{}, windows); err != nil || len(events) != 1 {
+		t.Fatalf("expected one violation, got %#v, %v", events, err)
+	}
+	unknown := snapshot.Snapshot{Hash: "h2", Peers: []snapshot.Peer{{ID: "1", Name: "app-1"}}}
+	for range 2 {
+		events, err := d.Tick(ctx, unknown)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 0 {
+			t.Fatalf("expected the open violation to be kept, got %#v", events)
+		}
+	}
+	warnings := logs.FilterMessage("expression failed to evaluate").All()
+	if len(warnings) != 1 {
+		t.Fatalf("expected one evaluation warning, got %d", len(warnings))
+	}
+	if fields := warnings[0].ContextMap(); fields["detector"] != "compliance" || fields["rule"] != "linux-only" {
+		t.Fatalf("unexpected warning fields: %#v", fields)
+	}
+}
@@ -0,0 +1,116 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DetectorStateStore persists detector state across cycles and restarts.
+// state.StateStore satisfies it.
+type DetectorStateStore interface {
+	LoadDetectorState(name string) (json.RawMessage, error)
+	SaveDetectorState(name string, data json.RawMessage) error
+}
+
+// loadDetectorState decodes the state saved under name into out. A nil
+// store or missing state leaves out untouched.
+func loadDetectorState(store DetectorStateStore, name string, out any) error {
+	if store == nil {
+		return nil
+	}
+	raw, err := store.LoadDetectorState(name)
+	if err != nil {
+		return fmt.Errorf("load %s state: %w", name, err)
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("decode %s state: %w", name, err)
+	}
+	return nil
+}
+
+// saveDetectorState writes next under name when it differs from prev, so
+// quiet cycles do not rewrite the state file.
+func saveDetectorState(store DetectorStateStore, name string, prev, next any) error {
+	if store == nil {
+		return nil
+	}
+	before, _ := json.Marshal(prev)
+	after, err := json.Marshal(next)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(before, after) {
+		return nil
+	}
+	if err := store.SaveDetectorState(name, after); err != nil {
+		return fmt.Errorf("save %s state: %w", name, err)
+	}
+	return nil
+}
+
+// StagedStateStore buffers detector state saves until Commit. The runner
+// commits once a cycle's events are delivered and discards the state of a
+// failed cycle, so its events are detected again on the next cycle instead
+// of being recorded as already fired.
+type StagedStateStore struct {
+	base DetectorStateStore
+
+	mu      sync.Mutex
+	pending map[string]json.RawMessage
+}
+
+// NewStagedStateStore returns a store staging saves in front of base.
+func NewStagedStateStore(base DetectorStateStore) *StagedStateStore {
+	return &StagedStateStore{base: base, pending: map[string]json.RawMessage{}}
+}
+
+// LoadDetectorState returns the state staged under name, or the committed
+// state when nothing is staged.
+func (s *StagedStateStore) LoadDetectorState(name string) (json.RawMessage, error) {
+	s.mu.Lock()
+	staged, ok := s.pending[name]
+	s.mu.Unlock()
+	if ok {
+		return staged, nil
+	}
+	return s.base.LoadDetectorState(name)
+}
+
+// SaveDetectorState stages data under name until Commit.
+func (s *StagedStateStore) SaveDetectorState(name string, data json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[name] = data
+	return nil
+}
+
+// Commit writes the staged state to the underlying store.
+func (s *StagedStateStore) Commit() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.pending))
+	for name := range s.pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := s.base.SaveDetectorState(name, s.pending[name]); err != nil {
+			return fmt.Errorf("save %s state: %w", name, err)
+		}
+		delete(s.pending, name)
+	}
+	return nil
+}
+
+// Discard drops the staged state.
+func (s *StagedStateStore) Discard() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.pending)
+}
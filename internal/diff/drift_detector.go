@@ -0,0 +1,87 @@
+package diff
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/inventory"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+var driftEventTypes = map[string]string{
+	inventory.KindMissingDevice:    event.TypeDriftMissingDevice,
+	inventory.KindUnexpectedDevice: event.TypeDriftUnexpectedDevice,
+	inventory.KindTagsMismatch:     event.TypeDriftTagsMismatch,
+	inventory.KindRoutesMismatch:   event.TypeDriftRoutesMismatch,
+}
+
+// DriftDetector compares snapshots with a declared inventory. It emits a
+// drift.* event when a finding first appears, or changes, between the
+// previous snapshot and the current one; drift that persists stays quiet.
+type DriftDetector struct {
+	inv inventory.Inventory
+	now func() time.Time
+}
+
+// NewDriftDetector returns a detector for inv. An empty inventory reports
+// nothing.
+func NewDriftDetector(inv inventory.Inventory) *DriftDetector {
+	return &DriftDetector{inv: inv, now: time.Now}
+}
+
+func (d *DriftDetector) Name() string { return "drift" }
+
+func (d *DriftDetector) Detect(_ context.Context, before, after snapshot.Snapshot) ([]event.Event, error) {
+	result := make([]event.Event, 0)
+	if len(d.inv.Devices) == 0 && len(d.inv.Managed.Tags) == 0 && len(d.inv.Managed.DeviceNames) == 0 {
+		return result, nil
+	}
+	prev := map[string]struct{}{}
+	if before.Hash != "" {
+		for _, f := range inventory.Compare(d.inv, before) {
+			prev[driftKey(f)] = struct{}{}
+		}
+	}
+	for _, f := range inventory.Compare(d.inv, after) {
+		if _, ok := prev[driftKey(f)]; ok {
+			continue
+		}
+		result = append(result, d.event(f, before.Hash, after.Hash))
+	}
+	return result, nil
+}
+
+// driftKey identifies a finding including its details, so a mismatch that
+// changes shape is reported again.
+func driftKey(f inventory.Finding) string {
+	return strings.Join([]string{
+		f.Kind,
+		strings.ToLower(f.Device),
+		f.PeerID,
+		f.Reason,
+		strings.Join(f.Actual, ","),
+	}, "|")
+}
+
+func (d *DriftDetector) event(f inventory.Finding, beforeHash, afterHash string) event.Event {
+	payload := deviceIdentityPayload(snapshot.Peer{Name: f.Device})
+	if f.Peer != nil {
+		payload = deviceIdentityPayload(*f.Peer)
+		payload["peer_id"] = f.PeerID
+	}
+	subjectID := f.Device
+	switch f.Kind {
+	case inventory.KindMissingDevice:
+		payload["reason"] = f.Reason
+	case inventory.KindUnexpectedDevice:
+		subjectID = f.PeerID
+	default:
+		payload["expected"] = normalizedIdentitySlice(f.Expected)
+		payload["actual"] = normalizedIdentitySlice(f.Actual)
+		payload["missing"] = normalizedIdentitySlice(f.Missing)
+		payload["extra"] = normalizedIdentitySlice(f.Extra)
+	}
+	return event.NewDriftEvent(driftEventTypes[f.Kind], subjectID, beforeHash, afterHash, payload, d.now())
+}
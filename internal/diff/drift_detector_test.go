@@ -0,0 +1,64 @@
+package diff
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/inventory"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+func TestDriftDetectorReportsNewDriftOnce(t *testing.T) {
+	d := NewDriftDetector(inventory.Inventory{
+		Managed: inventory.Selector{Tags: []string{"tag:infra"}},
+		Devices: []inventory.Device{
+			{Name: "router-1", Tags: []string{"tag:infra", "tag:router"}},
+			{Name: "db-1"},
+		},
+	})
+	ctx := context.Background()
+	s1 := snapshot.Snapshot{Hash: "h1", Peers: []snapshot.Peer{
+		{ID: "n1", Name: "router-1", Tags: []string{"tag:infra", "tag:router"}},
+	}}
+	events, err := d.Detect(ctx, snapshot.Snapshot{}, s1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypeDriftMissingDevice || events[0].SubjectID != "db-1" || events[0].Payload["reason"] != inventory.ReasonAbsent {
+		t.Fatalf("expected missing db-1 on startup, got %#v", events)
+	}
+	if events[0].SubjectType != event.SubjectDrift || events[0].Payload["name"] != "db-1" {
+		t.Fatalf("unexpected drift subject: %#v", events[0])
+	}
+
+	s2 := snapshot.Snapshot{Hash: "h2", Peers: []snapshot.Peer{
+		{ID: "n1", Name: "router-1", Tags: []string{"tag:infra"}},
+		{ID: "n9", Name: "stray", Tags: []string{"tag:infra"}},
+	}}
+	events, err = d.Detect(ctx, s1, s2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected tags mismatch and unexpected device, got %#v", events)
+	}
+	if events[0].EventType != event.TypeDriftTagsMismatch || events[0].SubjectID != "router-1" || !reflect.DeepEqual(events[0].Payload["missing"], []string{"tag:router"}) {
+		t.Fatalf("unexpected tags mismatch: %#v", events[0])
+	}
+	if events[1].EventType != event.TypeDriftUnexpectedDevice || events[1].SubjectID != "n9" || events[1].Payload["name"] != "stray" {
+		t.Fatalf("unexpected device event: %#v", events[1])
+	}
+
+	if events, _ := d.Detect(ctx, s2, s2); len(events) != 0 {
+		t.Fatalf("expected persisting drift to stay quiet, got %#v", events)
+	}
+}
+
+func TestDriftDetectorWithoutInventoryIsQuiet(t *testing.T) {
+	s := snapshot.Snapshot{Hash: "h1", Peers: []snapshot.Peer{{ID: "n1", Name: "laptop"}}}
+	if events, _ := NewDriftDetector(inventory.Inventory{}).Detect(context.Background(), snapshot.Snapshot{}, s); len(events) != 0 {
+		t.Fatalf("expected no events, got %#v", events)
+	}
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/jaxxstorm/sentinel/internal/event"
 	"github.com/jaxxstorm/sentinel/internal/snapshot"
+	"github.com/jaxxstorm/sentinel/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Detector interface {
@@ -13,6 +15,14 @@ type Detector interface {
 	Detect(ctx context.Context, before, after snapshot.Snapshot) ([]event.Event, error)
 }
 
+// TickDetector is a Detector whose findings also depend on the clock. Tick
+// runs on cycles where the snapshot is unchanged so thresholds are noticed
+// without waiting for a netmap update.
+type TickDetector interface {
+	Detector
+	Tick(ctx context.Context, current snapshot.Snapshot) ([]event.Event, error)
+}
+
 type Engine struct {
 	detectors map[string]Detector
 }
@@ -26,6 +36,23 @@ func NewEngine(ds []Detector) *Engine {
 }
 
 func (e *Engine) Diff(ctx context.Context, before, after snapshot.Snapshot, order []string, enabled map[string]bool) ([]event.Event, error) {
+	return e.run(order, enabled, func(d Detector) ([]event.Event, error) {
+		return detect(ctx, d, before, after)
+	})
+}
+
+// Tick runs the enabled TickDetectors against an unchanged snapshot.
+func (e *Engine) Tick(ctx context.Context, current snapshot.Snapshot, order []string, enabled map[string]bool) ([]event.Event, error) {
+	return e.run(order, enabled, func(d Detector) ([]event.Event, error) {
+		td, ok := d.(TickDetector)
+		if !ok {
+			return nil, nil
+		}
+		return tick(ctx, td, current)
+	})
+}
+
+func (e *Engine) run(order []string, enabled map[string]bool, fn func(Detector) ([]event.Event, error)) ([]event.Event, error) {
 	out := make([]event.Event, 0)
 	for _, name := range order {
 		d, ok := e.detectors[name]
@@ -38,7 +65,7 @@ func (e *Engine) Diff(ctx context.Context, before, after snapshot.Snapshot, orde
 				continue
 			}
 		}
-		events, err := d.Detect(ctx, before, after)
+		events, err := fn(d)
 		if err != nil {
 			return nil, fmt.Errorf("detector %q failed: %w", name, err)
 		}
@@ -46,3 +73,19 @@ func (e *Engine) Diff(ctx context.Context, before, after snapshot.Snapshot, orde
 	}
 	return out, nil
 }
+
+func detect(ctx context.Context, d Detector, before, after snapshot.Snapshot) ([]event.Event, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "diff.Detect", trace.WithAttributes(tracing.AttrDetector.String(d.Name())))
+	events, err := d.Detect(ctx, before, after)
+	span.SetAttributes(tracing.EventAttributes(events)...)
+	tracing.EndSpan(span, err)
+	return events, err
+}
+
+func tick(ctx context.Context, d TickDetector, current snapshot.Snapshot) ([]event.Event, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "diff.Tick", trace.WithAttributes(tracing.AttrDetector.String(d.Name())))
+	events, err := d.Tick(ctx, current)
+	span.SetAttributes(tracing.EventAttributes(events)...)
+	tracing.EndSpan(span, err)
+	return events, err
+}
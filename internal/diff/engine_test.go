@@ -36,3 +36,27 @@ func TestEngineOrderingAndEnablement(t *testing.T) {
 		t.Fatalf("expected presence first, got %s", events[0].EventType)
 	}
 }
+
+func TestEngineTickRunsOnlyTickDetectors(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	keyExpiry := NewKeyExpiryDetector([]time.Duration{24 * time.Hour}, nil)
+	keyExpiry.now = func() time.Time { return now }
+	e := NewEngine([]Detector{NewPresenceDetector(), keyExpiry})
+
+	current := snapshot.Snapshot{Hash: "h", Peers: []snapshot.Peer{{ID: "p1", Online: true, KeyExpiry: now.Add(time.Hour).Format(time.RFC3339)}}}
+	events, err := e.Tick(context.Background(), current, []string{"presence", "key_expiry"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypePeerKeyExpiryApproaching {
+		t.Fatalf("expected only the key expiry event, got %#v", events)
+	}
+
+	events, err = e.Tick(context.Background(), current, []string{"presence", "key_expiry"}, map[string]bool{"key_expiry": false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected disabled tick detector to be skipped, got %#v", events)
+	}
+}
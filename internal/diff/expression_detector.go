@@ -0,0 +1,184 @@
+package diff
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/expr"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+	"go.uber.org/zap"
+)
+
+// ExpressionDetectorConfig describes a user-defined detector from
+// detectors.custom.
+type ExpressionDetectorConfig struct {
+	Name        string
+	EventType   string
+	Severity    string
+	Tags        []string
+	DeviceNames []string
+	Expression  string
+	// Log receives a warning the first time Expression fails to evaluate;
+	// nil discards it.
+	Log *zap.Logger
+}
+
+// ExpressionDetector emits EventType for every peer whose before and after
+// state satisfies a CEL expression. Peers are first narrowed by the subject
+// selector: tags and device name globs, matched against the after state, or
+// the before state for removed peers.
+type ExpressionDetector struct {
+	cfg      ExpressionDetectorConfig
+	selector peerSelector
+	program  *expr.PeerChangeProgram
+	warnings evalWarnings
+	now      func() time.Time
+}
+
+func NewExpressionDetector(cfg ExpressionDetectorConfig) (*ExpressionDetector, error) {
+	program, err := expr.CompilePeerChange(cfg.Expression)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(cfg.Severity) == "" {
+		cfg.Severity = event.SeverityInfo
+	}
+	return &ExpressionDetector{
+		cfg:      cfg,
+		selector: peerSelector{tags: cfg.Tags, deviceNames: cfg.DeviceNames},
+		program:  program,
+		warnings: newEvalWarnings(cfg.Log),
+		now:      time.Now,
+	}, nil
+}
+
+func (d *ExpressionDetector) Name() string { return d.cfg.Name }
+
+func (d *ExpressionDetector) Detect(_ context.Context, before, after snapshot.Snapshot) ([]event.Event, error) {
+	prev := snapshot.IndexByPeerID(before)
+	next := snapshot.IndexByPeerID(after)
+	ids := make(map[string]snapshot.Peer, len(prev)+len(next))
+	for id, p := range prev {
+		ids[id] = p
+	}
+	for id, p := range next {
+		ids[id] = p
+	}
+
+	result := make([]event.Event, 0)
+	for _, id := range sortedPeerIDs(ids) {
+		subject := ids[id]
+		if !d.selector.selects(subject) {
+			continue
+		}
+		var oldPeer, newPeer *snapshot.Peer
+		if p, ok := prev[id]; ok {
+			oldPeer = &p
+		}
+		if p, ok := next[id]; ok {
+			newPeer = &p
+		}
+		// Evaluation errors, such as reading a meta key the peer does not
+		// have, count as no match.
+		matched, err := d.program.Match(oldPeer, newPeer)
+		if err != nil {
+			d.warnings.warn(d.cfg.Name, d.cfg.Expression, subject, err)
+			continue
+		}
+		if !matched {
+			continue
+		}
+		evt := event.NewPeerEvent(
+			d.cfg.EventType,
+			id,
+			before.Hash,
+			after.Hash,
+			mergePayload(deviceIdentityPayload(subject), map[string]any{
+				"detector": d.cfg.Name,
+			}),
+			d.now(),
+		)
+		evt.Severity = d.cfg.Severity
+		// Several custom detectors may share an event type.
+		evt.EventID = event.DeriveScopedEventID(evt, d.cfg.Name)
+		result = append(result, evt)
+	}
+	return result, nil
+}
+
+// evalWarnings logs a CEL evaluation failure once per detector and
+// expression, so an expression that fails for every peer neither floods the
+// log nor goes unnoticed.
+type evalWarnings struct {
+	log  *zap.Logger
+	seen map[string]struct{}
+}
+
+func newEvalWarnings(log *zap.Logger) evalWarnings {
+	if log == nil {
+		log = zap.NewNop()
+	}
+	return evalWarnings{log: log, seen: map[string]struct{}{}}
+}
+
+func (w evalWarnings) warn(detector, expression string, p snapshot.Peer, err error, fields ...zap.Field) {
+	key := detector + "\x00" + expression
+	if _, ok := w.seen[key]; ok {
+		return
+	}
+	w.seen[key] = struct{}{}
+	w.log.Warn("expression failed to evaluate",
+		append([]zap.Field{
+			zap.String("detector", detector),
+			zap.String("expression", expression),
+			zap.String("device", p.Name),
+			zap.Error(err),
+		}, fields...)...,
+	)
+}
+
+// peerSelector narrows user-defined detectors to peers carrying one of tags
+// and whose name matches one of the deviceNames globs. Empty fields match
+// every peer.
+type peerSelector struct {
+	tags        []string
+	deviceNames []string
+}
+
+func (s peerSelector) selects(p snapshot.Peer) bool {
+	if len(s.tags) > 0 && !containsFold(s.tags, p.Tags) {
+		return false
+	}
+	if len(s.deviceNames) > 0 && !matchesNameGlob(s.deviceNames, p.Name) {
+		return false
+	}
+	return true
+}
+
+func containsFold(selector, values []string) bool {
+	for _, value := range values {
+		for _, raw := range selector {
+			if strings.EqualFold(strings.TrimSpace(raw), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesNameGlob(patterns []string, name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return false
+	}
+	for _, raw := range patterns {
+		pattern := strings.ToLower(strings.TrimSpace(raw))
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
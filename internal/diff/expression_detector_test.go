@@ -0,0 +1,103 @@
+package diff
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestExpressionDetectorEmitsMatchesForSelectedPeers(t *testing.T) {
+	d, err := NewExpressionDetector(ExpressionDetectorConfig{
+		Name:       "db_down",
+		EventType:  "custom.db.down",
+		Severity:   event.SeverityCritical,
+		Tags:       []string{"tag:db"},
+		Expression: `before.online && !after.online`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := snapshot.Snapshot{Hash: "h1", Peers: []snapshot.Peer{
+		{ID: "db-1", Name: "db-1", Online: true, Tags: []string{"tag:db"}},
+		{ID: "web-1", Name: "web-1", Online: true, Tags: []string{"tag:web"}},
+	}}
+	after := snapshot.Snapshot{Hash: "h2", Peers: []snapshot.Peer{
+		{ID: "db-1", Name: "db-1", Online: false, Tags: []string{"tag:db"}},
+		{ID: "web-1", Name: "web-1", Online: false, Tags: []string{"tag:web"}},
+	}}
+
+	events, err := d.Detect(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %#v", events)
+	}
+	evt := events[0]
+	if evt.EventType != "custom.db.down" || evt.SubjectID != "db-1" || evt.Severity != event.SeverityCritical {
+		t.Fatalf("unexpected event: %#v", evt)
+	}
+	if evt.Payload["detector"] != "db_down" || evt.Payload["name"] != "db-1" {
+		t.Fatalf("unexpected payload: %#v", evt.Payload)
+	}
+}
+
+func TestExpressionDetectorHandlesRemovedPeersAndEvalErrors(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	d, err := NewExpressionDetector(ExpressionDetectorConfig{
+		Name:        "os_change",
+		EventType:   "custom.os.changed",
+		DeviceNames: []string{"app-*"},
+		Expression:  `removed || after.meta.os != before.meta.os`,
+		Log:         zap.New(core),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := snapshot.Snapshot{Hash: "h1", Peers: []snapshot.Peer{
+		{ID: "1", Name: "app-1", Meta: map[string]string{"os": "linux"}},
+		{ID: "2", Name: "app-2", Meta: map[string]string{"os": "linux"}},
+		{ID: "3", Name: "app-3"},
+		{ID: "4", Name: "db-1", Meta: map[string]string{"os": "linux"}},
+	}}
+	after := snapshot.Snapshot{Hash: "h2", Peers: []snapshot.Peer{
+		{ID: "1", Name: "app-1", Meta: map[string]string{"os": "windows"}},
+		{ID: "3", Name: "app-3"},
+		{ID: "4", Name: "db-1", Meta: map[string]string{"os": "windows"}},
+	}}
+
+	events, err := d.Detect(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].SubjectID != "1" || events[1].SubjectID != "2" {
+		t.Fatalf("expected events for app-1 and removed app-2, got %#v", events)
+	}
+	if events[0].Severity != event.SeverityInfo {
+		t.Fatalf("expected default info severity, got %q", events[0].Severity)
+	}
+
+	// app-3 has no os meta key, so the expression fails for it on every
+	// cycle but is reported once.
+	if _, err := d.Detect(context.Background(), before, after); err != nil {
+		t.Fatal(err)
+	}
+	warnings := logs.FilterMessage("expression failed to evaluate").All()
+	if len(warnings) != 1 {
+		t.Fatalf("expected one evaluation warning, got %d", len(warnings))
+	}
+	if fields := warnings[0].ContextMap(); fields["detector"] != "os_change" || fields["device"] != "app-3" {
+		t.Fatalf("unexpected warning fields: %#v", fields)
+	}
+}
+
+func TestNewExpressionDetectorRejectsInvalidExpression(t *testing.T) {
+	if _, err := NewExpressionDetector(ExpressionDetectorConfig{Name: "bad", EventType: "custom.bad", Expression: `after.online &&`}); err == nil {
+		t.Fatal("expected invalid expression to be rejected")
+	}
+}
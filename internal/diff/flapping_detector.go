@@ -0,0 +1,186 @@
+package diff
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/durfmt"
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+const (
+	DefaultFlappingWindow      = 10 * time.Minute
+	DefaultFlappingTransitions = 4
+
+	// SuppressReasonFlapping is the policy suppression reason for presence
+	// events held back while a peer flaps.
+	SuppressReasonFlapping = "flapping"
+)
+
+// FlappingDetector counts online/offline transitions per peer over a sliding
+// window. A peer with at least transitions state changes in the window is
+// flapping: the detector emits peer.flapping.started, holds back its presence
+// events through Suppress, and emits peer.flapping.stopped once a full window
+// passes without a transition.
+type FlappingDetector struct {
+	window      time.Duration
+	transitions int
+	store       DetectorStateStore
+	state       flappingState
+	now         func() time.Time
+}
+
+type flappingState map[string]flappingRecord
+
+type flappingRecord struct {
+	Transitions []time.Time `json:"transitions,omitempty"`
+	Online      bool        `json:"online"`
+	Flapping    bool        `json:"flapping,omitempty"`
+	Since       time.Time   `json:"since,omitzero"`
+}
+
+// NewFlappingDetector returns a flapping detector. Zero window or
+// transitions use the defaults. When store is nil, state is only kept in
+// memory.
+func NewFlappingDetector(window time.Duration, transitions int, store DetectorStateStore) *FlappingDetector {
+	if window <= 0 {
+		window = DefaultFlappingWindow
+	}
+	if transitions <= 0 {
+		transitions = DefaultFlappingTransitions
+	}
+	return &FlappingDetector{
+		window:      window,
+		transitions: transitions,
+		store:       store,
+		state:       flappingState{},
+		now:         time.Now,
+	}
+}
+
+func (d *FlappingDetector) Name() string { return "flapping" }
+
+func (d *FlappingDetector) Detect(_ context.Context, before, after snapshot.Snapshot) ([]event.Event, error) {
+	return d.check(before, after, true)
+}
+
+func (d *FlappingDetector) Tick(_ context.Context, current snapshot.Snapshot) ([]event.Event, error) {
+	return d.check(current, current, false)
+}
+
+// Suppress reports whether evt is a presence event for a flapping peer. It
+// implements policy.Suppressor.
+func (d *FlappingDetector) Suppress(evt event.Event) (string, bool) {
+	if evt.EventType != event.TypePeerOnline && evt.EventType != event.TypePeerOffline {
+		return "", false
+	}
+	if d.state[evt.SubjectID].Flapping {
+		return SuppressReasonFlapping, true
+	}
+	return "", false
+}
+
+func (d *FlappingDetector) check(before, after snapshot.Snapshot, changed bool) ([]event.Event, error) {
+	prev, err := d.load()
+	if err != nil {
+		return nil, err
+	}
+	now := d.now().UTC()
+	next := make(flappingState, len(prev))
+	for id, rec := range prev {
+		rec.Transitions = append([]time.Time(nil), rec.Transitions...)
+		next[id] = rec
+	}
+
+	peers := snapshot.IndexByPeerID(after)
+	if changed {
+		old := snapshot.IndexByPeerID(before)
+		for id, p := range peers {
+			o, exists := old[id]
+			if !exists || o.Online == p.Online {
+				continue
+			}
+			rec := next[id]
+			rec.Transitions = append(rec.Transitions, now)
+			rec.Online = p.Online
+			next[id] = rec
+		}
+	}
+
+	ids := make([]string, 0, len(next))
+	for id := range next {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	result := make([]event.Event, 0)
+	cutoff := now.Add(-d.window)
+	for _, id := range ids {
+		rec := next[id]
+		kept := rec.Transitions[:0]
+		for _, t := range rec.Transitions {
+			if t.After(cutoff) {
+				kept = append(kept, t)
+			}
+		}
+		rec.Transitions = kept
+
+		p, ok := peers[id]
+		if !ok {
+			p = snapshot.Peer{ID: id, Online: rec.Online}
+		}
+		switch {
+		case !rec.Flapping && len(rec.Transitions) >= d.transitions:
+			rec.Flapping = true
+			rec.Since = now
+			result = append(result, d.event(event.TypePeerFlappingStarted, p, before.Hash, after.Hash, rec, now, map[string]any{
+				"transitions": len(rec.Transitions),
+				"window":      durfmt.Short(d.window),
+				"online":      rec.Online,
+			}))
+		case rec.Flapping && len(rec.Transitions) == 0:
+			result = append(result, d.event(event.TypePeerFlappingStopped, p, before.Hash, after.Hash, rec, now, map[string]any{
+				"online":   rec.Online,
+				"duration": durfmt.Short(now.Sub(rec.Since).Round(time.Second)),
+			}))
+			rec.Flapping = false
+			rec.Since = time.Time{}
+		}
+		if !rec.Flapping && len(rec.Transitions) == 0 {
+			delete(next, id)
+			continue
+		}
+		next[id] = rec
+	}
+
+	if err := d.save(prev, next); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (d *FlappingDetector) event(eventType string, p snapshot.Peer, beforeHash, afterHash string, rec flappingRecord, now time.Time, extras map[string]any) event.Event {
+	evt := event.NewPeerEvent(eventType, p.ID, beforeHash, afterHash, mergePayload(deviceIdentityPayload(p), extras), now)
+	// Started and stopped can repeat against the same snapshot hashes, so
+	// scope the ID to the flapping episode.
+	evt.EventID = event.DeriveScopedEventID(evt, rec.Since.Format(time.RFC3339Nano))
+	return evt
+}
+
+func (d *FlappingDetector) load() (flappingState, error) {
+	if d.store == nil {
+		return d.state, nil
+	}
+	out := flappingState{}
+	if err := loadDetectorState(d.store, d.Name(), &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (d *FlappingDetector) save(prev, next flappingState) error {
+	d.state = next
+	return saveDetectorState(d.store, d.Name(), prev, next)
+}
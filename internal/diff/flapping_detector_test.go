@@ -0,0 +1,110 @@
+package diff
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+func presenceSnapshot(hash string, online bool) snapshot.Snapshot {
+	return snapshot.Snapshot{Hash: hash, Peers: []snapshot.Peer{{ID: "laptop", Name: "laptop", Online: online}}}
+}
+
+func TestFlappingDetectorStartsSuppressesAndStops(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	d := NewFlappingDetector(10*time.Minute, 3, memoryDetectorStore{})
+	d.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	online := true
+	prev := presenceSnapshot("h0", online)
+	var started []event.Event
+	for i := 1; i <= 3; i++ {
+		now = now.Add(time.Minute)
+		online = !online
+		next := presenceSnapshot("h"+string(rune('0'+i)), online)
+		events, err := d.Detect(ctx, prev, next)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < 3 && len(events) != 0 {
+			t.Fatalf("expected no flapping event after %d transitions, got %#v", i, events)
+		}
+		started = events
+		prev = next
+	}
+	if len(started) != 1 || started[0].EventType != event.TypePeerFlappingStarted {
+		t.Fatalf("expected peer.flapping.started, got %#v", started)
+	}
+	if started[0].Payload["transitions"] != 3 || started[0].Payload["window"] != "10m" {
+		t.Fatalf("unexpected started payload: %#v", started[0].Payload)
+	}
+
+	presence := event.NewPresenceEvent(event.TypePeerOffline, "laptop", "", "", nil, now)
+	if reason, ok := d.Suppress(presence); !ok || reason != SuppressReasonFlapping {
+		t.Fatalf("expected presence event to be suppressed while flapping, got %q %v", reason, ok)
+	}
+	if _, ok := d.Suppress(event.NewPeerEvent(event.TypePeerRoutesChanged, "laptop", "", "", nil, now)); ok {
+		t.Fatal("expected non-presence events to pass")
+	}
+
+	now = now.Add(5 * time.Minute)
+	if events, _ := d.Tick(ctx, prev); len(events) != 0 {
+		t.Fatalf("expected flapping to continue inside the window, got %#v", events)
+	}
+
+	now = now.Add(10 * time.Minute)
+	events, err := d.Tick(ctx, prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypePeerFlappingStopped {
+		t.Fatalf("expected peer.flapping.stopped after a quiet window, got %#v", events)
+	}
+	if events[0].Payload["online"] != false || events[0].Payload["duration"] != "15m" {
+		t.Fatalf("unexpected stopped payload: %#v", events[0].Payload)
+	}
+	if events[0].EventID == started[0].EventID {
+		t.Fatal("expected distinct started and stopped event IDs")
+	}
+	if _, ok := d.Suppress(presence); ok {
+		t.Fatal("expected presence events to pass once stable")
+	}
+}
+
+func TestFlappingDetectorIgnoresSlowTransitions(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	d := NewFlappingDetector(10*time.Minute, 2, nil)
+	d.now = func() time.Time { return now }
+
+	if events, _ := d.Detect(context.Background(), presenceSnapshot("a", true), presenceSnapshot("b", false)); len(events) != 0 {
+		t.Fatalf("unexpected events: %#v", events)
+	}
+	now = now.Add(11 * time.Minute)
+	if events, _ := d.Detect(context.Background(), presenceSnapshot("b", false), presenceSnapshot("c", true)); len(events) != 0 {
+		t.Fatalf("expected transitions outside the window not to count, got %#v", events)
+	}
+}
+
+func TestFlappingDetectorRestoresStateFromStore(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := memoryDetectorStore{}
+	d := NewFlappingDetector(10*time.Minute, 1, store)
+	d.now = func() time.Time { return now }
+	if events, _ := d.Detect(context.Background(), presenceSnapshot("a", true), presenceSnapshot("b", false)); len(events) != 1 {
+		t.Fatalf("expected flapping to start, got %#v", events)
+	}
+
+	restarted := NewFlappingDetector(10*time.Minute, 1, store)
+	restarted.now = func() time.Time { return now.Add(11 * time.Minute) }
+	events, err := restarted.Tick(context.Background(), presenceSnapshot("b", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypePeerFlappingStopped {
+		t.Fatalf("expected restarted detector to stop the persisted episode, got %#v", events)
+	}
+}
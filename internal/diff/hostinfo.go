@@ -0,0 +1,41 @@
+package diff
+
+import "github.com/jaxxstorm/sentinel/internal/snapshot"
+
+// Hostinfo fields peer_changes diffs.
+const (
+	HostinfoOS           = "os"
+	HostinfoOSVersion    = "os_version"
+	HostinfoIPNVersion   = "ipn_version"
+	HostinfoDistro       = "distro"
+	HostinfoDeviceModel  = "device_model"
+	HostinfoShieldsUp    = "shields_up"
+	HostinfoAllowsUpdate = "allows_update"
+	HostinfoServices     = "services"
+)
+
+type hostinfoField struct {
+	name  string
+	value func(snapshot.Hostinfo) any
+}
+
+// hostinfoFields are the tracked fields in the order changes are reported.
+var hostinfoFields = []hostinfoField{
+	{HostinfoOS, func(h snapshot.Hostinfo) any { return h.OS }},
+	{HostinfoOSVersion, func(h snapshot.Hostinfo) any { return h.OSVersion }},
+	{HostinfoIPNVersion, func(h snapshot.Hostinfo) any { return h.IPNVersion }},
+	{HostinfoDistro, func(h snapshot.Hostinfo) any { return h.Distro }},
+	{HostinfoDeviceModel, func(h snapshot.Hostinfo) any { return h.DeviceModel }},
+	{HostinfoShieldsUp, func(h snapshot.Hostinfo) any { return h.ShieldsUp }},
+	{HostinfoAllowsUpdate, func(h snapshot.Hostinfo) any { return h.AllowsUpdate }},
+	{HostinfoServices, func(h snapshot.Hostinfo) any { return normalizedIdentitySlice(h.Services) }},
+}
+
+// HostinfoFields lists the names of the tracked Hostinfo fields.
+var HostinfoFields = func() []string {
+	out := make([]string, 0, len(hostinfoFields))
+	for _, f := range hostinfoFields {
+		out = append(out, f.name)
+	}
+	return out
+}()
@@ -0,0 +1,152 @@
+package diff
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/durfmt"
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+// DefaultKeyExpiryThresholds are used when the key_expiry detector has no
+// thresholds configured.
+var DefaultKeyExpiryThresholds = []time.Duration{30 * 24 * time.Hour, 7 * 24 * time.Hour, 24 * time.Hour}
+
+// KeyExpiryDetector emits peer.key_expiry.approaching when a peer's key
+// expiry comes within a threshold. Each threshold fires once per key expiry
+// value; renewing the key re-arms them.
+type KeyExpiryDetector struct {
+	thresholds []time.Duration
+	store      DetectorStateStore
+	fired      keyExpiryState
+	now        func() time.Time
+}
+
+// keyExpiryState records, per peer ID, the thresholds already fired for the
+// key expiry they were fired against.
+type keyExpiryState map[string]keyExpiryRecord
+
+type keyExpiryRecord struct {
+	KeyExpiry string   `json:"key_expiry"`
+	Fired     []string `json:"fired"`
+}
+
+// NewKeyExpiryDetector returns a detector for the given thresholds. When
+// store is nil, fired thresholds are only remembered in memory.
+func NewKeyExpiryDetector(thresholds []time.Duration, store DetectorStateStore) *KeyExpiryDetector {
+	return &KeyExpiryDetector{
+		thresholds: keyExpiryThresholds(thresholds),
+		store:      store,
+		fired:      keyExpiryState{},
+		now:        time.Now,
+	}
+}
+
+func (d *KeyExpiryDetector) Name() string { return "key_expiry" }
+
+func (d *KeyExpiryDetector) Detect(_ context.Context, before, after snapshot.Snapshot) ([]event.Event, error) {
+	return d.check(before.Hash, after)
+}
+
+func (d *KeyExpiryDetector) Tick(_ context.Context, current snapshot.Snapshot) ([]event.Event, error) {
+	return d.check(current.Hash, current)
+}
+
+func (d *KeyExpiryDetector) check(beforeHash string, current snapshot.Snapshot) ([]event.Event, error) {
+	prev, err := d.load()
+	if err != nil {
+		return nil, err
+	}
+	now := d.now()
+	peers := snapshot.IndexByPeerID(current)
+	next := keyExpiryState{}
+	result := make([]event.Event, 0)
+	for _, id := range sortedPeerIDs(peers) {
+		p := peers[id]
+		expiry, ok := p.KeyExpiryTime()
+		if !ok || p.Expired {
+			continue
+		}
+		remaining := expiry.Sub(now)
+		if remaining <= 0 {
+			// Already expired; peer.key_expired covers it.
+			continue
+		}
+		rec, crossed := crossKeyExpiryThresholds(d.thresholds, prev[id], p.KeyExpiry, remaining)
+		if len(rec.Fired) > 0 {
+			next[id] = rec
+		}
+		if crossed == 0 {
+			continue
+		}
+		evt := event.NewPeerEvent(
+			event.TypePeerKeyExpiryApproaching,
+			id,
+			beforeHash,
+			current.Hash,
+			mergePayload(deviceIdentityPayload(p), map[string]any{
+				"key_expiry": p.KeyExpiry,
+				"threshold":  durfmt.Short(crossed),
+				"remaining":  durfmt.Short(remaining.Round(time.Minute)),
+			}),
+			now,
+		)
+		evt.EventID = event.DeriveScopedEventID(evt, p.KeyExpiry+"|"+durfmt.Short(crossed))
+		result = append(result, evt)
+	}
+	if err := d.save(prev, next); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// keyExpiryThresholds returns thresholds, or DefaultKeyExpiryThresholds when
+// empty, deduplicated and sorted longest first.
+func keyExpiryThresholds(thresholds []time.Duration) []time.Duration {
+	if len(thresholds) == 0 {
+		thresholds = DefaultKeyExpiryThresholds
+	}
+	sorted := slices.Clone(thresholds)
+	slices.Sort(sorted)
+	slices.Reverse(sorted)
+	return slices.Compact(sorted)
+}
+
+// crossKeyExpiryThresholds marks the thresholds that remaining has crossed in
+// rec, resetting rec when the key expiry changed, and returns the tightest
+// newly crossed threshold, or 0. thresholds must be sorted longest first;
+// longer thresholds crossed in the same cycle are marked fired without their
+// own event.
+func crossKeyExpiryThresholds(thresholds []time.Duration, rec keyExpiryRecord, keyExpiry string, remaining time.Duration) (keyExpiryRecord, time.Duration) {
+	if rec.KeyExpiry != keyExpiry {
+		rec = keyExpiryRecord{KeyExpiry: keyExpiry}
+	}
+	var crossed time.Duration
+	for _, threshold := range thresholds {
+		label := durfmt.Short(threshold)
+		if remaining > threshold || slices.Contains(rec.Fired, label) {
+			continue
+		}
+		rec.Fired = append(rec.Fired, label)
+		crossed = threshold
+	}
+	return rec, crossed
+}
+
+func (d *KeyExpiryDetector) load() (keyExpiryState, error) {
+	if d.store == nil {
+		return d.fired, nil
+	}
+	out := keyExpiryState{}
+	if err := loadDetectorState(d.store, d.Name(), &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (d *KeyExpiryDetector) save(prev, next keyExpiryState) error {
+	d.fired = next
+	return saveDetectorState(d.store, d.Name(), prev, next)
+}
@@ -0,0 +1,142 @@
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+type memoryDetectorStore map[string]json.RawMessage
+
+func (m memoryDetectorStore) LoadDetectorState(name string) (json.RawMessage, error) {
+	return m[name], nil
+}
+
+func (m memoryDetectorStore) SaveDetectorState(name string, data json.RawMessage) error {
+	m[name] = data
+	return nil
+}
+
+func keyExpirySnapshot(expiry time.Time) snapshot.Snapshot {
+	return snapshot.Snapshot{
+		Hash:  "hash",
+		Peers: []snapshot.Peer{{ID: "server-1", Name: "server-1", KeyExpiry: expiry.Format(time.RFC3339)}},
+	}
+}
+
+func TestKeyExpiryDetectorFiresEachThresholdOnce(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	expiry := start.Add(10 * 24 * time.Hour)
+	store := memoryDetectorStore{}
+	d := NewKeyExpiryDetector([]time.Duration{24 * time.Hour, 7 * 24 * time.Hour}, store)
+	now := start
+	d.now = func() time.Time { return now }
+	current := keyExpirySnapshot(expiry)
+
+	events, err := d.Detect(context.Background(), snapshot.Snapshot{}, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no events 10 days out, got %d", len(events))
+	}
+
+	now = start.Add(4 * 24 * time.Hour)
+	events, err = d.Tick(context.Background(), current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypePeerKeyExpiryApproaching {
+		t.Fatalf("expected one approaching event at 6 days, got %#v", events)
+	}
+	if events[0].Payload["threshold"] != "168h" || events[0].Payload["remaining"] != "144h" {
+		t.Fatalf("unexpected payload: %#v", events[0].Payload)
+	}
+	sevenDayID := events[0].EventID
+
+	// A restarted detector reads fired thresholds back from the store.
+	d = NewKeyExpiryDetector([]time.Duration{24 * time.Hour, 7 * 24 * time.Hour}, store)
+	d.now = func() time.Time { return now }
+	events, err = d.Tick(context.Background(), current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected 7d threshold to fire once, got %d events", len(events))
+	}
+
+	now = expiry.Add(-12 * time.Hour)
+	events, err = d.Tick(context.Background(), current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Payload["threshold"] != "24h" {
+		t.Fatalf("expected 24h threshold event, got %#v", events)
+	}
+	if events[0].EventID == sevenDayID {
+		t.Fatal("expected distinct event IDs per threshold on the same snapshot")
+	}
+}
+
+func TestKeyExpiryDetectorReportsOnlyTightestThresholdCrossed(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	d := NewKeyExpiryDetector(nil, nil)
+	d.now = func() time.Time { return now }
+	current := keyExpirySnapshot(now.Add(12 * time.Hour))
+
+	events, err := d.Tick(context.Background(), current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Payload["threshold"] != "24h" {
+		t.Fatalf("expected a single 24h event, got %#v", events)
+	}
+	events, err = d.Tick(context.Background(), current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected longer thresholds to be marked fired, got %#v", events)
+	}
+}
+
+func TestKeyExpiryDetectorRearmsAfterRenewal(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	d := NewKeyExpiryDetector([]time.Duration{24 * time.Hour}, memoryDetectorStore{})
+	d.now = func() time.Time { return now }
+
+	if events, _ := d.Tick(context.Background(), keyExpirySnapshot(now.Add(time.Hour))); len(events) != 1 {
+		t.Fatalf("expected initial event, got %d", len(events))
+	}
+	renewed := keyExpirySnapshot(now.Add(180 * 24 * time.Hour))
+	if events, _ := d.Detect(context.Background(), snapshot.Snapshot{}, renewed); len(events) != 0 {
+		t.Fatalf("expected no event after renewal, got %d", len(events))
+	}
+	now = now.Add(180*24*time.Hour - time.Hour)
+	if events, _ := d.Tick(context.Background(), renewed); len(events) != 1 {
+		t.Fatalf("expected renewed key to fire again, got %d", len(events))
+	}
+}
+
+func TestKeyExpiryDetectorIgnoresNonExpiringAndExpiredKeys(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	d := NewKeyExpiryDetector(nil, nil)
+	d.now = func() time.Time { return now }
+	current := snapshot.Snapshot{Peers: []snapshot.Peer{
+		{ID: "no-expiry", KeyExpiry: "0001-01-01T00:00:00Z"},
+		{ID: "empty"},
+		{ID: "expired", KeyExpiry: now.Add(-time.Hour).Format(time.RFC3339)},
+		{ID: "flagged", Expired: true, KeyExpiry: now.Add(time.Hour).Format(time.RFC3339)},
+	}}
+	events, err := d.Tick(context.Background(), current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no events, got %#v", events)
+	}
+}
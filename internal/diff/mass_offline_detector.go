@@ -0,0 +1,288 @@
+package diff
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/durfmt"
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+const (
+	DefaultMassOfflineWindow   = 5 * time.Minute
+	DefaultMassOfflineMinPeers = 10
+
+	// SuppressReasonMassOffline is the policy suppression reason for
+	// presence events covered by a tailnet.mass_offline event.
+	SuppressReasonMassOffline = "mass_offline"
+
+	// massOfflineScopeTailnet is the scope of the tailnet-wide outage; tag
+	// groups use the tag as their scope.
+	massOfflineScopeTailnet = "tailnet"
+	// massOfflineMinGroupPeers keeps a single peer of a small tag group from
+	// counting as a mass outage.
+	massOfflineMinGroupPeers = 2
+)
+
+// MassOfflineDetector collapses many peers going offline together into one
+// event. It emits tailnet.mass_offline when at least minPeers peers, or
+// the configured percentage of a tag group, went offline within the window
+// and are still offline. The individual presence events of the affected
+// peers are held back through Suppress, as long as the aggregate event is
+// routed to a sink. tailnet.mass_offline.recovered
+// follows once every affected peer is online again or has left the
+// tailnet.
+type MassOfflineDetector struct {
+	window         time.Duration
+	minPeers       int
+	tagPercentages map[string]int
+	store          DetectorStateStore
+	state          massOfflineState
+	// covered holds the peers whose presence events the last check
+	// accounted for, including outages that just recovered.
+	covered map[string]struct{}
+	// routed reports whether an aggregate event would be delivered; nil
+	// treats every event as delivered.
+	routed func(event.Event) bool
+	now    func() time.Time
+}
+
+// MassOfflineOption customizes a MassOfflineDetector.
+type MassOfflineOption func(*MassOfflineDetector)
+
+// WithMassOfflineRouted makes the detector suppress the presence events of
+// an outage only while routed reports its tailnet.mass_offline or
+// tailnet.mass_offline.recovered event as delivered, so they are not lost
+// when no route takes the aggregate.
+func WithMassOfflineRouted(routed func(event.Event) bool) MassOfflineOption {
+	return func(d *MassOfflineDetector) {
+		d.routed = routed
+	}
+}
+
+type massOfflineState struct {
+	// Offline maps peer IDs to when they went offline, for peers still
+	// offline that went down within the window.
+	Offline map[string]time.Time `json:"offline,omitempty"`
+	// Outages maps scope to the active outage.
+	Outages map[string]massOutage `json:"outages,omitempty"`
+}
+
+type massOutage struct {
+	Since time.Time `json:"since"`
+	Peers []string  `json:"peers"`
+}
+
+// NewMassOfflineDetector returns a mass-offline detector. Zero window or
+// minPeers use the defaults. When store is nil, state is only kept in
+// memory.
+func NewMassOfflineDetector(window time.Duration, minPeers int, tagPercentages map[string]int, store DetectorStateStore, opts ...MassOfflineOption) *MassOfflineDetector {
+	if window <= 0 {
+		window = DefaultMassOfflineWindow
+	}
+	if minPeers <= 0 {
+		minPeers = DefaultMassOfflineMinPeers
+	}
+	d := &MassOfflineDetector{
+		window:         window,
+		minPeers:       minPeers,
+		tagPercentages: tagPercentages,
+		store:          store,
+		covered:        map[string]struct{}{},
+		now:            time.Now,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+func (d *MassOfflineDetector) Name() string { return "mass_offline" }
+
+// Suppress reports whether evt is a presence event for a peer covered by a
+// mass-offline event. It implements policy.Suppressor.
+func (d *MassOfflineDetector) Suppress(evt event.Event) (string, bool) {
+	if evt.EventType != event.TypePeerOnline && evt.EventType != event.TypePeerOffline {
+		return "", false
+	}
+	if _, ok := d.covered[evt.SubjectID]; ok {
+		return SuppressReasonMassOffline, true
+	}
+	return "", false
+}
+
+func (d *MassOfflineDetector) Detect(_ context.Context, before, after snapshot.Snapshot) ([]event.Event, error) {
+	prev, err := d.load()
+	if err != nil {
+		return nil, err
+	}
+	now := d.now().UTC()
+	next := massOfflineState{Offline: map[string]time.Time{}, Outages: map[string]massOutage{}}
+	peers := snapshot.IndexByPeerID(after)
+	old := snapshot.IndexByPeerID(before)
+
+	cutoff := now.Add(-d.window)
+	for id, since := range prev.Offline {
+		if p, ok := peers[id]; ok && !p.Online && since.After(cutoff) {
+			next.Offline[id] = since
+		}
+	}
+	for id, p := range peers {
+		if o, ok := old[id]; ok && o.Online && !p.Online {
+			next.Offline[id] = now
+		}
+	}
+
+	result := make([]event.Event, 0)
+	covered := map[string]struct{}{}
+	cover := func(aggregate event.Event, ids []string) {
+		if d.routed != nil && !d.routed(aggregate) {
+			return
+		}
+		for _, id := range ids {
+			covered[id] = struct{}{}
+		}
+	}
+	for _, scope := range d.scopes(prev) {
+		members := d.members(scope, after)
+		outage, active := prev.Outages[scope]
+		if !active {
+			recent := make([]string, 0)
+			for _, id := range members {
+				if _, ok := next.Offline[id]; ok {
+					recent = append(recent, id)
+				}
+			}
+			if !d.triggered(scope, len(recent), len(members)) {
+				continue
+			}
+			outage = massOutage{Since: now, Peers: recent}
+		}
+		// An open outage is checked against the event it started with, so
+		// its peers stay covered while that event's routes deliver it.
+		started := d.event(event.TypeTailnetMassOffline, scope, outage, peers, old, before.Hash, after.Hash, now, map[string]any{
+			"offline": len(outage.Peers),
+			"total":   len(members),
+			"window":  durfmt.Short(d.window),
+		})
+		if !active {
+			result = append(result, started)
+		} else {
+			// Peers of the scope that go down while the outage is open
+			// join it.
+			for _, id := range members {
+				if _, ok := next.Offline[id]; ok && !slices.Contains(outage.Peers, id) {
+					outage.Peers = append(outage.Peers, id)
+				}
+			}
+			sort.Strings(outage.Peers)
+			stillOffline := 0
+			for _, id := range outage.Peers {
+				if p, ok := peers[id]; ok && !p.Online {
+					stillOffline++
+				}
+			}
+			if stillOffline == 0 {
+				recovered := d.event(event.TypeTailnetMassOfflineRecovered, scope, outage, peers, old, before.Hash, after.Hash, now, map[string]any{
+					"duration": durfmt.Short(now.Sub(outage.Since).Round(time.Second)),
+				})
+				result = append(result, recovered)
+				cover(recovered, outage.Peers)
+				continue
+			}
+		}
+		next.Outages[scope] = outage
+		cover(started, outage.Peers)
+	}
+
+	if err := d.save(prev, next); err != nil {
+		return nil, err
+	}
+	d.covered = covered
+	return result, nil
+}
+
+// scopes returns the tailnet scope, every configured tag, and any scope with
+// an outage still open from an earlier config, sorted.
+func (d *MassOfflineDetector) scopes(prev massOfflineState) []string {
+	set := map[string]struct{}{massOfflineScopeTailnet: {}}
+	for tag := range d.tagPercentages {
+		set[tag] = struct{}{}
+	}
+	for scope := range prev.Outages {
+		set[scope] = struct{}{}
+	}
+	out := make([]string, 0, len(set))
+	for scope := range set {
+		out = append(out, scope)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// members returns the sorted IDs of the peers in scope.
+func (d *MassOfflineDetector) members(scope string, s snapshot.Snapshot) []string {
+	out := make([]string, 0, len(s.Peers))
+	for _, p := range s.Peers {
+		if scope == massOfflineScopeTailnet || containsFold([]string{scope}, p.Tags) {
+			out = append(out, p.ID)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// triggered reports whether offline of total peers in scope is a mass
+// outage. Both thresholds are inclusive: min_peers peers, or exactly the
+// tag's percentage, is enough.
+func (d *MassOfflineDetector) triggered(scope string, offline, total int) bool {
+	if scope == massOfflineScopeTailnet {
+		return offline >= d.minPeers
+	}
+	percent, ok := d.tagPercentages[scope]
+	if !ok || percent <= 0 || total == 0 || offline < massOfflineMinGroupPeers {
+		return false
+	}
+	return offline*100 >= percent*total
+}
+
+func (d *MassOfflineDetector) event(eventType, scope string, outage massOutage, peers, old map[string]snapshot.Peer, beforeHash, afterHash string, now time.Time, extras map[string]any) event.Event {
+	affected := make([]map[string]any, 0, len(outage.Peers))
+	for _, id := range outage.Peers {
+		p, ok := peers[id]
+		if !ok {
+			if p, ok = old[id]; !ok {
+				p = snapshot.Peer{ID: id}
+			}
+		}
+		affected = append(affected, map[string]any{
+			"id":   p.ID,
+			"name": p.Name,
+			"tags": normalizedIdentitySlice(p.Tags),
+		})
+	}
+	payload := mergePayload(map[string]any{"scope": scope, "peers": affected}, extras)
+	evt := event.NewTailnetEvent(eventType, scope, beforeHash, afterHash, payload, now)
+	// Each outage is its own episode, so scope IDs to when it started.
+	evt.EventID = event.DeriveScopedEventID(evt, outage.Since.Format(time.RFC3339Nano))
+	return evt
+}
+
+func (d *MassOfflineDetector) load() (massOfflineState, error) {
+	if d.store == nil {
+		return d.state, nil
+	}
+	out := massOfflineState{}
+	if err := loadDetectorState(d.store, d.Name(), &out); err != nil {
+		return massOfflineState{}, err
+	}
+	return out, nil
+}
+
+func (d *MassOfflineDetector) save(prev, next massOfflineState) error {
+	d.state = next
+	return saveDetectorState(d.store, d.Name(), prev, next)
+}
@@ -0,0 +1,158 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+func massOfflineSnapshot(hash string, online func(i int) bool, n int, tags ...string) snapshot.Snapshot {
+	s := snapshot.Snapshot{Hash: hash}
+	for i := 0; i < n; i++ {
+		s.Peers = append(s.Peers, snapshot.Peer{ID: fmt.Sprintf("n%02d", i), Name: fmt.Sprintf("host-%02d", i), Online: online(i), Tags: tags})
+	}
+	return s
+}
+
+func TestMassOfflineDetectorCollapsesOutage(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	d := NewMassOfflineDetector(time.Minute, 3, nil, memoryDetectorStore{})
+	d.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	up := massOfflineSnapshot("h1", func(int) bool { return true }, 5)
+	down := massOfflineSnapshot("h2", func(i int) bool { return i >= 3 }, 5)
+	events, err := d.Detect(ctx, up, down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypeTailnetMassOffline || events[0].SubjectID != "tailnet" {
+		t.Fatalf("expected one mass_offline event, got %#v", events)
+	}
+	if events[0].Payload["offline"] != 3 || events[0].Payload["total"] != 5 || len(events[0].Payload["peers"].([]map[string]any)) != 3 {
+		t.Fatalf("unexpected payload: %#v", events[0].Payload)
+	}
+	for _, tc := range []struct {
+		evt  event.Event
+		want bool
+	}{
+		{event.Event{EventType: event.TypePeerOffline, SubjectID: "n00"}, true},
+		{event.Event{EventType: event.TypePeerOffline, SubjectID: "n04"}, false},
+		{event.Event{EventType: event.TypePeerRoutesChanged, SubjectID: "n00"}, false},
+	} {
+		if reason, ok := d.Suppress(tc.evt); ok != tc.want || (ok && reason != SuppressReasonMassOffline) {
+			t.Fatalf("Suppress(%s %s) = %q, %v; want %v", tc.evt.EventType, tc.evt.SubjectID, reason, ok, tc.want)
+		}
+	}
+
+	// The outage stays open until every affected peer is back.
+	now = now.Add(2 * time.Minute)
+	partial := massOfflineSnapshot("h3", func(i int) bool { return i == 0 || i >= 3 }, 5)
+	if events, _ := d.Detect(ctx, down, partial); len(events) != 0 {
+		t.Fatalf("expected outage to stay open, got %#v", events)
+	}
+	if _, ok := d.Suppress(event.Event{EventType: event.TypePeerOnline, SubjectID: "n00"}); !ok {
+		t.Fatal("expected returning peer's online event to be suppressed")
+	}
+
+	now = now.Add(time.Minute)
+	events, err = d.Detect(ctx, partial, up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypeTailnetMassOfflineRecovered || events[0].Payload["duration"] != "3m" {
+		t.Fatalf("expected recovery, got %#v", events)
+	}
+	if _, ok := d.Suppress(event.Event{EventType: event.TypePeerOnline, SubjectID: "n01"}); !ok {
+		t.Fatal("expected online events in the recovery cycle to be suppressed")
+	}
+
+	if events, _ := d.Detect(ctx, up, up); len(events) != 0 {
+		t.Fatalf("expected quiet cycle, got %#v", events)
+	}
+	if _, ok := d.Suppress(event.Event{EventType: event.TypePeerOffline, SubjectID: "n00"}); ok {
+		t.Fatal("expected no suppression once the outage is over")
+	}
+}
+
+func TestMassOfflineDetectorIgnoresSpreadOutOfflines(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	d := NewMassOfflineDetector(time.Minute, 2, nil, nil)
+	d.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	s0 := massOfflineSnapshot("h0", func(int) bool { return true }, 3)
+	s1 := massOfflineSnapshot("h1", func(i int) bool { return i != 0 }, 3)
+	s2 := massOfflineSnapshot("h2", func(i int) bool { return i == 2 }, 3)
+	if events, _ := d.Detect(ctx, s0, s1); len(events) != 0 {
+		t.Fatalf("expected no outage for one peer, got %#v", events)
+	}
+	now = now.Add(5 * time.Minute)
+	if events, _ := d.Detect(ctx, s1, s2); len(events) != 0 {
+		t.Fatalf("expected offlines outside the window not to count, got %#v", events)
+	}
+}
+
+func TestMassOfflineDetectorTagPercentage(t *testing.T) {
+	d := NewMassOfflineDetector(time.Minute, 100, map[string]int{"tag:office": 50}, nil)
+	ctx := context.Background()
+
+	up := massOfflineSnapshot("h1", func(int) bool { return true }, 4, "tag:office")
+	down := massOfflineSnapshot("h2", func(i int) bool { return i >= 2 }, 4, "tag:office")
+	events, err := d.Detect(ctx, up, down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].SubjectID != "tag:office" || events[0].Payload["scope"] != "tag:office" {
+		t.Fatalf("expected tag group outage, got %#v", events)
+	}
+}
+
+func TestMassOfflineDetectorSuppressesOnlyWhenAggregateIsRouted(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	routedTypes := map[string]bool{}
+	d := NewMassOfflineDetector(time.Minute, 3, nil, nil, WithMassOfflineRouted(func(evt event.Event) bool {
+		return routedTypes[evt.EventType]
+	}))
+	d.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	up := massOfflineSnapshot("h1", func(int) bool { return true }, 5)
+	down := massOfflineSnapshot("h2", func(i int) bool { return i >= 3 }, 5)
+	events, err := d.Detect(ctx, up, down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypeTailnetMassOffline {
+		t.Fatalf("expected the aggregate event regardless of routing, got %#v", events)
+	}
+	offline := event.Event{EventType: event.TypePeerOffline, SubjectID: "n00"}
+	if _, ok := d.Suppress(offline); ok {
+		t.Fatal("expected peer.offline to pass when no route delivers tailnet.mass_offline")
+	}
+
+	// Once a route takes the aggregate, the open outage covers its peers.
+	routedTypes[event.TypeTailnetMassOffline] = true
+	if _, err := d.Detect(ctx, down, down); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.Suppress(offline); !ok {
+		t.Fatal("expected peer.offline to be suppressed once tailnet.mass_offline is routed")
+	}
+
+	// The recovery cycle is checked against the recovered event.
+	events, err = d.Detect(ctx, down, up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypeTailnetMassOfflineRecovered {
+		t.Fatalf("expected recovery, got %#v", events)
+	}
+	if _, ok := d.Suppress(event.Event{EventType: event.TypePeerOnline, SubjectID: "n00"}); ok {
+		t.Fatal("expected peer.online to pass when no route delivers tailnet.mass_offline.recovered")
+	}
+}
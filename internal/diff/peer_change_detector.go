@@ -2,19 +2,67 @@ package diff
 
 import (
 	"context"
+	"reflect"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/jaxxstorm/sentinel/internal/event"
 	"github.com/jaxxstorm/sentinel/internal/snapshot"
 )
 
+// Peer changes the peer_changes detector can report besides peers being
+// added and removed.
+const (
+	PeerChangeRoutes            = "routes"
+	PeerChangeTags              = "tags"
+	PeerChangeMachineAuthorized = "machine_authorized"
+	PeerChangeKeyExpiry         = "key_expiry"
+	PeerChangeHostinfo          = "hostinfo"
+)
+
+// PeerChanges lists every change peer_changes can report.
+var PeerChanges = []string{PeerChangeRoutes, PeerChangeTags, PeerChangeMachineAuthorized, PeerChangeKeyExpiry, PeerChangeHostinfo}
+
 type PeerChangeDetector struct {
-	now func() time.Time
+	changes        map[string]bool
+	hostinfoFields []hostinfoField
+	now            func() time.Time
 }
 
-func NewPeerChangeDetector() *PeerChangeDetector {
-	return &PeerChangeDetector{now: time.Now}
+// PeerChangeOption customizes a PeerChangeDetector.
+type PeerChangeOption func(*PeerChangeDetector)
+
+// WithPeerChanges limits peer_changes to the listed changes. Empty reports
+// all of them. key_expiry covers both peer.key_expiry.changed and
+// peer.key_expired.
+func WithPeerChanges(changes ...string) PeerChangeOption {
+	return func(d *PeerChangeDetector) {
+		d.changes = selectedKinds(changes, PeerChanges)
+	}
+}
+
+// WithIgnoreHostinfoFields stops peer_changes diffing the listed Hostinfo
+// fields.
+func WithIgnoreHostinfoFields(fields ...string) PeerChangeOption {
+	return func(d *PeerChangeDetector) {
+		ignored := selectedKinds(fields, nil)
+		kept := make([]hostinfoField, 0, len(hostinfoFields))
+		for _, f := range hostinfoFields {
+			if !ignored[f.name] {
+				kept = append(kept, f)
+			}
+		}
+		d.hostinfoFields = kept
+	}
+}
+
+func NewPeerChangeDetector(opts ...PeerChangeOption) *PeerChangeDetector {
+	d := &PeerChangeDetector{changes: selectedKinds(nil, PeerChanges), hostinfoFields: hostinfoFields, now: time.Now}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
 }
 
 func (d *PeerChangeDetector) Name() string { return "peer_changes" }
@@ -43,7 +91,7 @@ func (d *PeerChangeDetector) Detect(_ context.Context, before, after snapshot.Sn
 			continue
 		}
 
-		if !stringSliceEqual(old.Routes, p.Routes) {
+		if d.changes[PeerChangeRoutes] && !stringSliceEqual(old.Routes, p.Routes) {
 			result = append(result, event.NewPeerEvent(
 				event.TypePeerRoutesChanged,
 				id,
@@ -56,7 +104,7 @@ func (d *PeerChangeDetector) Detect(_ context.Context, before, after snapshot.Sn
 				d.now(),
 			))
 		}
-		if !stringSliceEqual(old.Tags, p.Tags) {
+		if d.changes[PeerChangeTags] && !stringSliceEqual(old.Tags, p.Tags) {
 			result = append(result, event.NewPeerEvent(
 				event.TypePeerTagsChanged,
 				id,
@@ -69,7 +117,7 @@ func (d *PeerChangeDetector) Detect(_ context.Context, before, after snapshot.Sn
 				d.now(),
 			))
 		}
-		if old.MachineAuthorized != p.MachineAuthorized {
+		if d.changes[PeerChangeMachineAuthorized] && old.MachineAuthorized != p.MachineAuthorized {
 			result = append(result, event.NewPeerEvent(
 				event.TypePeerMachineAuthorizedChanged,
 				id,
@@ -83,7 +131,7 @@ func (d *PeerChangeDetector) Detect(_ context.Context, before, after snapshot.Sn
 				d.now(),
 			))
 		}
-		if old.KeyExpiry != p.KeyExpiry {
+		if d.changes[PeerChangeKeyExpiry] && old.KeyExpiry != p.KeyExpiry {
 			result = append(result, event.NewPeerEvent(
 				event.TypePeerKeyExpiryChanged,
 				id,
@@ -96,7 +144,7 @@ func (d *PeerChangeDetector) Detect(_ context.Context, before, after snapshot.Sn
 				d.now(),
 			))
 		}
-		if !old.Expired && p.Expired {
+		if d.changes[PeerChangeKeyExpiry] && !old.Expired && p.Expired {
 			result = append(result, event.NewPeerEvent(
 				event.TypePeerKeyExpired,
 				id,
@@ -108,18 +156,21 @@ func (d *PeerChangeDetector) Detect(_ context.Context, before, after snapshot.Sn
 				d.now(),
 			))
 		}
-		if old.HostinfoHash != "" && p.HostinfoHash != "" && old.HostinfoHash != p.HostinfoHash {
-			result = append(result, event.NewPeerEvent(
-				event.TypePeerHostinfoChanged,
-				id,
-				before.Hash,
-				after.Hash,
-				mergePayload(deviceIdentityPayload(p), map[string]any{
-					"before_hostinfo_hash": old.HostinfoHash,
-					"after_hostinfo_hash":  p.HostinfoHash,
-				}),
-				d.now(),
-			))
+		if d.changes[PeerChangeHostinfo] {
+			if changes, ok := d.hostinfoChanges(old, p); ok {
+				result = append(result, event.NewPeerEvent(
+					event.TypePeerHostinfoChanged,
+					id,
+					before.Hash,
+					after.Hash,
+					mergePayload(deviceIdentityPayload(p), map[string]any{
+						"before_hostinfo_hash": old.HostinfoHash,
+						"after_hostinfo_hash":  p.HostinfoHash,
+						"changes":              changes,
+					}),
+					d.now(),
+				))
+			}
 		}
 	}
 
@@ -141,6 +192,27 @@ func (d *PeerChangeDetector) Detect(_ context.Context, before, after snapshot.Sn
 	return result, nil
 }
 
+// hostinfoChanges reports the tracked Hostinfo fields that differ between
+// old and p. Peers recorded before Hostinfo was tracked field by field fall
+// back to comparing the Hostinfo hash, with no field changes; a peer with
+// Hostinfo on only one side is not compared.
+func (d *PeerChangeDetector) hostinfoChanges(old, p snapshot.Peer) ([]map[string]any, bool) {
+	if old.Hostinfo == nil || p.Hostinfo == nil {
+		changed := old.Hostinfo == nil && p.Hostinfo == nil &&
+			old.HostinfoHash != "" && p.HostinfoHash != "" && old.HostinfoHash != p.HostinfoHash
+		return []map[string]any{}, changed
+	}
+	changes := make([]map[string]any, 0)
+	for _, f := range d.hostinfoFields {
+		before, after := f.value(*old.Hostinfo), f.value(*p.Hostinfo)
+		if reflect.DeepEqual(before, after) {
+			continue
+		}
+		changes = append(changes, map[string]any{"field": f.name, "before": before, "after": after})
+	}
+	return changes, len(changes) > 0
+}
+
 func sortedPeerIDs(peers map[string]snapshot.Peer) []string {
 	ids := make([]string, 0, len(peers))
 	for id := range peers {
@@ -161,3 +233,16 @@ func stringSliceEqual(a, b []string) bool {
 	}
 	return true
 }
+
+// selectedKinds returns the set of selected kinds, or every kind when
+// selected is empty.
+func selectedKinds(selected, all []string) map[string]bool {
+	if len(selected) == 0 {
+		selected = all
+	}
+	out := make(map[string]bool, len(selected))
+	for _, kind := range selected {
+		out[strings.ToLower(strings.TrimSpace(kind))] = true
+	}
+	return out
+}
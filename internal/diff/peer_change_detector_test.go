@@ -2,6 +2,8 @@ package diff
 
 import (
 	"context"
+	"reflect"
+	"slices"
 	"testing"
 	"time"
 
@@ -119,3 +121,77 @@ func assertPeerIdentityPayload(t *testing.T, payload map[string]any) {
 		}
 	}
 }
+
+func TestPeerChangeDetectorReportsSelectedChanges(t *testing.T) {
+	d := NewPeerChangeDetector(WithPeerChanges(PeerChangeRoutes, "Key_Expiry"))
+	before := snapshot.Snapshot{Hash: "before", Peers: []snapshot.Peer{{
+		ID: "peer1", Routes: []string{"10.0.0.0/24"}, Tags: []string{"tag:a"}, KeyExpiry: "2026-01-01T00:00:00Z", HostinfoHash: "h1",
+	}}}
+	after := snapshot.Snapshot{Hash: "after", Peers: []snapshot.Peer{{
+		ID: "peer1", Routes: []string{"10.0.1.0/24"}, Tags: []string{"tag:b"}, KeyExpiry: "2026-02-01T00:00:00Z", HostinfoHash: "h2", Expired: true,
+	}}}
+
+	events, err := d.Detect(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(events))
+	for _, evt := range events {
+		got = append(got, evt.EventType)
+	}
+	want := []string{event.TypePeerRoutesChanged, event.TypePeerKeyExpiryChanged, event.TypePeerKeyExpired}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestPeerChangeDetectorReportsHostinfoFieldChanges(t *testing.T) {
+	before := snapshot.Snapshot{Hash: "before", Peers: []snapshot.Peer{{
+		ID: "peer1", Name: "laptop", HostinfoHash: "h1",
+		Hostinfo: &snapshot.Hostinfo{OS: "macOS", OSVersion: "14.4", IPNVersion: "1.80.0", Services: []string{"tcp:22"}},
+	}}}
+	after := snapshot.Snapshot{Hash: "after", Peers: []snapshot.Peer{{
+		ID: "peer1", Name: "laptop", HostinfoHash: "h2",
+		Hostinfo: &snapshot.Hostinfo{OS: "macOS", OSVersion: "14.5", IPNVersion: "1.82.0", ShieldsUp: true, Services: []string{"tcp:22"}},
+	}}}
+
+	events, err := NewPeerChangeDetector().Detect(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypePeerHostinfoChanged {
+		t.Fatalf("expected one hostinfo event, got %#v", events)
+	}
+	want := []map[string]any{
+		{"field": HostinfoOSVersion, "before": "14.4", "after": "14.5"},
+		{"field": HostinfoIPNVersion, "before": "1.80.0", "after": "1.82.0"},
+		{"field": HostinfoShieldsUp, "before": false, "after": true},
+	}
+	if got := events[0].Payload["changes"]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected changes:\n got %#v\nwant %#v", got, want)
+	}
+
+	// Ignoring every changed field leaves nothing to report, even though
+	// the hash changed.
+	d := NewPeerChangeDetector(WithIgnoreHostinfoFields(HostinfoOSVersion, HostinfoIPNVersion, HostinfoShieldsUp))
+	events, err = d.Detect(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected ignored fields to be dropped, got %#v", events)
+	}
+}
+
+func TestPeerChangeDetectorSkipsHostinfoUntilBothSidesTracked(t *testing.T) {
+	before := snapshot.Snapshot{Hash: "before", Peers: []snapshot.Peer{{ID: "peer1", HostinfoHash: "h1"}}}
+	after := snapshot.Snapshot{Hash: "after", Peers: []snapshot.Peer{{ID: "peer1", HostinfoHash: "h2", Hostinfo: &snapshot.Hostinfo{OS: "linux"}}}}
+
+	events, err := NewPeerChangeDetector().Detect(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no hostinfo event against an untracked baseline, got %#v", events)
+	}
+}
@@ -9,11 +9,27 @@ import (
 )
 
 type PresenceDetector struct {
-	now func() time.Time
+	ignoreTags []string
+	now        func() time.Time
 }
 
-func NewPresenceDetector() *PresenceDetector {
-	return &PresenceDetector{now: time.Now}
+// PresenceOption customizes a PresenceDetector.
+type PresenceOption func(*PresenceDetector)
+
+// WithIgnoreTags makes presence ignore peers carrying any of tags, such as
+// tagged ephemeral CI nodes.
+func WithIgnoreTags(tags ...string) PresenceOption {
+	return func(d *PresenceDetector) {
+		d.ignoreTags = tags
+	}
+}
+
+func NewPresenceDetector(opts ...PresenceOption) *PresenceDetector {
+	d := &PresenceDetector{now: time.Now}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
 }
 
 func (d *PresenceDetector) Name() string { return "presence" }
@@ -24,6 +40,9 @@ func (d *PresenceDetector) Detect(_ context.Context, before, after snapshot.Snap
 	result := make([]event.Event, 0)
 
 	for id, p := range next {
+		if containsFold(d.ignoreTags, p.Tags) {
+			continue
+		}
 		old, exists := prev[id]
 		if !exists {
 			if p.Online {
@@ -44,7 +63,7 @@ func (d *PresenceDetector) Detect(_ context.Context, before, after snapshot.Snap
 		if _, exists := next[id]; exists {
 			continue
 		}
-		if !old.Online {
+		if !old.Online || containsFold(d.ignoreTags, old.Tags) {
 			continue
 		}
 		result = append(result, event.NewPresenceEvent(
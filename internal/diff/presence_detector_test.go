@@ -72,3 +72,25 @@ func assertDeviceIdentityPayload(t *testing.T, payload map[string]any) {
 		}
 	}
 }
+
+func TestPresenceDetectorIgnoresTaggedPeers(t *testing.T) {
+	d := NewPresenceDetector(WithIgnoreTags("tag:ci"))
+	before := snapshot.Snapshot{Hash: "before", Peers: []snapshot.Peer{
+		{ID: "runner1", Online: true, Tags: []string{"tag:ci"}},
+		{ID: "runner2", Online: true, Tags: []string{"TAG:CI"}},
+		{ID: "web", Online: true, Tags: []string{"tag:prod"}},
+	}}
+	after := snapshot.Snapshot{Hash: "after", Peers: []snapshot.Peer{
+		{ID: "runner1", Online: false, Tags: []string{"tag:ci"}},
+		{ID: "runner3", Online: true, Tags: []string{"tag:ci"}},
+		{ID: "web", Online: false, Tags: []string{"tag:prod"}},
+	}}
+
+	events, err := d.Detect(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].SubjectID != "web" || events[0].EventType != event.TypePeerOffline {
+		t.Fatalf("expected only web offline, got %#v", events)
+	}
+}
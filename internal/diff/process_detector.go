@@ -0,0 +1,217 @@
+package diff
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultProcessTimeout = 10 * time.Second
+
+	// maxProcessLineBytes bounds a single event line read from a process.
+	maxProcessLineBytes = 1 << 20
+	// maxProcessStdoutBytes bounds the output of one run; a process that
+	// writes more fails the run.
+	maxProcessStdoutBytes = 16 << 20
+	// maxProcessStderrBytes bounds the stderr kept for failure logs.
+	maxProcessStderrBytes = 4 << 10
+	// processWaitDelay bounds how long a killed process's children may keep
+	// its output pipes open.
+	processWaitDelay = time.Second
+)
+
+// ProcessDetectorConfig describes an external detector from
+// detectors.process.
+type ProcessDetectorConfig struct {
+	Name    string
+	Command string
+	Args    []string
+	Timeout time.Duration
+	// EventTypes lists the event types the process may emit. Output with
+	// any other event type fails the run.
+	EventTypes []string
+}
+
+// ProcessInput is written as JSON to an external detector's stdin.
+type ProcessInput struct {
+	Before snapshot.Snapshot `json:"before"`
+	After  snapshot.Snapshot `json:"after"`
+}
+
+// ProcessDetector runs an executable on every diff cycle. The process reads a
+// ProcessInput from stdin and writes newline-delimited event.Event JSON to
+// stdout. Failures are isolated: a crash, timeout or malformed output is
+// logged and the cycle continues without the detector's events. Cancellation
+// of the cycle's context is returned instead.
+type ProcessDetector struct {
+	cfg ProcessDetectorConfig
+	log *zap.Logger
+	now func() time.Time
+}
+
+// NewProcessDetector returns a process detector. A zero timeout uses
+// DefaultProcessTimeout; a nil logger discards failure logs.
+func NewProcessDetector(cfg ProcessDetectorConfig, log *zap.Logger) *ProcessDetector {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultProcessTimeout
+	}
+	if log == nil {
+		log = zap.NewNop()
+	}
+	return &ProcessDetector{cfg: cfg, log: log, now: time.Now}
+}
+
+func (d *ProcessDetector) Name() string { return d.cfg.Name }
+
+func (d *ProcessDetector) Detect(ctx context.Context, before, after snapshot.Snapshot) ([]event.Event, error) {
+	events, err := d.run(ctx, before, after)
+	if err != nil {
+		// Cancellation of the cycle is not a detector failure.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		d.log.Warn("external detector failed",
+			zap.String("detector", d.cfg.Name),
+			zap.String("command", d.cfg.Command),
+			zap.Error(err),
+		)
+		return []event.Event{}, nil
+	}
+	return events, nil
+}
+
+func (d *ProcessDetector) run(ctx context.Context, before, after snapshot.Snapshot) ([]event.Event, error) {
+	input, err := json.Marshal(ProcessInput{Before: before, After: after})
+	if err != nil {
+		return nil, fmt.Errorf("encode input: %w", err)
+	}
+	runCtx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, d.cfg.Command, d.cfg.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.WaitDelay = processWaitDelay
+	stdout := &limitedBuffer{limit: maxProcessStdoutBytes, strict: true}
+	stderr := &limitedBuffer{limit: maxProcessStderrBytes}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err = cmd.Run()
+	if stdout.exceeded {
+		return nil, fmt.Errorf("stdout exceeded %d bytes", maxProcessStdoutBytes)
+	}
+	if err != nil {
+		// Only the detector's own timeout is reported as one; a canceled
+		// cycle surfaces through ctx.
+		if ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("timed out after %s", d.cfg.Timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return d.parse(&stdout.buf, before.Hash, after.Hash)
+}
+
+// parse decodes one event per non-empty line. Any invalid line fails the
+// whole run so a half-broken process does not emit a partial set.
+func (d *ProcessDetector) parse(stdout *bytes.Buffer, beforeHash, afterHash string) ([]event.Event, error) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxProcessLineBytes)
+	now := d.now().UTC()
+	result := make([]event.Event, 0)
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var evt event.Event
+		if err := json.Unmarshal(raw, &evt); err != nil {
+			return nil, fmt.Errorf("stdout line %d: %w", line, err)
+		}
+		if err := d.normalize(&evt, beforeHash, afterHash, now); err != nil {
+			return nil, fmt.Errorf("stdout line %d: %w", line, err)
+		}
+		result = append(result, evt)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read stdout: %w", err)
+	}
+	return result, nil
+}
+
+// normalize validates evt and fills the fields a process may leave out.
+func (d *ProcessDetector) normalize(evt *event.Event, beforeHash, afterHash string, now time.Time) error {
+	if !slices.Contains(d.cfg.EventTypes, evt.EventType) {
+		return fmt.Errorf("event type %q is not listed in event_types", evt.EventType)
+	}
+	if strings.TrimSpace(evt.SubjectID) == "" {
+		return fmt.Errorf("event %q has no subject_id", evt.EventType)
+	}
+	if evt.SubjectType == "" {
+		evt.SubjectType = event.SubjectPeer
+	}
+	if evt.Severity == "" {
+		evt.Severity = event.SeverityInfo
+	}
+	if !event.IsKnownSeverity(evt.Severity) {
+		return fmt.Errorf("event %q has unknown severity %q", evt.EventType, evt.Severity)
+	}
+	evt.SchemaVersion = event.SchemaVersion
+	if evt.Timestamp.IsZero() {
+		evt.Timestamp = now
+	}
+	if evt.BeforeHash == "" {
+		evt.BeforeHash = beforeHash
+	}
+	if evt.AfterHash == "" {
+		evt.AfterHash = afterHash
+	}
+	if evt.Payload == nil {
+		evt.Payload = map[string]any{}
+	}
+	evt.Payload["detector"] = d.cfg.Name
+	if evt.EventID == "" {
+		evt.EventID = event.DeriveScopedEventID(*evt, d.cfg.Name)
+	}
+	return nil
+}
+
+// errOutputLimit stops copying a process's output past a strict
+// limitedBuffer's limit.
+var errOutputLimit = errors.New("output limit exceeded")
+
+// limitedBuffer keeps the first limit bytes written and discards the rest.
+// A strict buffer instead fails the write, which closes the process's pipe.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	strict   bool
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	room := b.limit - b.buf.Len()
+	b.buf.Write(p[:min(len(p), max(room, 0))])
+	if len(p) > room {
+		b.exceeded = true
+		if b.strict {
+			return max(room, 0), errOutputLimit
+		}
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string { return b.buf.String() }
@@ -0,0 +1,125 @@
+package diff
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func writeProcessScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "detector.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProcessDetectorReadsEventsFromStdout(t *testing.T) {
+	// The script echoes the after hash from its input back as the subject.
+	script := writeProcessScript(t, `
+input=$(cat)
+case "$input" in
+  *'"hash":"h2"'*) ;;
+  *) echo "missing after snapshot" >&2; exit 1 ;;
+esac
+echo '{"event_type":"custom.python.alert","subject_id":"db-1","severity":"warning","payload":{"reason":"history"}}'
+echo
+echo '{"event_type":"custom.python.alert","subject_id":"db-2"}'
+`)
+	d := NewProcessDetector(ProcessDetectorConfig{
+		Name:       "python",
+		Command:    script,
+		EventTypes: []string{"custom.python.alert"},
+	}, nil)
+	before := snapshot.Snapshot{Hash: "h1"}
+	after := snapshot.Snapshot{Hash: "h2", Peers: []snapshot.Peer{{ID: "db-1"}}}
+
+	events, err := d.Detect(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected two events, got %#v", events)
+	}
+	first := events[0]
+	if first.SubjectID != "db-1" || first.Severity != event.SeverityWarning || first.SubjectType != event.SubjectPeer {
+		t.Fatalf("unexpected event: %#v", first)
+	}
+	if first.BeforeHash != "h1" || first.AfterHash != "h2" || first.EventID == "" || first.Timestamp.IsZero() {
+		t.Fatalf("expected defaults to be filled, got %#v", first)
+	}
+	if first.Payload["reason"] != "history" || first.Payload["detector"] != "python" {
+		t.Fatalf("unexpected payload: %#v", first.Payload)
+	}
+	if events[1].Severity != event.SeverityInfo || events[0].EventID == events[1].EventID {
+		t.Fatalf("unexpected second event: %#v", events[1])
+	}
+}
+
+func TestProcessDetectorIsolatesFailures(t *testing.T) {
+	cases := map[string]struct {
+		script  string
+		timeout time.Duration
+		want    string
+	}{
+		"crash":              {script: "echo boom >&2\nexit 3\n", want: "boom"},
+		"timeout":            {script: "sleep 5\n", timeout: 100 * time.Millisecond, want: "timed out"},
+		"malformed":          {script: "echo not-json\n", want: "stdout line 1"},
+		"unlisted type":      {script: `echo '{"event_type":"peer.offline","subject_id":"a"}'` + "\n", want: `"peer.offline" is not listed`},
+		"missing subject":    {script: `echo '{"event_type":"custom.python.alert"}'` + "\n", want: "no subject_id"},
+		"unknown severity":   {script: `echo '{"event_type":"custom.python.alert","subject_id":"a","severity":"loud"}'` + "\n", want: "unknown severity"},
+		"partial then crash": {script: `echo '{"event_type":"custom.python.alert","subject_id":"a"}'` + "\nexit 1\n", want: "exit status 1"},
+		"runaway stdout":     {script: "while :; do echo aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa; done\n", timeout: time.Minute, want: "stdout exceeded"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			core, logs := observer.New(zap.WarnLevel)
+			d := NewProcessDetector(ProcessDetectorConfig{
+				Name:       "python",
+				Command:    writeProcessScript(t, tc.script),
+				Timeout:    tc.timeout,
+				EventTypes: []string{"custom.python.alert"},
+			}, zap.New(core))
+			events, err := d.Detect(context.Background(), snapshot.Snapshot{}, snapshot.Snapshot{})
+			if err != nil {
+				t.Fatalf("expected failure to be isolated, got %v", err)
+			}
+			if len(events) != 0 {
+				t.Fatalf("expected no events, got %#v", events)
+			}
+			entries := logs.All()
+			if len(entries) != 1 || !strings.Contains(entries[0].ContextMap()["error"].(string), tc.want) {
+				t.Fatalf("expected warning containing %q, got %#v", tc.want, entries)
+			}
+		})
+	}
+}
+
+func TestProcessDetectorReturnsCycleCancellation(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	d := NewProcessDetector(ProcessDetectorConfig{
+		Name:       "python",
+		Command:    writeProcessScript(t, "sleep 5\n"),
+		Timeout:    time.Minute,
+		EventTypes: []string{"custom.python.alert"},
+	}, zap.New(core))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, err := d.Detect(ctx, snapshot.Snapshot{}, snapshot.Snapshot{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the cycle's deadline to be returned, got %v", err)
+	}
+	if logs.Len() != 0 {
+		t.Fatalf("expected no detector failure logged for a canceled cycle, got %#v", logs.All())
+	}
+}
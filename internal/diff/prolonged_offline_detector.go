@@ -0,0 +1,162 @@
+package diff
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/durfmt"
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+const DefaultProlongedOfflineThreshold = 15 * time.Minute
+
+// ProlongedOfflineDetector emits peer.offline.prolonged once a peer has stayed
+// offline for its threshold. The time each peer went offline is persisted so
+// restarts do not reset the clock. Peers already offline when first seen, such
+// as long-dead devices on a fresh deploy or upgrade, are recorded without an
+// event; only peers seen going offline are reported.
+type ProlongedOfflineDetector struct {
+	threshold     time.Duration
+	tagThresholds map[string]time.Duration
+	store         DetectorStateStore
+	state         prolongedOfflineState
+	now           func() time.Time
+}
+
+type prolongedOfflineState map[string]prolongedOfflineRecord
+
+type prolongedOfflineRecord struct {
+	OfflineSince time.Time `json:"offline_since"`
+	Fired        bool      `json:"fired,omitempty"`
+}
+
+// NewProlongedOfflineDetector returns a detector that fires after threshold,
+// or after the smallest matching tagThresholds entry for tagged peers. A zero
+// threshold uses DefaultProlongedOfflineThreshold.
+func NewProlongedOfflineDetector(threshold time.Duration, tagThresholds map[string]time.Duration, store DetectorStateStore) *ProlongedOfflineDetector {
+	if threshold <= 0 {
+		threshold = DefaultProlongedOfflineThreshold
+	}
+	tags := make(map[string]time.Duration, len(tagThresholds))
+	for tag, d := range tagThresholds {
+		tags[strings.ToLower(strings.TrimSpace(tag))] = d
+	}
+	return &ProlongedOfflineDetector{
+		threshold:     threshold,
+		tagThresholds: tags,
+		store:         store,
+		state:         prolongedOfflineState{},
+		now:           time.Now,
+	}
+}
+
+func (d *ProlongedOfflineDetector) Name() string { return "prolonged_offline" }
+
+func (d *ProlongedOfflineDetector) Detect(_ context.Context, before, after snapshot.Snapshot) ([]event.Event, error) {
+	wentOffline := map[string]bool{}
+	for _, p := range before.Peers {
+		if p.Online {
+			wentOffline[p.ID] = true
+		}
+	}
+	return d.check(before.Hash, after, wentOffline)
+}
+
+func (d *ProlongedOfflineDetector) Tick(_ context.Context, current snapshot.Snapshot) ([]event.Event, error) {
+	return d.check(current.Hash, current, nil)
+}
+
+// check tracks the offline peers of current. wentOffline holds the peers that
+// were online before this cycle; other untracked offline peers were already
+// offline when first seen.
+func (d *ProlongedOfflineDetector) check(beforeHash string, current snapshot.Snapshot, wentOffline map[string]bool) ([]event.Event, error) {
+	prev, err := d.load()
+	if err != nil {
+		return nil, err
+	}
+	now := d.now().UTC()
+	peers := snapshot.IndexByPeerID(current)
+	next := prolongedOfflineState{}
+	result := make([]event.Event, 0)
+	for _, id := range sortedPeerIDs(peers) {
+		p := peers[id]
+		if p.Online {
+			continue
+		}
+		rec, tracked := prev[id]
+		if !tracked {
+			rec = prolongedOfflineRecord{OfflineSince: offlineSince(p, now), Fired: !wentOffline[id]}
+		}
+		threshold := d.thresholdFor(p)
+		offlineFor := now.Sub(rec.OfflineSince)
+		if !rec.Fired && offlineFor >= threshold {
+			rec.Fired = true
+			evt := event.NewPeerEvent(
+				event.TypePeerOfflineProlonged,
+				id,
+				beforeHash,
+				current.Hash,
+				mergePayload(deviceIdentityPayload(p), map[string]any{
+					"offline_since": rec.OfflineSince.Format(time.RFC3339),
+					"offline_for":   durfmt.Short(offlineFor.Round(time.Second)),
+					"threshold":     durfmt.Short(threshold),
+				}),
+				now,
+			)
+			evt.EventID = event.DeriveScopedEventID(evt, rec.OfflineSince.Format(time.RFC3339Nano))
+			result = append(result, evt)
+		}
+		next[id] = rec
+	}
+	if err := d.save(prev, next); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// thresholdFor returns the smallest tag threshold matching p, falling back
+// to the detector default.
+func (d *ProlongedOfflineDetector) thresholdFor(p snapshot.Peer) time.Duration {
+	threshold := time.Duration(0)
+	for _, tag := range p.Tags {
+		if t, ok := d.tagThresholds[strings.ToLower(tag)]; ok && (threshold == 0 || t < threshold) {
+			threshold = t
+		}
+	}
+	if threshold == 0 {
+		return d.threshold
+	}
+	return threshold
+}
+
+// offlineSince estimates when a peer first seen offline went down, using the
+// control plane's last_seen when it is available.
+func offlineSince(p snapshot.Peer, now time.Time) time.Time {
+	raw := strings.TrimSpace(p.Meta["last_seen"])
+	if raw == "" {
+		return now
+	}
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil || t.IsZero() || t.After(now) {
+		return now
+	}
+	return t.UTC()
+}
+
+func (d *ProlongedOfflineDetector) load() (prolongedOfflineState, error) {
+	if d.store == nil {
+		return d.state, nil
+	}
+	out := prolongedOfflineState{}
+	if err := loadDetectorState(d.store, d.Name(), &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (d *ProlongedOfflineDetector) save(prev, next prolongedOfflineState) error {
+	d.state = next
+	return saveDetectorState(d.store, d.Name(), prev, next)
+}
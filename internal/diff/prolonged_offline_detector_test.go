@@ -0,0 +1,122 @@
+package diff
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+func TestProlongedOfflineDetectorFiresOnceAfterThreshold(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	store := memoryDetectorStore{}
+	d := NewProlongedOfflineDetector(15*time.Minute, nil, store)
+	d.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	up := snapshot.Snapshot{Hash: "up", Peers: []snapshot.Peer{{ID: "db-1", Name: "db-1", Online: true}}}
+	down := snapshot.Snapshot{Hash: "down", Peers: []snapshot.Peer{{ID: "db-1", Name: "db-1", Online: false}}}
+	if events, _ := d.Detect(ctx, up, down); len(events) != 0 {
+		t.Fatalf("expected no event at the transition, got %#v", events)
+	}
+
+	// A restart must not reset the offline clock.
+	d = NewProlongedOfflineDetector(15*time.Minute, nil, store)
+	now = start.Add(16 * time.Minute)
+	d.now = func() time.Time { return now }
+	events, err := d.Tick(ctx, down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypePeerOfflineProlonged {
+		t.Fatalf("expected peer.offline.prolonged, got %#v", events)
+	}
+	if events[0].Payload["offline_since"] != "2026-03-01T12:00:00Z" || events[0].Payload["offline_for"] != "16m" {
+		t.Fatalf("unexpected payload: %#v", events[0].Payload)
+	}
+
+	now = now.Add(time.Hour)
+	if events, _ := d.Tick(ctx, down); len(events) != 0 {
+		t.Fatalf("expected a single event per outage, got %#v", events)
+	}
+
+	if events, _ := d.Detect(ctx, down, up); len(events) != 0 {
+		t.Fatalf("unexpected events on recovery: %#v", events)
+	}
+	if events, _ := d.Detect(ctx, up, down); len(events) != 0 {
+		t.Fatalf("expected the clock to restart for a new outage, got %#v", events)
+	}
+}
+
+func TestProlongedOfflineDetectorUsesTagThresholdsAndLastSeen(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	d := NewProlongedOfflineDetector(time.Hour, map[string]time.Duration{"tag:server": 5 * time.Minute, "tag:db": 10 * time.Minute}, nil)
+	d.now = func() time.Time { return now }
+
+	current := snapshot.Snapshot{Hash: "h", Peers: []snapshot.Peer{
+		{ID: "server", Tags: []string{"tag:db", "tag:server"}, Meta: map[string]string{"last_seen": now.Add(-6 * time.Minute).Format(time.RFC3339)}},
+		{ID: "laptop", Meta: map[string]string{"last_seen": now.Add(-30 * time.Minute).Format(time.RFC3339)}},
+		{ID: "unknown"},
+	}}
+	// The peers went offline while Sentinel was down, so last_seen dates
+	// the outage.
+	before := snapshot.Snapshot{Hash: "b", Peers: []snapshot.Peer{
+		{ID: "server", Online: true},
+		{ID: "laptop", Online: true},
+		{ID: "unknown", Online: true},
+	}}
+	events, err := d.Detect(context.Background(), before, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].SubjectID != "server" || events[0].Payload["threshold"] != "5m" {
+		t.Fatalf("expected only the tagged server to fire at 5m, got %#v", events)
+	}
+}
+
+func TestProlongedOfflineDetectorQuietForPeersAlreadyOfflineOnUpgrade(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := memoryDetectorStore{}
+	d := NewProlongedOfflineDetector(15*time.Minute, nil, store)
+	d.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// A stored snapshot from before the upgrade, with no detector state yet.
+	stale := now.Add(-90 * 24 * time.Hour).Format(time.RFC3339)
+	before := snapshot.Snapshot{Hash: "before", Peers: []snapshot.Peer{
+		{ID: "dead-1", Meta: map[string]string{"last_seen": stale}},
+		{ID: "dead-2", Meta: map[string]string{"last_seen": stale}},
+		{ID: "web-1", Online: true},
+	}}
+	after := snapshot.Snapshot{Hash: "after", Peers: []snapshot.Peer{
+		{ID: "dead-1", Meta: map[string]string{"last_seen": stale}},
+		{ID: "dead-2", Meta: map[string]string{"last_seen": stale}},
+		{ID: "web-1", Online: false},
+	}}
+	events, err := d.Detect(ctx, before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected long-dead peers to be recorded quietly, got %#v", events)
+	}
+
+	now = now.Add(20 * time.Minute)
+	events, err = d.Tick(ctx, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].SubjectID != "web-1" {
+		t.Fatalf("expected only the peer seen going offline to fire, got %#v", events)
+	}
+
+	// A peer first seen offline on a fresh deploy is quiet as well.
+	fresh := NewProlongedOfflineDetector(15*time.Minute, nil, nil)
+	fresh.now = func() time.Time { return now }
+	if events, _ := fresh.Detect(ctx, snapshot.Snapshot{}, after); len(events) != 0 {
+		t.Fatalf("expected no events on a fresh deploy, got %#v", events)
+	}
+}
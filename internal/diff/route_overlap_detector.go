@@ -0,0 +1,146 @@
+package diff
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+// RouteHAGroup declares peers that intentionally advertise overlapping
+// routes, such as a pair of subnet routers. A peer belongs to the group when
+// it carries one of Tags or its name matches one of the Names globs.
+type RouteHAGroup struct {
+	Tags  []string
+	Names []string
+}
+
+// RouteOverlapDetector compares every peer's routes across the tailnet. It
+// emits tailnet.routes.overlap when two peers start advertising overlapping
+// prefixes and tailnet.routes.overlap.resolved when they stop. Peers in the
+// same RouteHAGroup and default routes from exit nodes are ignored.
+type RouteOverlapDetector struct {
+	haGroups []RouteHAGroup
+	now      func() time.Time
+}
+
+func NewRouteOverlapDetector(haGroups []RouteHAGroup) *RouteOverlapDetector {
+	return &RouteOverlapDetector{haGroups: haGroups, now: time.Now}
+}
+
+func (d *RouteOverlapDetector) Name() string { return "route_overlap" }
+
+// routeOverlap is one pair of overlapping prefixes from two peers. a is the
+// peer with the lower ID.
+type routeOverlap struct {
+	a, b             snapshot.Peer
+	prefixA, prefixB netip.Prefix
+}
+
+func (o routeOverlap) key() string {
+	return o.a.ID + "=" + o.prefixA.String() + "|" + o.b.ID + "=" + o.prefixB.String()
+}
+
+func (d *RouteOverlapDetector) Detect(_ context.Context, before, after snapshot.Snapshot) ([]event.Event, error) {
+	prev := d.overlaps(before)
+	next := d.overlaps(after)
+	result := make([]event.Event, 0)
+	for _, key := range sortedOverlapKeys(next) {
+		if _, ok := prev[key]; !ok {
+			result = append(result, d.event(event.TypeTailnetRoutesOverlap, next[key], before.Hash, after.Hash))
+		}
+	}
+	for _, key := range sortedOverlapKeys(prev) {
+		if _, ok := next[key]; !ok {
+			result = append(result, d.event(event.TypeTailnetRoutesOverlapResolved, prev[key], before.Hash, after.Hash))
+		}
+	}
+	return result, nil
+}
+
+func (d *RouteOverlapDetector) overlaps(s snapshot.Snapshot) map[string]routeOverlap {
+	type route struct {
+		peer   snapshot.Peer
+		prefix netip.Prefix
+	}
+	routes := make([]route, 0)
+	for _, p := range s.Peers {
+		// Only one peer can be primary for a prefix, so an identical prefix
+		// advertised by a second peer shows up in its advertised routes.
+		seen := map[netip.Prefix]bool{}
+		for _, raw := range slices.Concat(p.Routes, p.AdvertisedRoutes) {
+			prefix, err := netip.ParsePrefix(strings.TrimSpace(raw))
+			// Default routes are exit node routes and overlap everything.
+			if err != nil || prefix.Bits() == 0 || seen[prefix.Masked()] {
+				continue
+			}
+			seen[prefix.Masked()] = true
+			routes = append(routes, route{peer: p, prefix: prefix.Masked()})
+		}
+	}
+
+	out := map[string]routeOverlap{}
+	for i, x := range routes {
+		for _, y := range routes[i+1:] {
+			if x.peer.ID == y.peer.ID || !x.prefix.Overlaps(y.prefix) || d.haPair(x.peer, y.peer) {
+				continue
+			}
+			o := routeOverlap{a: x.peer, b: y.peer, prefixA: x.prefix, prefixB: y.prefix}
+			if o.b.ID < o.a.ID {
+				o = routeOverlap{a: y.peer, b: x.peer, prefixA: y.prefix, prefixB: x.prefix}
+			}
+			out[o.key()] = o
+		}
+	}
+	return out
+}
+
+func (d *RouteOverlapDetector) haPair(a, b snapshot.Peer) bool {
+	for _, group := range d.haGroups {
+		if group.matches(a) && group.matches(b) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g RouteHAGroup) matches(p snapshot.Peer) bool {
+	if containsFold(g.Tags, p.Tags) {
+		return true
+	}
+	return len(g.Names) > 0 && matchesNameGlob(g.Names, p.Name)
+}
+
+func (d *RouteOverlapDetector) event(eventType string, o routeOverlap, beforeHash, afterHash string) event.Event {
+	// Each overlap is its own subject so policy debounce and event IDs keep
+	// concurrent overlaps apart.
+	return event.NewTailnetEvent(eventType, o.key(), beforeHash, afterHash, map[string]any{
+		"peers": []map[string]any{
+			overlapPeerPayload(o.a, o.prefixA),
+			overlapPeerPayload(o.b, o.prefixB),
+		},
+	}, d.now())
+}
+
+func overlapPeerPayload(p snapshot.Peer, prefix netip.Prefix) map[string]any {
+	return map[string]any{
+		"id":     p.ID,
+		"name":   p.Name,
+		"tags":   normalizedIdentitySlice(p.Tags),
+		"prefix": prefix.String(),
+	}
+}
+
+func sortedOverlapKeys(m map[string]routeOverlap) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,102 @@
+package diff
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+func TestRouteOverlapDetectorReportsNewAndResolvedOverlaps(t *testing.T) {
+	d := NewRouteOverlapDetector(nil)
+	router := snapshot.Peer{ID: "router", Name: "prod-router", Tags: []string{"tag:router"}, Routes: []string{"10.20.0.0/16"}}
+	laptop := snapshot.Peer{ID: "laptop", Name: "laptop", Routes: []string{"10.0.0.0/8", "0.0.0.0/0", "::/0"}}
+	exit := snapshot.Peer{ID: "exit", Name: "exit", Routes: []string{"0.0.0.0/0", "::/0"}}
+
+	before := snapshot.Snapshot{Hash: "h1", Peers: []snapshot.Peer{router, exit}}
+	after := snapshot.Snapshot{Hash: "h2", Peers: []snapshot.Peer{router, laptop, exit}}
+
+	events, err := d.Detect(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypeTailnetRoutesOverlap {
+		t.Fatalf("expected one overlap event, got %#v", events)
+	}
+	evt := events[0]
+	if evt.SubjectType != event.SubjectTailnet || evt.SubjectID != "laptop=10.0.0.0/8|router=10.20.0.0/16" {
+		t.Fatalf("unexpected subject: %s %s", evt.SubjectType, evt.SubjectID)
+	}
+	peers := evt.Payload["peers"].([]map[string]any)
+	if peers[0]["name"] != "laptop" || peers[0]["prefix"] != "10.0.0.0/8" || peers[1]["prefix"] != "10.20.0.0/16" {
+		t.Fatalf("unexpected payload: %#v", peers)
+	}
+
+	// Unchanged overlaps are not reported again.
+	if events, _ := d.Detect(context.Background(), after, after); len(events) != 0 {
+		t.Fatalf("expected no events for a standing overlap, got %#v", events)
+	}
+
+	events, err = d.Detect(context.Background(), after, before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypeTailnetRoutesOverlapResolved || events[0].SubjectID != evt.SubjectID {
+		t.Fatalf("expected overlap resolved, got %#v", events)
+	}
+}
+
+func TestRouteOverlapDetectorReportsIdenticalAdvertisedPrefix(t *testing.T) {
+	d := NewRouteOverlapDetector(nil)
+	// The router is primary for the prefix, so the laptop advertising the
+	// same prefix only lists it in its advertised routes.
+	router := snapshot.Peer{ID: "router", Name: "prod-router", Routes: []string{"10.20.0.0/16"}, AdvertisedRoutes: []string{"10.20.0.0/16"}}
+	laptop := snapshot.Peer{ID: "laptop", Name: "laptop", AdvertisedRoutes: []string{"10.20.0.0/16"}}
+
+	before := snapshot.Snapshot{Hash: "h1", Peers: []snapshot.Peer{router}}
+	after := snapshot.Snapshot{Hash: "h2", Peers: []snapshot.Peer{router, laptop}}
+
+	events, err := d.Detect(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypeTailnetRoutesOverlap || events[0].SubjectID != "laptop=10.20.0.0/16|router=10.20.0.0/16" {
+		t.Fatalf("expected one overlap for the identical prefix, got %#v", events)
+	}
+}
+
+func TestRouteOverlapDetectorIgnoresHAGroups(t *testing.T) {
+	d := NewRouteOverlapDetector([]RouteHAGroup{
+		{Tags: []string{"tag:router-eu"}},
+		{Names: []string{"us-router-*"}},
+	})
+	after := snapshot.Snapshot{Hash: "h2", Peers: []snapshot.Peer{
+		{ID: "eu-1", Tags: []string{"tag:router-eu"}, Routes: []string{"10.1.0.0/16"}},
+		{ID: "eu-2", Tags: []string{"tag:router-eu"}, Routes: []string{"10.1.0.0/16"}},
+		{ID: "us-1", Name: "us-router-a", Routes: []string{"10.2.0.0/16"}},
+		{ID: "us-2", Name: "us-router-b", Routes: []string{"10.2.0.0/24"}},
+		{ID: "rogue", Name: "rogue", Routes: []string{"10.1.2.0/24"}},
+	}}
+
+	events, err := d.Detect(context.Background(), snapshot.Snapshot{Hash: "h1"}, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, e := range events {
+		got[e.SubjectID] = true
+	}
+	want := map[string]bool{
+		"eu-1=10.1.0.0/16|rogue=10.1.2.0/24": true,
+		"eu-2=10.1.0.0/16|rogue=10.1.2.0/24": true,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected overlaps %v, got %v", want, got)
+	}
+	for k := range want {
+		if !got[k] {
+			t.Fatalf("expected overlaps %v, got %v", want, got)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package diff
+
+import (
+	"context"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+// routeLevel is the redundancy of a critical prefix.
+type routeLevel int
+
+const (
+	routeUnavailable routeLevel = iota
+	routeDegraded
+	routeRedundant
+)
+
+// RouteRedundancyDetector watches configured critical prefixes. It emits
+// tailnet.route.degraded when a prefix is down to one online router,
+// tailnet.route.unavailable when no online router serves it, and
+// tailnet.route.recovered when two or more routers are online again.
+//
+// A router serves a prefix when one of its primary or approved routes
+// contains it, so approved standby routers of an HA pair count. A route the
+// peer only advertises, without approval, does not.
+type RouteRedundancyDetector struct {
+	prefixes []netip.Prefix
+	now      func() time.Time
+}
+
+// NewRouteRedundancyDetector returns a detector for the given critical
+// prefixes. Prefixes that do not parse are skipped; config validation
+// rejects them first.
+func NewRouteRedundancyDetector(criticalPrefixes []string) *RouteRedundancyDetector {
+	prefixes := make([]netip.Prefix, 0, len(criticalPrefixes))
+	for _, raw := range criticalPrefixes {
+		if prefix, err := netip.ParsePrefix(strings.TrimSpace(raw)); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		}
+	}
+	return &RouteRedundancyDetector{prefixes: prefixes, now: time.Now}
+}
+
+func (d *RouteRedundancyDetector) Name() string { return "route_redundancy" }
+
+func (d *RouteRedundancyDetector) Detect(_ context.Context, before, after snapshot.Snapshot) ([]event.Event, error) {
+	result := make([]event.Event, 0)
+	for _, prefix := range d.prefixes {
+		_, prevOnline := routersFor(before, prefix)
+		routers, online := routersFor(after, prefix)
+		prev, next := levelFor(len(prevOnline)), levelFor(len(online))
+		// Without a previous snapshot, assume the prefix was redundant so
+		// startup reports existing problems but no recoveries.
+		if before.Hash == "" {
+			prev = routeRedundant
+		}
+		if prev == next {
+			continue
+		}
+		var eventType string
+		switch next {
+		case routeUnavailable:
+			eventType = event.TypeTailnetRouteUnavailable
+		case routeDegraded:
+			eventType = event.TypeTailnetRouteDegraded
+		default:
+			eventType = event.TypeTailnetRouteRecovered
+		}
+		result = append(result, event.NewTailnetEvent(
+			eventType,
+			prefix.String(),
+			before.Hash,
+			after.Hash,
+			map[string]any{
+				"prefix":         prefix.String(),
+				"routers":        routers,
+				"online_routers": online,
+			},
+			d.now(),
+		))
+	}
+	return result, nil
+}
+
+func levelFor(online int) routeLevel {
+	switch online {
+	case 0:
+		return routeUnavailable
+	case 1:
+		return routeDegraded
+	}
+	return routeRedundant
+}
+
+// routersFor returns the names of all peers serving prefix and of those that
+// are online, both sorted.
+func routersFor(s snapshot.Snapshot, prefix netip.Prefix) (routers, online []string) {
+	routers, online = []string{}, []string{}
+	for _, p := range s.Peers {
+		if !servesPrefix(p, prefix) {
+			continue
+		}
+		name := p.Name
+		if name == "" {
+			name = p.ID
+		}
+		routers = append(routers, name)
+		if p.Online {
+			online = append(online, name)
+		}
+	}
+	sort.Strings(routers)
+	sort.Strings(online)
+	return routers, online
+}
+
+func servesPrefix(p snapshot.Peer, prefix netip.Prefix) bool {
+	for _, routes := range [][]string{p.Routes, p.ApprovedRoutes} {
+		for _, raw := range routes {
+			route, err := netip.ParsePrefix(strings.TrimSpace(raw))
+			// Default routes come from exit nodes, not subnet routers.
+			if err != nil || route.Bits() == 0 {
+				continue
+			}
+			if route.Bits() <= prefix.Bits() && route.Contains(prefix.Addr()) {
+				return true
+			}
+		}
+	}
+	return false
+}
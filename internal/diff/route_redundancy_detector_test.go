@@ -0,0 +1,91 @@
+package diff
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+func routerSnapshot(hash string, aOnline, bOnline bool) snapshot.Snapshot {
+	return snapshot.Snapshot{Hash: hash, Peers: []snapshot.Peer{
+		// router-a is primary; router-b is the approved standby.
+		{ID: "a", Name: "router-a", Online: aOnline, Routes: []string{"10.20.0.0/16"}, AdvertisedRoutes: []string{"10.20.0.0/16"}, ApprovedRoutes: []string{"10.20.0.0/16"}},
+		{ID: "b", Name: "router-b", Online: bOnline, AdvertisedRoutes: []string{"10.0.0.0/8"}, ApprovedRoutes: []string{"10.0.0.0/8"}},
+		{ID: "exit", Name: "exit", Online: true, Routes: []string{"0.0.0.0/0"}},
+	}}
+}
+
+func TestRouteRedundancyDetectorTracksCriticalPrefixes(t *testing.T) {
+	d := NewRouteRedundancyDetector([]string{"10.20.1.0/24"})
+	ctx := context.Background()
+	steps := []struct {
+		before, after snapshot.Snapshot
+		want          string
+		online        []string
+	}{
+		{routerSnapshot("h1", true, true), routerSnapshot("h2", true, false), event.TypeTailnetRouteDegraded, []string{"router-a"}},
+		{routerSnapshot("h2", true, false), routerSnapshot("h3", false, false), event.TypeTailnetRouteUnavailable, []string{}},
+		{routerSnapshot("h3", false, false), routerSnapshot("h4", false, true), event.TypeTailnetRouteDegraded, []string{"router-b"}},
+		{routerSnapshot("h4", false, true), routerSnapshot("h5", true, true), event.TypeTailnetRouteRecovered, []string{"router-a", "router-b"}},
+		{routerSnapshot("h5", true, true), routerSnapshot("h6", true, true), "", nil},
+	}
+	for i, step := range steps {
+		events, err := d.Detect(ctx, step.before, step.after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if step.want == "" {
+			if len(events) != 0 {
+				t.Fatalf("step %d: expected no events, got %#v", i, events)
+			}
+			continue
+		}
+		if len(events) != 1 || events[0].EventType != step.want {
+			t.Fatalf("step %d: expected %s, got %#v", i, step.want, events)
+		}
+		evt := events[0]
+		if evt.SubjectType != event.SubjectTailnet || evt.SubjectID != "10.20.1.0/24" {
+			t.Fatalf("step %d: unexpected subject %s %s", i, evt.SubjectType, evt.SubjectID)
+		}
+		online := evt.Payload["online_routers"].([]string)
+		if len(online) != len(step.online) || (len(online) > 0 && online[0] != step.online[0]) {
+			t.Fatalf("step %d: expected online routers %v, got %v", i, step.online, online)
+		}
+		if routers := evt.Payload["routers"].([]string); len(routers) != 2 {
+			t.Fatalf("step %d: expected both routers listed, got %v", i, routers)
+		}
+	}
+}
+
+func TestRouteRedundancyDetectorReportsDegradedPrefixAtStartup(t *testing.T) {
+	d := NewRouteRedundancyDetector([]string{"10.20.0.0/16", "192.168.0.0/24"})
+	events, err := d.Detect(context.Background(), snapshot.Snapshot{}, routerSnapshot("h1", true, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypeTailnetRouteUnavailable || events[0].SubjectID != "192.168.0.0/24" {
+		t.Fatalf("expected only the unserved prefix to be reported, got %#v", events)
+	}
+}
+
+func TestRouteRedundancyDetectorIgnoresUnapprovedStandby(t *testing.T) {
+	d := NewRouteRedundancyDetector([]string{"10.20.0.0/16"})
+	after := snapshot.Snapshot{Hash: "h1", Peers: []snapshot.Peer{
+		{ID: "a", Name: "router-a", Online: true, Routes: []string{"10.20.0.0/16"}, AdvertisedRoutes: []string{"10.20.0.0/16"}, ApprovedRoutes: []string{"10.20.0.0/16"}},
+		// router-b advertises the prefix but was never approved for it.
+		{ID: "b", Name: "router-b", Online: true, AdvertisedRoutes: []string{"10.20.0.0/16"}},
+	}}
+
+	events, err := d.Detect(context.Background(), snapshot.Snapshot{}, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypeTailnetRouteDegraded {
+		t.Fatalf("expected an unapproved standby not to count, got %#v", events)
+	}
+	if routers := events[0].Payload["routers"].([]string); len(routers) != 1 || routers[0] != "router-a" {
+		t.Fatalf("unexpected routers: %#v", routers)
+	}
+}
@@ -10,12 +10,50 @@ import (
 
 const localSubjectID = "local"
 
+// Settings the runtime detector can watch.
+const (
+	RuntimeWatchDaemonState     = "daemon_state"
+	RuntimeWatchAdvertiseRoutes = "advertise_routes"
+	RuntimeWatchExitNode        = "exit_node"
+	RuntimeWatchRunSSH          = "run_ssh"
+	RuntimeWatchShieldsUp       = "shields_up"
+	RuntimeWatchDomain          = "domain"
+	RuntimeWatchTKAEnabled      = "tka_enabled"
+)
+
+// RuntimeWatches lists every setting runtime can watch.
+var RuntimeWatches = []string{
+	RuntimeWatchDaemonState,
+	RuntimeWatchAdvertiseRoutes,
+	RuntimeWatchExitNode,
+	RuntimeWatchRunSSH,
+	RuntimeWatchShieldsUp,
+	RuntimeWatchDomain,
+	RuntimeWatchTKAEnabled,
+}
+
 type RuntimeDetector struct {
-	now func() time.Time
+	watch map[string]bool
+	now   func() time.Time
 }
 
-func NewRuntimeDetector() *RuntimeDetector {
-	return &RuntimeDetector{now: time.Now}
+// RuntimeOption customizes a RuntimeDetector.
+type RuntimeOption func(*RuntimeDetector)
+
+// WithRuntimeWatch limits runtime to the listed settings. Empty watches all
+// of them.
+func WithRuntimeWatch(watch ...string) RuntimeOption {
+	return func(d *RuntimeDetector) {
+		d.watch = selectedKinds(watch, RuntimeWatches)
+	}
+}
+
+func NewRuntimeDetector(opts ...RuntimeOption) *RuntimeDetector {
+	d := &RuntimeDetector{watch: selectedKinds(nil, RuntimeWatches), now: time.Now}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
 }
 
 func (d *RuntimeDetector) Name() string { return "runtime" }
@@ -27,7 +65,7 @@ func (d *RuntimeDetector) Detect(_ context.Context, before, after snapshot.Snaps
 	}
 
 	out := make([]event.Event, 0)
-	if before.DaemonState != "" && after.DaemonState != "" && before.DaemonState != after.DaemonState {
+	if d.watch[RuntimeWatchDaemonState] && before.DaemonState != "" && after.DaemonState != "" && before.DaemonState != after.DaemonState {
 		out = append(out, event.NewDaemonEvent(
 			event.TypeDaemonStateChanged,
 			localSubjectID,
@@ -40,7 +78,7 @@ func (d *RuntimeDetector) Detect(_ context.Context, before, after snapshot.Snaps
 			d.now(),
 		))
 	}
-	if !stringSliceEqual(before.Prefs.AdvertiseRoutes, after.Prefs.AdvertiseRoutes) {
+	if d.watch[RuntimeWatchAdvertiseRoutes] && !stringSliceEqual(before.Prefs.AdvertiseRoutes, after.Prefs.AdvertiseRoutes) {
 		out = append(out, event.NewPrefsEvent(
 			event.TypePrefsAdvertiseRoutesChanged,
 			localSubjectID,
@@ -53,7 +91,7 @@ func (d *RuntimeDetector) Detect(_ context.Context, before, after snapshot.Snaps
 			d.now(),
 		))
 	}
-	if before.Prefs.ExitNodeID != after.Prefs.ExitNodeID {
+	if d.watch[RuntimeWatchExitNode] && before.Prefs.ExitNodeID != after.Prefs.ExitNodeID {
 		out = append(out, event.NewPrefsEvent(
 			event.TypePrefsExitNodeChanged,
 			localSubjectID,
@@ -66,7 +104,7 @@ func (d *RuntimeDetector) Detect(_ context.Context, before, after snapshot.Snaps
 			d.now(),
 		))
 	}
-	if before.Prefs.RunSSH != after.Prefs.RunSSH {
+	if d.watch[RuntimeWatchRunSSH] && before.Prefs.RunSSH != after.Prefs.RunSSH {
 		out = append(out, event.NewPrefsEvent(
 			event.TypePrefsRunSSHChanged,
 			localSubjectID,
@@ -79,7 +117,7 @@ func (d *RuntimeDetector) Detect(_ context.Context, before, after snapshot.Snaps
 			d.now(),
 		))
 	}
-	if before.Prefs.ShieldsUp != after.Prefs.ShieldsUp {
+	if d.watch[RuntimeWatchShieldsUp] && before.Prefs.ShieldsUp != after.Prefs.ShieldsUp {
 		out = append(out, event.NewPrefsEvent(
 			event.TypePrefsShieldsUpChanged,
 			localSubjectID,
@@ -92,7 +130,7 @@ func (d *RuntimeDetector) Detect(_ context.Context, before, after snapshot.Snaps
 			d.now(),
 		))
 	}
-	if before.Tailnet.Domain != after.Tailnet.Domain {
+	if d.watch[RuntimeWatchDomain] && before.Tailnet.Domain != after.Tailnet.Domain {
 		out = append(out, event.NewTailnetEvent(
 			event.TypeTailnetDomainChanged,
 			tailnetSubject(after.Tailnet.Domain),
@@ -105,7 +143,7 @@ func (d *RuntimeDetector) Detect(_ context.Context, before, after snapshot.Snaps
 			d.now(),
 		))
 	}
-	if before.Tailnet.TKAEnabled != after.Tailnet.TKAEnabled {
+	if d.watch[RuntimeWatchTKAEnabled] && before.Tailnet.TKAEnabled != after.Tailnet.TKAEnabled {
 		out = append(out, event.NewTailnetEvent(
 			event.TypeTailnetTKAEnabledChanged,
 			tailnetSubject(after.Tailnet.Domain),
@@ -82,3 +82,17 @@ func TestRuntimeDetectorSuppressesStartupBaseline(t *testing.T) {
 		t.Fatalf("expected no startup events, got %#v", events)
 	}
 }
+
+func TestRuntimeDetectorWatchesSelectedSettings(t *testing.T) {
+	d := NewRuntimeDetector(WithRuntimeWatch(RuntimeWatchShieldsUp, RuntimeWatchDaemonState))
+	before := snapshot.Snapshot{Hash: "before", DaemonState: "Starting", Prefs: snapshot.Prefs{ExitNodeID: "a"}}
+	after := snapshot.Snapshot{Hash: "after", DaemonState: "Running", Prefs: snapshot.Prefs{ExitNodeID: "b", ShieldsUp: true}, Tailnet: snapshot.Tailnet{Domain: "tail"}}
+
+	events, err := d.Detect(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].EventType != event.TypeDaemonStateChanged || events[1].EventType != event.TypePrefsShieldsUpChanged {
+		t.Fatalf("expected only daemon state and shields up events, got %#v", events)
+	}
+}
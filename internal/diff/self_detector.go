@@ -0,0 +1,151 @@
+package diff
+
+import (
+	"context"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/durfmt"
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+// selfSubjectID is the subject ID of events about Sentinel's own node.
+const selfSubjectID = "self"
+
+// SelfDetector reports changes to Sentinel's own node: its tags, IPs,
+// machine authorization and key expiry. It warns with
+// self.key_expiry.approaching at each threshold before the node key expires,
+// so monitoring does not stop silently when it does.
+type SelfDetector struct {
+	thresholds []time.Duration
+	store      DetectorStateStore
+	fired      keyExpiryRecord
+	now        func() time.Time
+}
+
+// NewSelfDetector returns a detector warning at the given thresholds, or
+// DefaultKeyExpiryThresholds when empty. When store is nil, fired thresholds
+// are only remembered in memory.
+func NewSelfDetector(thresholds []time.Duration, store DetectorStateStore) *SelfDetector {
+	return &SelfDetector{
+		thresholds: keyExpiryThresholds(thresholds),
+		store:      store,
+		now:        time.Now,
+	}
+}
+
+func (d *SelfDetector) Name() string { return "self" }
+
+func (d *SelfDetector) Detect(_ context.Context, before, after snapshot.Snapshot) ([]event.Event, error) {
+	out := make([]event.Event, 0)
+	// Sources that do not report the self node, and startup, have nothing to
+	// compare.
+	if before.Hash != "" && before.Self != nil && after.Self != nil {
+		out = append(out, d.changes(before, after)...)
+	}
+	approaching, err := d.check(before.Hash, after)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, approaching...), nil
+}
+
+func (d *SelfDetector) Tick(_ context.Context, current snapshot.Snapshot) ([]event.Event, error) {
+	return d.check(current.Hash, current)
+}
+
+func (d *SelfDetector) changes(before, after snapshot.Snapshot) []event.Event {
+	b, a := *before.Self, *after.Self
+	identity := deviceIdentityPayload(a.Peer())
+	newEvent := func(eventType string, payload map[string]any) event.Event {
+		return event.NewSentinelEvent(eventType, selfSubjectID, before.Hash, after.Hash, mergePayload(identity, payload), d.now())
+	}
+
+	out := make([]event.Event, 0)
+	if !stringSliceEqual(b.Tags, a.Tags) {
+		out = append(out, newEvent(event.TypeSelfTagsChanged, map[string]any{
+			"before_tags": normalizedIdentitySlice(b.Tags),
+			"after_tags":  normalizedIdentitySlice(a.Tags),
+		}))
+	}
+	if !stringSliceEqual(b.IPs, a.IPs) {
+		out = append(out, newEvent(event.TypeSelfIPsChanged, map[string]any{
+			"before_ips": normalizedIdentitySlice(b.IPs),
+			"after_ips":  normalizedIdentitySlice(a.IPs),
+		}))
+	}
+	if b.MachineAuthorized != a.MachineAuthorized {
+		out = append(out, newEvent(event.TypeSelfMachineAuthorizedChanged, map[string]any{
+			"before_authorized": b.MachineAuthorized,
+			"after_authorized":  a.MachineAuthorized,
+		}))
+	}
+	if b.KeyExpiry != a.KeyExpiry {
+		out = append(out, newEvent(event.TypeSelfKeyExpiryChanged, map[string]any{
+			"before_key_expiry": b.KeyExpiry,
+			"after_key_expiry":  a.KeyExpiry,
+		}))
+	}
+	if !b.Expired && a.Expired {
+		out = append(out, newEvent(event.TypeSelfKeyExpired, map[string]any{
+			"key_expiry": a.KeyExpiry,
+		}))
+	}
+	return out
+}
+
+func (d *SelfDetector) check(beforeHash string, current snapshot.Snapshot) ([]event.Event, error) {
+	if current.Self == nil {
+		return nil, nil
+	}
+	prev, err := d.load()
+	if err != nil {
+		return nil, err
+	}
+	now := d.now()
+	self := *current.Self
+	expiry, ok := self.KeyExpiryTime()
+	remaining := expiry.Sub(now)
+	if !ok || self.Expired || remaining <= 0 {
+		// Keys that do not expire, or already expired; self.key_expired
+		// covers the latter.
+		return nil, d.save(prev, keyExpiryRecord{})
+	}
+	rec, crossed := crossKeyExpiryThresholds(d.thresholds, prev, self.KeyExpiry, remaining)
+	if err := d.save(prev, rec); err != nil {
+		return nil, err
+	}
+	if crossed == 0 {
+		return nil, nil
+	}
+	evt := event.NewSentinelEvent(
+		event.TypeSelfKeyExpiryApproaching,
+		selfSubjectID,
+		beforeHash,
+		current.Hash,
+		mergePayload(deviceIdentityPayload(self.Peer()), map[string]any{
+			"key_expiry": self.KeyExpiry,
+			"threshold":  durfmt.Short(crossed),
+			"remaining":  durfmt.Short(remaining.Round(time.Minute)),
+		}),
+		now,
+	)
+	evt.EventID = event.DeriveScopedEventID(evt, self.KeyExpiry+"|"+durfmt.Short(crossed))
+	return []event.Event{evt}, nil
+}
+
+func (d *SelfDetector) load() (keyExpiryRecord, error) {
+	if d.store == nil {
+		return d.fired, nil
+	}
+	var out keyExpiryRecord
+	if err := loadDetectorState(d.store, d.Name(), &out); err != nil {
+		return keyExpiryRecord{}, err
+	}
+	return out, nil
+}
+
+func (d *SelfDetector) save(prev, next keyExpiryRecord) error {
+	d.fired = next
+	return saveDetectorState(d.store, d.Name(), prev, next)
+}
@@ -0,0 +1,125 @@
+package diff
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+func selfSnapshot(hash string, self snapshot.Self) snapshot.Snapshot {
+	return snapshot.Snapshot{Hash: hash, Self: &self}
+}
+
+func TestSelfDetectorReportsOwnNodeChanges(t *testing.T) {
+	d := NewSelfDetector(nil, nil)
+	before := selfSnapshot("h1", snapshot.Self{
+		ID:        "self-1",
+		Name:      "sentinel",
+		IPs:       []string{"100.64.0.1"},
+		KeyExpiry: "2026-09-01T00:00:00Z",
+	})
+	after := selfSnapshot("h2", snapshot.Self{
+		ID:                "self-1",
+		Name:              "sentinel",
+		Tags:              []string{"tag:sentinel"},
+		IPs:               []string{"100.64.0.2"},
+		MachineAuthorized: true,
+		Expired:           true,
+		KeyExpiry:         "2026-03-01T00:00:00Z",
+	})
+
+	events, err := d.Detect(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]event.Event{}
+	for _, evt := range events {
+		if evt.SubjectType != event.SubjectSentinel || evt.SubjectID != "self" {
+			t.Fatalf("unexpected subject %s/%s", evt.SubjectType, evt.SubjectID)
+		}
+		got[evt.EventType] = evt
+	}
+	for _, eventType := range []string{
+		event.TypeSelfTagsChanged,
+		event.TypeSelfIPsChanged,
+		event.TypeSelfMachineAuthorizedChanged,
+		event.TypeSelfKeyExpiryChanged,
+		event.TypeSelfKeyExpired,
+	} {
+		if _, ok := got[eventType]; !ok {
+			t.Fatalf("expected %s, got %#v", eventType, events)
+		}
+	}
+	tags := got[event.TypeSelfTagsChanged].Payload
+	if !reflect.DeepEqual(tags["before_tags"], []string{}) || !reflect.DeepEqual(tags["after_tags"], []string{"tag:sentinel"}) {
+		t.Fatalf("unexpected tags payload: %#v", tags)
+	}
+	if tags["name"] != "sentinel" {
+		t.Fatalf("expected self identity in payload, got %#v", tags)
+	}
+}
+
+func TestSelfDetectorQuietWithoutBaselineOrSelf(t *testing.T) {
+	d := NewSelfDetector(nil, nil)
+	self := selfSnapshot("h1", snapshot.Self{ID: "self-1", Tags: []string{"tag:sentinel"}})
+
+	for name, tc := range map[string]struct{ before, after snapshot.Snapshot }{
+		"startup":      {before: snapshot.Snapshot{}, after: self},
+		"not reported": {before: snapshot.Snapshot{Hash: "h0"}, after: self},
+		"none":         {before: snapshot.Snapshot{Hash: "h0"}, after: snapshot.Snapshot{Hash: "h1"}},
+	} {
+		events, err := d.Detect(context.Background(), tc.before, tc.after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 0 {
+			t.Fatalf("%s: expected no events, got %#v", name, events)
+		}
+	}
+}
+
+func TestSelfDetectorWarnsBeforeOwnKeyExpires(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	expiry := start.Add(3 * 24 * time.Hour)
+	store := memoryDetectorStore{}
+	d := NewSelfDetector([]time.Duration{7 * 24 * time.Hour, 24 * time.Hour}, store)
+	now := start
+	d.now = func() time.Time { return now }
+	current := selfSnapshot("h1", snapshot.Self{ID: "self-1", Name: "sentinel", KeyExpiry: expiry.Format(time.RFC3339)})
+
+	events, err := d.Detect(context.Background(), snapshot.Snapshot{}, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != event.TypeSelfKeyExpiryApproaching {
+		t.Fatalf("expected one approaching event, got %#v", events)
+	}
+	if events[0].Payload["threshold"] != "168h" || events[0].Payload["remaining"] != "72h" {
+		t.Fatalf("unexpected payload: %#v", events[0].Payload)
+	}
+
+	// A restarted detector reads the fired thresholds back from the store.
+	d = NewSelfDetector([]time.Duration{7 * 24 * time.Hour, 24 * time.Hour}, store)
+	d.now = func() time.Time { return now }
+	if events, err = d.Tick(context.Background(), current); err != nil || len(events) != 0 {
+		t.Fatalf("expected 168h to fire once, got %#v, %v", events, err)
+	}
+
+	now = expiry.Add(-time.Hour)
+	events, err = d.Tick(context.Background(), current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Payload["threshold"] != "24h" {
+		t.Fatalf("expected 24h threshold event, got %#v", events)
+	}
+
+	now = expiry.Add(time.Hour)
+	if events, err = d.Tick(context.Background(), current); err != nil || len(events) != 0 {
+		t.Fatalf("expected no approaching event after expiry, got %#v, %v", events, err)
+	}
+}
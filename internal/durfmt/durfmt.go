@@ -0,0 +1,19 @@
+// Package durfmt formats durations for metric labels and event payloads.
+package durfmt
+
+import (
+	"strings"
+	"time"
+)
+
+// Short renders d without zero-valued trailing units, so 168h0m0s becomes 168h.
+func Short(d time.Duration) string {
+	out := d.String()
+	if strings.HasSuffix(out, "m0s") {
+		out = strings.TrimSuffix(out, "0s")
+	}
+	if strings.HasSuffix(out, "h0m") {
+		out = strings.TrimSuffix(out, "0m")
+	}
+	return out
+}
@@ -0,0 +1,21 @@
+package durfmt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShort(t *testing.T) {
+	cases := map[time.Duration]string{
+		24 * time.Hour:             "24h",
+		90 * time.Minute:           "1h30m",
+		30 * time.Minute:           "30m",
+		45 * time.Second:           "45s",
+		time.Hour + 30*time.Second: "1h0m30s",
+	}
+	for in, want := range cases {
+		if got := Short(in); got != want {
+			t.Fatalf("Short(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -15,18 +15,36 @@ const (
 	SubjectDaemon  = "daemon"
 	SubjectPrefs   = "prefs"
 	SubjectTailnet = "tailnet"
+	// SubjectCompliance events are about one compliance rule on one peer.
+	// Their subject ID is "<rule>/<peer-id>".
+	SubjectCompliance = "compliance"
+	// SubjectDrift events compare a peer with the declared inventory. Their
+	// subject ID is the inventory device name, or the peer ID for
+	// drift.unexpected_device.
+	SubjectDrift = "drift"
+	// SubjectSentinel events are about Sentinel's own node. Their subject
+	// ID is "self".
+	SubjectSentinel = "sentinel"
 
 	TypePeerOnline  = "peer.online"
 	TypePeerOffline = "peer.offline"
-	TypePeerAdded   = "peer.added"
-	TypePeerRemoved = "peer.removed"
+
+	TypePeerOfflineProlonged = "peer.offline.prolonged"
+	TypePeerAdded            = "peer.added"
+	TypePeerRemoved          = "peer.removed"
+
+	TypePeerFlappingStarted = "peer.flapping.started"
+	TypePeerFlappingStopped = "peer.flapping.stopped"
 
 	TypePeerRoutesChanged            = "peer.routes.changed"
 	TypePeerTagsChanged              = "peer.tags.changed"
 	TypePeerMachineAuthorizedChanged = "peer.machine_authorized.changed"
 	TypePeerKeyExpiryChanged         = "peer.key_expiry.changed"
 	TypePeerKeyExpired               = "peer.key_expired"
+	TypePeerKeyExpiryApproaching     = "peer.key_expiry.approaching"
 	TypePeerHostinfoChanged          = "peer.hostinfo.changed"
+	TypePeerClientVersionChanged     = "peer.client_version.changed"
+	TypePeerClientOutdated           = "peer.client_outdated"
 
 	TypeDaemonStateChanged = "daemon.state.changed"
 
@@ -38,21 +56,59 @@ const (
 	TypeTailnetDomainChanged     = "tailnet.domain.changed"
 	TypeTailnetTKAEnabledChanged = "tailnet.tka_enabled.changed"
 
-	SeverityInfo = "info"
+	TypeTailnetRoutesOverlap         = "tailnet.routes.overlap"
+	TypeTailnetRoutesOverlapResolved = "tailnet.routes.overlap.resolved"
+	TypeTailnetRouteDegraded         = "tailnet.route.degraded"
+	TypeTailnetRouteUnavailable      = "tailnet.route.unavailable"
+	TypeTailnetRouteRecovered        = "tailnet.route.recovered"
+	TypeTailnetMassOffline           = "tailnet.mass_offline"
+	TypeTailnetMassOfflineRecovered  = "tailnet.mass_offline.recovered"
+
+	TypeComplianceViolation = "compliance.violation"
+	TypeComplianceResolved  = "compliance.resolved"
+
+	TypeDriftMissingDevice    = "drift.missing_device"
+	TypeDriftUnexpectedDevice = "drift.unexpected_device"
+	TypeDriftTagsMismatch     = "drift.tags_mismatch"
+	TypeDriftRoutesMismatch   = "drift.routes_mismatch"
+
+	TypeSelfTagsChanged              = "self.tags.changed"
+	TypeSelfIPsChanged               = "self.ips.changed"
+	TypeSelfMachineAuthorizedChanged = "self.machine_authorized.changed"
+	TypeSelfKeyExpiryChanged         = "self.key_expiry.changed"
+	TypeSelfKeyExpiryApproaching     = "self.key_expiry.approaching"
+	TypeSelfKeyExpired               = "self.key_expired"
+
+	TypeSentinelConnectivityLost     = "sentinel.connectivity.lost"
+	TypeSentinelConnectivityRestored = "sentinel.connectivity.restored"
+	TypeSentinelBaselineCaptured     = "sentinel.baseline.captured"
+	TypeSentinelCatchup              = "sentinel.catchup"
+
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
 )
 
 var knownEventTypes = map[string]struct{}{
 	TypePeerOnline:  {},
 	TypePeerOffline: {},
-	TypePeerAdded:   {},
-	TypePeerRemoved: {},
+
+	TypePeerOfflineProlonged: {},
+	TypePeerAdded:            {},
+	TypePeerRemoved:          {},
+
+	TypePeerFlappingStarted: {},
+	TypePeerFlappingStopped: {},
 
 	TypePeerRoutesChanged:            {},
 	TypePeerTagsChanged:              {},
 	TypePeerMachineAuthorizedChanged: {},
 	TypePeerKeyExpiryChanged:         {},
 	TypePeerKeyExpired:               {},
+	TypePeerKeyExpiryApproaching:     {},
 	TypePeerHostinfoChanged:          {},
+	TypePeerClientVersionChanged:     {},
+	TypePeerClientOutdated:           {},
 
 	TypeDaemonStateChanged: {},
 
@@ -63,6 +119,34 @@ var knownEventTypes = map[string]struct{}{
 
 	TypeTailnetDomainChanged:     {},
 	TypeTailnetTKAEnabledChanged: {},
+
+	TypeTailnetRoutesOverlap:         {},
+	TypeTailnetRoutesOverlapResolved: {},
+	TypeTailnetRouteDegraded:         {},
+	TypeTailnetRouteUnavailable:      {},
+	TypeTailnetRouteRecovered:        {},
+	TypeTailnetMassOffline:           {},
+	TypeTailnetMassOfflineRecovered:  {},
+
+	TypeComplianceViolation: {},
+	TypeComplianceResolved:  {},
+
+	TypeDriftMissingDevice:    {},
+	TypeDriftUnexpectedDevice: {},
+	TypeDriftTagsMismatch:     {},
+	TypeDriftRoutesMismatch:   {},
+
+	TypeSelfTagsChanged:              {},
+	TypeSelfIPsChanged:               {},
+	TypeSelfMachineAuthorizedChanged: {},
+	TypeSelfKeyExpiryChanged:         {},
+	TypeSelfKeyExpiryApproaching:     {},
+	TypeSelfKeyExpired:               {},
+
+	TypeSentinelConnectivityLost:     {},
+	TypeSentinelConnectivityRestored: {},
+	TypeSentinelBaselineCaptured:     {},
+	TypeSentinelCatchup:              {},
 }
 
 type Event struct {
@@ -78,6 +162,14 @@ type Event struct {
 	Payload       map[string]any `json:"payload,omitempty"`
 }
 
+func IsKnownSeverity(severity string) bool {
+	switch severity {
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+		return true
+	}
+	return false
+}
+
 func IsKnownType(eventType string) bool {
 	_, ok := knownEventTypes[eventType]
 	return ok
@@ -115,6 +207,18 @@ func NewTailnetEvent(eventType, subjectID, beforeHash, afterHash string, payload
 	return NewEvent(eventType, SubjectTailnet, subjectID, beforeHash, afterHash, payload, now)
 }
 
+func NewComplianceEvent(eventType, subjectID, beforeHash, afterHash string, payload map[string]any, now time.Time) Event {
+	return NewEvent(eventType, SubjectCompliance, subjectID, beforeHash, afterHash, payload, now)
+}
+
+func NewSentinelEvent(eventType, subjectID, beforeHash, afterHash string, payload map[string]any, now time.Time) Event {
+	return NewEvent(eventType, SubjectSentinel, subjectID, beforeHash, afterHash, payload, now)
+}
+
+func NewDriftEvent(eventType, subjectID, beforeHash, afterHash string, payload map[string]any, now time.Time) Event {
+	return NewEvent(eventType, SubjectDrift, subjectID, beforeHash, afterHash, payload, now)
+}
+
 func NewPresenceEvent(eventType, subjectID, beforeHash, afterHash string, payload map[string]any, now time.Time) Event {
 	return NewPeerEvent(eventType, subjectID, beforeHash, afterHash, payload, now)
 }
@@ -125,6 +229,15 @@ func DeriveEventID(e Event) string {
 	return hex.EncodeToString(sum[:16])
 }
 
+// DeriveScopedEventID derives an event ID for detections that can repeat
+// against the same snapshot hashes, such as clock-driven thresholds. scope
+// distinguishes the occurrences.
+func DeriveScopedEventID(e Event, scope string) string {
+	msg := fmt.Sprintf("%s|%s|%s|%s|%s|%s", e.SchemaVersion, e.EventType, e.SubjectID, e.BeforeHash, e.AfterHash, scope)
+	sum := sha256.Sum256([]byte(msg))
+	return hex.EncodeToString(sum[:16])
+}
+
 func DeriveIdempotencyKey(e Event) string {
 	payload, _ := json.Marshal(e.Payload)
 	msg := fmt.Sprintf("%s|%s|%s|%s|%s|%s",
@@ -0,0 +1,133 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns the JSON Schema document for the given event schema
+// version. It is generated from the Go types by reflection, so the published
+// schema cannot drift from what the sinks encode.
+func JSONSchema(version string) ([]byte, error) {
+	g := &schemaGenerator{defs: map[string]any{}}
+	var doc map[string]any
+	switch version {
+	case SchemaVersion:
+		doc = g.inline(reflect.TypeFor[Event]())
+		doc["title"] = "Sentinel event (v1)"
+	case SchemaVersionV2:
+		doc = g.inline(reflect.TypeFor[EventV2]())
+		doc["title"] = "Sentinel event (v2)"
+		doc["allOf"] = g.v2PayloadConditions()
+	default:
+		return nil, fmt.Errorf("unknown schema version %q (supported: %s, %s)", version, SchemaVersion, SchemaVersionV2)
+	}
+	props := doc["properties"].(map[string]any)
+	props["schema_version"] = map[string]any{"const": version}
+	doc["$schema"] = jsonSchemaDialect
+	doc["$id"] = SchemaURL(version)
+	if len(g.defs) > 0 {
+		doc["$defs"] = g.defs
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// SchemaURL is the $id of the published JSON Schema for version.
+func SchemaURL(version string) string {
+	return fmt.Sprintf("https://github.com/jaxxstorm/sentinel/schema/event.%s.json", version)
+}
+
+type schemaGenerator struct {
+	defs map[string]any
+}
+
+func (g *schemaGenerator) v2PayloadConditions() []any {
+	eventTypes := make([]string, 0, len(v2PayloadSpecs))
+	for eventType := range v2PayloadSpecs {
+		eventTypes = append(eventTypes, eventType)
+	}
+	sort.Strings(eventTypes)
+	out := make([]any, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		out = append(out, map[string]any{
+			"if": map[string]any{
+				"properties": map[string]any{"event_type": map[string]any{"const": eventType}},
+				"required":   []string{"event_type"},
+			},
+			"then": map[string]any{
+				"properties": map[string]any{"payload": g.schemaFor(v2PayloadSpecs[eventType].payload)},
+			},
+		})
+	}
+	return out
+}
+
+func (g *schemaGenerator) schemaFor(t reflect.Type) map[string]any {
+	if t == reflect.TypeFor[time.Time]() {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schemaFor(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil
+			g.defs[t.Name()] = g.inline(t)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	default:
+		return map[string]any{}
+	}
+}
+
+// inline renders a struct's properties directly rather than as a $ref.
+func (g *schemaGenerator) inline(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		props[name] = g.schemaFor(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
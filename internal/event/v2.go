@@ -0,0 +1,522 @@
+package event
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+const SchemaVersionV2 = "v2"
+
+// EventV2 is the v2 wire format. It matches Event except that Payload holds
+// a typed struct per event type, documented by JSONSchema(SchemaVersionV2).
+type EventV2 struct {
+	SchemaVersion string    `json:"schema_version"`
+	EventID       string    `json:"event_id"`
+	EventType     string    `json:"event_type"`
+	Severity      string    `json:"severity"`
+	Timestamp     time.Time `json:"timestamp"`
+	SubjectID     string    `json:"subject_id"`
+	SubjectType   string    `json:"subject_type"`
+	BeforeHash    string    `json:"before_hash"`
+	AfterHash     string    `json:"after_hash"`
+	Payload       any       `json:"payload"`
+}
+
+// Device identifies the peer a peer-scoped event is about.
+type Device struct {
+	Name   string   `json:"name"`
+	Tags   []string `json:"tags"`
+	Owners []string `json:"owners"`
+	IPs    []string `json:"ips"`
+}
+
+// PeerPresencePayload is carried by peer.online and peer.offline.
+type PeerPresencePayload struct {
+	Device Device `json:"device"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// PeerOfflineProlongedPayload reports when the peer went offline (RFC 3339)
+// and, as Go duration strings, how long it has been down and the threshold
+// that fired.
+type PeerOfflineProlongedPayload struct {
+	Device       Device `json:"device"`
+	OfflineSince string `json:"offline_since"`
+	OfflineFor   string `json:"offline_for"`
+	Threshold    string `json:"threshold"`
+}
+
+type PeerAddedPayload struct {
+	Device Device   `json:"device"`
+	Online bool     `json:"online"`
+	Routes []string `json:"routes"`
+}
+
+type PeerRemovedPayload struct {
+	Device Device `json:"device"`
+	Reason string `json:"reason"`
+}
+
+// PeerFlappingStartedPayload reports the transitions counted within window
+// and the peer's latest presence.
+type PeerFlappingStartedPayload struct {
+	Device      Device `json:"device"`
+	Transitions int    `json:"transitions"`
+	Window      string `json:"window"`
+	Online      bool   `json:"online"`
+}
+
+// PeerFlappingStoppedPayload reports the settled presence and how long the
+// peer flapped.
+type PeerFlappingStoppedPayload struct {
+	Device   Device `json:"device"`
+	Online   bool   `json:"online"`
+	Duration string `json:"duration"`
+}
+
+// PeerListChangedPayload is carried by peer.routes.changed, peer.tags.changed
+// and their self.* counterparts, where Device is Sentinel's own node.
+type PeerListChangedPayload struct {
+	Device Device   `json:"device"`
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+}
+
+type PeerBoolChangedPayload struct {
+	Device Device `json:"device"`
+	Before bool   `json:"before"`
+	After  bool   `json:"after"`
+}
+
+// PeerStringChangedPayload is carried by peer.key_expiry.changed and
+// self.key_expiry.changed, where the values are RFC 3339 times, and by
+// peer.client_version.changed, where they are client versions.
+type PeerStringChangedPayload struct {
+	Device Device `json:"device"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// PeerHostinfoChangedPayload is carried by peer.hostinfo.changed. Before and
+// After are Hostinfo hashes; Changes lists the tracked fields that changed,
+// and is empty for peers only compared by hash.
+type PeerHostinfoChangedPayload struct {
+	Device  Device                `json:"device"`
+	Before  string                `json:"before"`
+	After   string                `json:"after"`
+	Changes []HostinfoFieldChange `json:"changes"`
+}
+
+// HostinfoFieldChange is one changed Hostinfo field. Before and After are
+// strings, bools or string lists depending on the field.
+type HostinfoFieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// PeerClientOutdatedPayload is carried by peer.client_outdated. Reason is
+// below_minimum or behind_newest; Minimum is empty when no minimum is set.
+// A peer on an older major release than Newest carries MajorsBehind
+// instead of MinorsBehind.
+type PeerClientOutdatedPayload struct {
+	Device       Device `json:"device"`
+	Version      string `json:"version"`
+	Reason       string `json:"reason"`
+	Minimum      string `json:"minimum,omitempty"`
+	Newest       string `json:"newest"`
+	MinorsBehind *int   `json:"minors_behind,omitempty"`
+	MajorsBehind int    `json:"majors_behind,omitempty"`
+}
+
+type PeerKeyExpiredPayload struct {
+	Device    Device `json:"device"`
+	KeyExpiry string `json:"key_expiry"`
+}
+
+// PeerKeyExpiryApproachingPayload reports the threshold that was crossed and
+// the time left, both as Go duration strings.
+type PeerKeyExpiryApproachingPayload struct {
+	Device    Device `json:"device"`
+	KeyExpiry string `json:"key_expiry"`
+	Threshold string `json:"threshold"`
+	Remaining string `json:"remaining"`
+}
+
+type StringChangedPayload struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type BoolChangedPayload struct {
+	Before bool `json:"before"`
+	After  bool `json:"after"`
+}
+
+type ListChangedPayload struct {
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+}
+
+// RouteOverlapPayload is carried by tailnet.routes.overlap and
+// tailnet.routes.overlap.resolved. Peers holds the two advertisers, each with
+// the prefix that overlaps the other's.
+type RouteOverlapPayload struct {
+	Peers []RouteOverlapPeer `json:"peers"`
+}
+
+type RouteOverlapPeer struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Tags   []string `json:"tags"`
+	Prefix string   `json:"prefix"`
+}
+
+// RouteRedundancyPayload is carried by tailnet.route.degraded,
+// tailnet.route.unavailable and tailnet.route.recovered. Routers lists every
+// peer serving the prefix and OnlineRouters those currently online.
+type RouteRedundancyPayload struct {
+	Prefix        string   `json:"prefix"`
+	Routers       []string `json:"routers"`
+	OnlineRouters []string `json:"online_routers"`
+}
+
+// MassOfflinePayload is carried by tailnet.mass_offline. Scope is tailnet or
+// the tag group that crossed its threshold; Offline of Total peers in scope
+// went offline within Window.
+type MassOfflinePayload struct {
+	Scope   string            `json:"scope"`
+	Peers   []MassOfflinePeer `json:"peers"`
+	Offline int               `json:"offline"`
+	Total   int               `json:"total"`
+	Window  string            `json:"window"`
+}
+
+// MassOfflineRecoveredPayload is carried by tailnet.mass_offline.recovered.
+// Peers lists every peer the outage covered and Duration how long it lasted.
+type MassOfflineRecoveredPayload struct {
+	Scope    string            `json:"scope"`
+	Peers    []MassOfflinePeer `json:"peers"`
+	Duration string            `json:"duration"`
+}
+
+type MassOfflinePeer struct {
+	ID   string   `json:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// ComplianceViolationPayload is carried by compliance.violation.
+type ComplianceViolationPayload struct {
+	Device      Device `json:"device"`
+	PeerID      string `json:"peer_id"`
+	Rule        string `json:"rule"`
+	Description string `json:"description,omitempty"`
+}
+
+// ComplianceResolvedPayload is carried by compliance.resolved. Reason is
+// passing, peer_removed or out_of_scope; ViolatedSince is RFC 3339.
+type ComplianceResolvedPayload struct {
+	Device        Device `json:"device"`
+	PeerID        string `json:"peer_id"`
+	Rule          string `json:"rule"`
+	Description   string `json:"description,omitempty"`
+	Reason        string `json:"reason"`
+	ViolatedSince string `json:"violated_since"`
+}
+
+// DriftDevicePayload is carried by drift.missing_device and
+// drift.unexpected_device. Reason is absent or offline for a missing device;
+// PeerID is empty when no peer has the device's name.
+type DriftDevicePayload struct {
+	Device Device `json:"device"`
+	PeerID string `json:"peer_id,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// DriftMismatchPayload is carried by drift.tags_mismatch and
+// drift.routes_mismatch. Missing lists expected values the peer lacks and
+// Extra the values it has beyond the inventory.
+type DriftMismatchPayload struct {
+	Device   Device   `json:"device"`
+	PeerID   string   `json:"peer_id"`
+	Expected []string `json:"expected"`
+	Actual   []string `json:"actual"`
+	Missing  []string `json:"missing"`
+	Extra    []string `json:"extra"`
+}
+
+// ConnectivityLostPayload is carried by sentinel.connectivity.lost. Reason is
+// daemon_state, ipn_error or not_joined.
+type ConnectivityLostPayload struct {
+	Reason      string `json:"reason"`
+	DaemonState string `json:"daemon_state,omitempty"`
+	LastError   string `json:"last_error,omitempty"`
+}
+
+// ConnectivityRestoredPayload is carried by sentinel.connectivity.restored.
+// LostSince is RFC 3339 and Duration a Go duration string.
+type ConnectivityRestoredPayload struct {
+	Reason    string `json:"reason"`
+	LostSince string `json:"lost_since"`
+	Duration  string `json:"duration"`
+}
+
+// BaselineCapturedPayload is carried by sentinel.baseline.captured. It
+// summarizes the first snapshot recorded without a stored baseline; HeldBack
+// counts the detector events not sent for it.
+type BaselineCapturedPayload struct {
+	Peers         int            `json:"peers"`
+	Online        int            `json:"online"`
+	Offline       int            `json:"offline"`
+	SubnetRouters int            `json:"subnet_routers"`
+	Tags          map[string]int `json:"tags"`
+	HeldBack      int            `json:"held_back"`
+}
+
+// CatchupPayload is carried by sentinel.catchup. It groups the changes found
+// after Sentinel was away for Downtime, since it was last seen running at
+// Since.
+type CatchupPayload struct {
+	Since    string          `json:"since"`
+	Downtime string          `json:"downtime"`
+	Total    int             `json:"total"`
+	Changes  []CatchupChange `json:"changes"`
+}
+
+// CatchupChange counts the events of one type in a catch-up summary.
+// Subjects are the device names, or subject IDs, they concern.
+type CatchupChange struct {
+	EventType string   `json:"event_type"`
+	Count     int      `json:"count"`
+	Subjects  []string `json:"subjects"`
+}
+
+// v2PayloadSpec maps a v1 payload map onto a v2 payload struct. fields maps
+// v2 JSON keys to the v1 payload keys they are read from.
+type v2PayloadSpec struct {
+	payload reflect.Type
+	device  bool
+	fields  map[string]string
+}
+
+var v2PayloadSpecs = map[string]v2PayloadSpec{
+	TypePeerOnline:  {payload: reflect.TypeFor[PeerPresencePayload](), device: true, fields: map[string]string{"reason": "reason"}},
+	TypePeerOffline: {payload: reflect.TypeFor[PeerPresencePayload](), device: true, fields: map[string]string{"reason": "reason"}},
+	TypePeerAdded:   {payload: reflect.TypeFor[PeerAddedPayload](), device: true, fields: map[string]string{"online": "online", "routes": "routes"}},
+	TypePeerRemoved: {payload: reflect.TypeFor[PeerRemovedPayload](), device: true, fields: map[string]string{"reason": "reason"}},
+
+	TypePeerOfflineProlonged: {payload: reflect.TypeFor[PeerOfflineProlongedPayload](), device: true, fields: map[string]string{
+		"offline_since": "offline_since",
+		"offline_for":   "offline_for",
+		"threshold":     "threshold",
+	}},
+
+	TypePeerFlappingStarted: {payload: reflect.TypeFor[PeerFlappingStartedPayload](), device: true, fields: map[string]string{
+		"transitions": "transitions",
+		"window":      "window",
+		"online":      "online",
+	}},
+	TypePeerFlappingStopped: {payload: reflect.TypeFor[PeerFlappingStoppedPayload](), device: true, fields: map[string]string{
+		"online":   "online",
+		"duration": "duration",
+	}},
+
+	TypePeerRoutesChanged:            {payload: reflect.TypeFor[PeerListChangedPayload](), device: true, fields: beforeAfter("routes")},
+	TypePeerTagsChanged:              {payload: reflect.TypeFor[PeerListChangedPayload](), device: true, fields: beforeAfter("tags")},
+	TypePeerMachineAuthorizedChanged: {payload: reflect.TypeFor[PeerBoolChangedPayload](), device: true, fields: beforeAfter("authorized")},
+	TypePeerKeyExpiryChanged:         {payload: reflect.TypeFor[PeerStringChangedPayload](), device: true, fields: beforeAfter("key_expiry")},
+	TypePeerKeyExpired:               {payload: reflect.TypeFor[PeerKeyExpiredPayload](), device: true, fields: map[string]string{"key_expiry": "key_expiry"}},
+	TypePeerKeyExpiryApproaching: {payload: reflect.TypeFor[PeerKeyExpiryApproachingPayload](), device: true, fields: map[string]string{
+		"key_expiry": "key_expiry",
+		"threshold":  "threshold",
+		"remaining":  "remaining",
+	}},
+	TypePeerClientVersionChanged: {payload: reflect.TypeFor[PeerStringChangedPayload](), device: true, fields: beforeAfter("version")},
+	TypePeerClientOutdated: {payload: reflect.TypeFor[PeerClientOutdatedPayload](), device: true, fields: map[string]string{
+		"version":       "version",
+		"reason":        "reason",
+		"minimum":       "minimum",
+		"newest":        "newest",
+		"minors_behind": "minors_behind",
+		"majors_behind": "majors_behind",
+	}},
+	TypePeerHostinfoChanged: {payload: reflect.TypeFor[PeerHostinfoChangedPayload](), device: true, fields: map[string]string{
+		"before":  "before_hostinfo_hash",
+		"after":   "after_hostinfo_hash",
+		"changes": "changes",
+	}},
+
+	TypeDaemonStateChanged: {payload: reflect.TypeFor[StringChangedPayload](), fields: beforeAfter("state")},
+
+	TypePrefsAdvertiseRoutesChanged: {payload: reflect.TypeFor[ListChangedPayload](), fields: beforeAfter("routes")},
+	TypePrefsExitNodeChanged:        {payload: reflect.TypeFor[StringChangedPayload](), fields: beforeAfter("exit_node_id")},
+	TypePrefsRunSSHChanged:          {payload: reflect.TypeFor[BoolChangedPayload](), fields: beforeAfter("run_ssh")},
+	TypePrefsShieldsUpChanged:       {payload: reflect.TypeFor[BoolChangedPayload](), fields: beforeAfter("shields_up")},
+
+	TypeTailnetDomainChanged:     {payload: reflect.TypeFor[StringChangedPayload](), fields: beforeAfter("domain")},
+	TypeTailnetTKAEnabledChanged: {payload: reflect.TypeFor[BoolChangedPayload](), fields: beforeAfter("tka_enabled")},
+
+	TypeTailnetRoutesOverlap:         {payload: reflect.TypeFor[RouteOverlapPayload](), fields: map[string]string{"peers": "peers"}},
+	TypeTailnetRoutesOverlapResolved: {payload: reflect.TypeFor[RouteOverlapPayload](), fields: map[string]string{"peers": "peers"}},
+	TypeTailnetRouteDegraded:         {payload: reflect.TypeFor[RouteRedundancyPayload](), fields: routeRedundancyFields},
+	TypeTailnetRouteUnavailable:      {payload: reflect.TypeFor[RouteRedundancyPayload](), fields: routeRedundancyFields},
+	TypeTailnetRouteRecovered:        {payload: reflect.TypeFor[RouteRedundancyPayload](), fields: routeRedundancyFields},
+	TypeTailnetMassOffline: {payload: reflect.TypeFor[MassOfflinePayload](), fields: map[string]string{
+		"scope":   "scope",
+		"peers":   "peers",
+		"offline": "offline",
+		"total":   "total",
+		"window":  "window",
+	}},
+	TypeTailnetMassOfflineRecovered: {payload: reflect.TypeFor[MassOfflineRecoveredPayload](), fields: map[string]string{
+		"scope":    "scope",
+		"peers":    "peers",
+		"duration": "duration",
+	}},
+
+	TypeComplianceViolation: {payload: reflect.TypeFor[ComplianceViolationPayload](), device: true, fields: map[string]string{
+		"peer_id":     "peer_id",
+		"rule":        "rule",
+		"description": "description",
+	}},
+	TypeComplianceResolved: {payload: reflect.TypeFor[ComplianceResolvedPayload](), device: true, fields: map[string]string{
+		"peer_id":        "peer_id",
+		"rule":           "rule",
+		"description":    "description",
+		"reason":         "reason",
+		"violated_since": "violated_since",
+	}},
+
+	TypeDriftMissingDevice:    {payload: reflect.TypeFor[DriftDevicePayload](), device: true, fields: driftDeviceFields},
+	TypeDriftUnexpectedDevice: {payload: reflect.TypeFor[DriftDevicePayload](), device: true, fields: driftDeviceFields},
+	TypeDriftTagsMismatch:     {payload: reflect.TypeFor[DriftMismatchPayload](), device: true, fields: driftMismatchFields},
+	TypeDriftRoutesMismatch:   {payload: reflect.TypeFor[DriftMismatchPayload](), device: true, fields: driftMismatchFields},
+
+	TypeSelfTagsChanged:              {payload: reflect.TypeFor[PeerListChangedPayload](), device: true, fields: beforeAfter("tags")},
+	TypeSelfIPsChanged:               {payload: reflect.TypeFor[PeerListChangedPayload](), device: true, fields: beforeAfter("ips")},
+	TypeSelfMachineAuthorizedChanged: {payload: reflect.TypeFor[PeerBoolChangedPayload](), device: true, fields: beforeAfter("authorized")},
+	TypeSelfKeyExpiryChanged:         {payload: reflect.TypeFor[PeerStringChangedPayload](), device: true, fields: beforeAfter("key_expiry")},
+	TypeSelfKeyExpired:               {payload: reflect.TypeFor[PeerKeyExpiredPayload](), device: true, fields: map[string]string{"key_expiry": "key_expiry"}},
+	TypeSelfKeyExpiryApproaching: {payload: reflect.TypeFor[PeerKeyExpiryApproachingPayload](), device: true, fields: map[string]string{
+		"key_expiry": "key_expiry",
+		"threshold":  "threshold",
+		"remaining":  "remaining",
+	}},
+
+	TypeSentinelConnectivityLost: {payload: reflect.TypeFor[ConnectivityLostPayload](), fields: map[string]string{
+		"reason":       "reason",
+		"daemon_state": "daemon_state",
+		"last_error":   "last_error",
+	}},
+	TypeSentinelConnectivityRestored: {payload: reflect.TypeFor[ConnectivityRestoredPayload](), fields: map[string]string{
+		"reason":     "reason",
+		"lost_since": "lost_since",
+		"duration":   "duration",
+	}},
+	TypeSentinelBaselineCaptured: {payload: reflect.TypeFor[BaselineCapturedPayload](), fields: map[string]string{
+		"peers":          "peers",
+		"online":         "online",
+		"offline":        "offline",
+		"subnet_routers": "subnet_routers",
+		"tags":           "tags",
+		"held_back":      "held_back",
+	}},
+	TypeSentinelCatchup: {payload: reflect.TypeFor[CatchupPayload](), fields: map[string]string{
+		"since":    "since",
+		"downtime": "downtime",
+		"total":    "total",
+		"changes":  "changes",
+	}},
+}
+
+var driftDeviceFields = map[string]string{
+	"peer_id": "peer_id",
+	"reason":  "reason",
+}
+
+var driftMismatchFields = map[string]string{
+	"peer_id":  "peer_id",
+	"expected": "expected",
+	"actual":   "actual",
+	"missing":  "missing",
+	"extra":    "extra",
+}
+
+var routeRedundancyFields = map[string]string{
+	"prefix":         "prefix",
+	"routers":        "routers",
+	"online_routers": "online_routers",
+}
+
+func beforeAfter(suffix string) map[string]string {
+	return map[string]string{"before": "before_" + suffix, "after": "after_" + suffix}
+}
+
+// ToV2 converts e to the v2 wire format. Event types without a typed
+// payload keep their v1 payload map.
+func ToV2(e Event) EventV2 {
+	out := EventV2{
+		SchemaVersion: SchemaVersionV2,
+		EventID:       e.EventID,
+		EventType:     e.EventType,
+		Severity:      e.Severity,
+		Timestamp:     e.Timestamp,
+		SubjectID:     e.SubjectID,
+		SubjectType:   e.SubjectType,
+		BeforeHash:    e.BeforeHash,
+		AfterHash:     e.AfterHash,
+		Payload:       e.Payload,
+	}
+	spec, ok := v2PayloadSpecs[e.EventType]
+	if !ok {
+		return out
+	}
+	if payload, err := spec.convert(e.Payload); err == nil {
+		out.Payload = payload
+	}
+	return out
+}
+
+func (s v2PayloadSpec) convert(v1 map[string]any) (any, error) {
+	fields := make(map[string]any, len(s.fields)+1)
+	if s.device {
+		fields["device"] = map[string]any{
+			"name":   v1["name"],
+			"tags":   v1["tags"],
+			"owners": v1["owners"],
+			"ips":    v1["ips"],
+		}
+	}
+	for v2Key, v1Key := range s.fields {
+		if v, ok := v1[v1Key]; ok && v != nil {
+			fields[v2Key] = v
+		}
+	}
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	payload := reflect.New(s.payload)
+	if err := json.Unmarshal(raw, payload.Interface()); err != nil {
+		return nil, err
+	}
+	fillNilSlices(payload.Elem())
+	return payload.Elem().Interface(), nil
+}
+
+// fillNilSlices replaces nil slices with empty ones so list fields always
+// encode as JSON arrays, as the schema requires.
+func fillNilSlices(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			fillNilSlices(v.Field(i))
+		}
+	case reflect.Slice:
+		if v.IsNil() && v.CanSet() {
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestToV2TypesPeerListPayload(t *testing.T) {
+	e := NewPeerEvent(TypePeerRoutesChanged, "peer1", "before", "after", map[string]any{
+		"name":          "router-01",
+		"tags":          []string{"tag:router"},
+		"ips":           []string{"100.64.0.1"},
+		"before_routes": []string{"10.0.0.0/24"},
+		"after_routes":  []string{"10.0.0.0/24", "10.0.1.0/24"},
+	}, time.Unix(1700000000, 0))
+
+	v2 := ToV2(e)
+	if v2.SchemaVersion != SchemaVersionV2 || v2.EventID != e.EventID {
+		t.Fatalf("expected v2 envelope preserving event id, got %+v", v2)
+	}
+	payload, ok := v2.Payload.(PeerListChangedPayload)
+	if !ok {
+		t.Fatalf("expected PeerListChangedPayload, got %T", v2.Payload)
+	}
+	want := PeerListChangedPayload{
+		Device: Device{Name: "router-01", Tags: []string{"tag:router"}, Owners: []string{}, IPs: []string{"100.64.0.1"}},
+		Before: []string{"10.0.0.0/24"},
+		After:  []string{"10.0.0.0/24", "10.0.1.0/24"},
+	}
+	if !reflect.DeepEqual(payload, want) {
+		t.Fatalf("unexpected payload:\n got %#v\nwant %#v", payload, want)
+	}
+}
+
+func TestToV2TypesScalarPayload(t *testing.T) {
+	e := NewPrefsEvent(TypePrefsShieldsUpChanged, "prefs", "before", "after", map[string]any{
+		"before_shields_up": false,
+		"after_shields_up":  true,
+	}, time.Now())
+
+	payload, ok := ToV2(e).Payload.(BoolChangedPayload)
+	if !ok || payload.Before || !payload.After {
+		t.Fatalf("expected typed shields_up payload, got %#v", ToV2(e).Payload)
+	}
+}
+
+func TestToV2TypesBaselineCapturedPayload(t *testing.T) {
+	e := NewSentinelEvent(TypeSentinelBaselineCaptured, "self", "", "after", map[string]any{
+		"peers":          3,
+		"online":         2,
+		"offline":        1,
+		"subnet_routers": 1,
+		"tags":           map[string]int{"tag:server": 2},
+		"held_back":      6,
+	}, time.Now())
+
+	payload, ok := ToV2(e).Payload.(BaselineCapturedPayload)
+	want := BaselineCapturedPayload{Peers: 3, Online: 2, Offline: 1, SubnetRouters: 1, Tags: map[string]int{"tag:server": 2}, HeldBack: 6}
+	if !ok || !reflect.DeepEqual(payload, want) {
+		t.Fatalf("expected typed baseline payload, got %#v", ToV2(e).Payload)
+	}
+}
+
+func TestToV2KeepsMapPayloadForUnknownTypes(t *testing.T) {
+	e := NewEvent("custom.thing", SubjectPeer, "peer1", "", "", map[string]any{"k": "v"}, time.Now())
+	if _, ok := ToV2(e).Payload.(map[string]any); !ok {
+		t.Fatalf("expected map payload for unknown event type, got %T", ToV2(e).Payload)
+	}
+}
+
+func TestV2PayloadSpecsCoverKnownEventTypes(t *testing.T) {
+	for eventType := range knownEventTypes {
+		if _, ok := v2PayloadSpecs[eventType]; !ok {
+			t.Errorf("event type %q has no v2 payload spec", eventType)
+		}
+	}
+}
+
+func TestJSONSchemaMatchesPublishedDocs(t *testing.T) {
+	for _, version := range []string{SchemaVersion, SchemaVersionV2} {
+		got, err := JSONSchema(version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := os.ReadFile("../../docs/schema/event." + version + ".schema.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("docs/schema/event.%s.schema.json is stale; regenerate with `sentinel schema --schema-version %s`", version, version)
+		}
+	}
+}
+
+func TestJSONSchemaV2IsValidJSON(t *testing.T) {
+	out, err := JSONSchema(SchemaVersionV2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Defs  map[string]any `json:"$defs"`
+		AllOf []any          `json:"allOf"`
+	}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.AllOf) != len(v2PayloadSpecs) {
+		t.Fatalf("expected one payload condition per event type, got %d", len(doc.AllOf))
+	}
+	if _, ok := doc.Defs["Device"]; !ok {
+		t.Fatal("expected Device definition in $defs")
+	}
+}
+
+func TestJSONSchemaRejectsUnknownVersion(t *testing.T) {
+	if _, err := JSONSchema("v9"); err == nil {
+		t.Fatal("expected error for unknown schema version")
+	}
+}
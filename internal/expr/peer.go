@@ -0,0 +1,154 @@
+// Package expr compiles user-supplied CEL expressions over snapshot peers.
+package expr
+
+import (
+	"fmt"
+
+	"cel.dev/cel-go/cel"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+// PeerChangeProgram is a compiled expression over a peer's before and after
+// state. It sees the variables before and after (peer maps, see PeerValue)
+// and added and removed (bools).
+type PeerChangeProgram struct {
+	prg cel.Program
+}
+
+// CompilePeerChange compiles src, which must evaluate to a bool.
+func CompilePeerChange(src string) (*PeerChangeProgram, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("before", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("after", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("added", cel.BoolType),
+		cel.Variable("removed", cel.BoolType),
+	)
+	if err != nil {
+		return nil, err
+	}
+	prg, err := compileBool(env, src)
+	if err != nil {
+		return nil, err
+	}
+	return &PeerChangeProgram{prg: prg}, nil
+}
+
+// Match evaluates the program. A peer missing on one side is passed as a
+// zero-valued peer with added or removed set.
+func (p *PeerChangeProgram) Match(before, after *snapshot.Peer) (bool, error) {
+	vars := map[string]any{
+		"before":  PeerValue(derefPeer(before)),
+		"after":   PeerValue(derefPeer(after)),
+		"added":   before == nil,
+		"removed": after == nil,
+	}
+	return evalBool(p.prg, vars)
+}
+
+// PeerProgram is a compiled expression over a single peer, exposed as the
+// variable peer (see PeerValue).
+type PeerProgram struct {
+	prg cel.Program
+}
+
+// CompilePeer compiles src, which must evaluate to a bool.
+func CompilePeer(src string) (*PeerProgram, error) {
+	env, err := cel.NewEnv(cel.Variable("peer", cel.MapType(cel.StringType, cel.DynType)))
+	if err != nil {
+		return nil, err
+	}
+	prg, err := compileBool(env, src)
+	if err != nil {
+		return nil, err
+	}
+	return &PeerProgram{prg: prg}, nil
+}
+
+// Match evaluates the program against p.
+func (p *PeerProgram) Match(peer snapshot.Peer) (bool, error) {
+	return evalBool(p.prg, map[string]any{"peer": PeerValue(peer)})
+}
+
+// PeerValue exposes p to expressions using the snapshot JSON field names.
+func PeerValue(p snapshot.Peer) map[string]any {
+	return map[string]any{
+		"id":                 p.ID,
+		"name":               p.Name,
+		"online":             p.Online,
+		"tags":               nonNil(p.Tags),
+		"owners":             nonNil(p.Owners),
+		"ips":                nonNil(p.IPs),
+		"routes":             nonNil(p.Routes),
+		"advertised_routes":  nonNil(p.AdvertisedRoutes),
+		"approved_routes":    nonNil(p.ApprovedRoutes),
+		"machine_authorized": p.MachineAuthorized,
+		"expired":            p.Expired,
+		"key_expiry":         p.KeyExpiry,
+		"hostinfo_hash":      p.HostinfoHash,
+		"hostinfo":           hostinfoValue(p.Hostinfo),
+		"meta":               nonNilMap(p.Meta),
+	}
+}
+
+// hostinfoValue exposes the tracked Hostinfo fields, zero-valued when the
+// source does not report Hostinfo.
+func hostinfoValue(h *snapshot.Hostinfo) map[string]any {
+	if h == nil {
+		h = &snapshot.Hostinfo{}
+	}
+	return map[string]any{
+		"os":            h.OS,
+		"os_version":    h.OSVersion,
+		"ipn_version":   h.IPNVersion,
+		"distro":        h.Distro,
+		"device_model":  h.DeviceModel,
+		"shields_up":    h.ShieldsUp,
+		"allows_update": h.AllowsUpdate,
+		"services":      nonNil(h.Services),
+	}
+}
+
+func compileBool(env *cel.Env, src string) (cel.Program, error) {
+	ast, iss := env.Compile(src)
+	if iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	out := ast.OutputType()
+	if !out.IsExactType(cel.BoolType) && !out.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("expression must evaluate to bool, got %s", out)
+	}
+	return env.Program(ast)
+}
+
+func evalBool(prg cel.Program, vars map[string]any) (bool, error) {
+	out, _, err := prg.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression returned %T, want bool", out.Value())
+	}
+	return b, nil
+}
+
+func derefPeer(p *snapshot.Peer) snapshot.Peer {
+	if p == nil {
+		return snapshot.Peer{}
+	}
+	return *p
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func nonNilMap(values map[string]string) map[string]string {
+	if values == nil {
+		return map[string]string{}
+	}
+	return values
+}
@@ -0,0 +1,98 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+func TestPeerChangeProgramMatches(t *testing.T) {
+	before := &snapshot.Peer{ID: "db-1", Online: true, Tags: []string{"tag:db"}, Meta: map[string]string{"os": "linux"}}
+	after := &snapshot.Peer{ID: "db-1", Online: false, Tags: []string{"tag:db"}, Meta: map[string]string{"os": "windows"}}
+
+	cases := map[string]bool{
+		`before.online && !after.online && "tag:db" in after.tags`: true,
+		`after.meta.os != before.meta.os`:                          true,
+		`"tag:web" in after.tags`:                                  false,
+		`added || removed`:                                         false,
+	}
+	for src, want := range cases {
+		prg, err := CompilePeerChange(src)
+		if err != nil {
+			t.Fatalf("compile %q: %v", src, err)
+		}
+		got, err := prg.Match(before, after)
+		if err != nil {
+			t.Fatalf("eval %q: %v", src, err)
+		}
+		if got != want {
+			t.Fatalf("%q = %v, want %v", src, got, want)
+		}
+	}
+}
+
+func TestPeerChangeProgramAddedPeer(t *testing.T) {
+	prg, err := CompilePeerChange(`added && after.online && !before.online`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := prg.Match(nil, &snapshot.Peer{ID: "new", Online: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got {
+		t.Fatal("expected added peer to match")
+	}
+}
+
+func TestCompilePeerChangeRejectsInvalidExpressions(t *testing.T) {
+	for _, src := range []string{`after.online &&`, `after.name + "x"`, `unknown.online`} {
+		if _, err := CompilePeerChange(src); err == nil {
+			t.Fatalf("expected compile error for %q", src)
+		}
+	}
+}
+
+func TestPeerChangeProgramMissingMetaKeyErrors(t *testing.T) {
+	prg, err := CompilePeerChange(`after.meta.os == "linux"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := prg.Match(&snapshot.Peer{}, &snapshot.Peer{}); err == nil {
+		t.Fatal("expected missing key error")
+	}
+	guarded, err := CompilePeerChange(`has(after.meta.os) && after.meta.os == "linux"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := guarded.Match(&snapshot.Peer{}, &snapshot.Peer{}); err != nil || got {
+		t.Fatalf("expected guarded expression to be false, got %v %v", got, err)
+	}
+}
+
+func TestPeerProgramMatches(t *testing.T) {
+	prg, err := CompilePeer(`!("0.0.0.0/0" in peer.advertised_routes) || "tag:exit" in peer.tags`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		peer snapshot.Peer
+		want bool
+	}{
+		{snapshot.Peer{AdvertisedRoutes: []string{"10.0.0.0/8"}}, true},
+		{snapshot.Peer{AdvertisedRoutes: []string{"0.0.0.0/0"}}, false},
+		{snapshot.Peer{AdvertisedRoutes: []string{"0.0.0.0/0"}, Tags: []string{"tag:exit"}}, true},
+	}
+	for i, tc := range cases {
+		got, err := prg.Match(tc.peer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Fatalf("case %d: got %v, want %v", i, got, tc.want)
+		}
+	}
+	if _, err := CompilePeer(`before.online`); err == nil {
+		t.Fatal("expected before to be undefined for single-peer programs")
+	}
+}
@@ -0,0 +1,265 @@
+// Package inventory loads a declared device inventory and compares it with
+// snapshots to report drift.
+package inventory
+
+import (
+	"fmt"
+	"net/netip"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+	"github.com/spf13/viper"
+)
+
+// Kinds of drift reported by Compare.
+const (
+	KindMissingDevice    = "missing_device"
+	KindUnexpectedDevice = "unexpected_device"
+	KindTagsMismatch     = "tags_mismatch"
+	KindRoutesMismatch   = "routes_mismatch"
+)
+
+// Reasons a device is reported missing.
+const (
+	ReasonAbsent  = "absent"
+	ReasonOffline = "offline"
+)
+
+// Inventory is the desired state of the managed part of a tailnet.
+type Inventory struct {
+	// Managed selects the peers the inventory is authoritative for. A
+	// selected peer that is not listed in Devices is unexpected. When empty,
+	// no peer is reported as unexpected.
+	Managed Selector `mapstructure:"managed" json:"managed"`
+	Devices []Device `mapstructure:"devices" json:"devices"`
+}
+
+// Selector matches peers carrying one of Tags or whose name matches one of
+// the DeviceNames globs.
+type Selector struct {
+	Tags        []string `mapstructure:"tags" json:"tags,omitempty"`
+	DeviceNames []string `mapstructure:"device_names" json:"device_names,omitempty"`
+}
+
+// Device is one expected node, matched to a peer by name. Tags and Routes
+// are only checked when set.
+type Device struct {
+	Name         string   `mapstructure:"name" json:"name"`
+	Tags         []string `mapstructure:"tags" json:"tags,omitempty"`
+	Routes       []string `mapstructure:"routes" json:"routes,omitempty"`
+	MustBeOnline bool     `mapstructure:"must_be_online" json:"must_be_online,omitempty"`
+}
+
+// Finding is one difference between the inventory and a snapshot. Expected,
+// Actual, Missing and Extra are set for tags and routes mismatches.
+type Finding struct {
+	Kind     string   `json:"kind"`
+	Device   string   `json:"device"`
+	PeerID   string   `json:"peer_id,omitempty"`
+	Reason   string   `json:"reason,omitempty"`
+	Expected []string `json:"expected,omitempty"`
+	Actual   []string `json:"actual,omitempty"`
+	Missing  []string `json:"missing,omitempty"`
+	Extra    []string `json:"extra,omitempty"`
+	// Peer is the matched peer, nil when the device is absent.
+	Peer *snapshot.Peer `json:"-"`
+}
+
+// Load reads and validates an inventory file. YAML and JSON are accepted.
+func Load(file string) (Inventory, error) {
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		return Inventory{}, fmt.Errorf("read inventory: %w", err)
+	}
+	var inv Inventory
+	if err := v.Unmarshal(&inv); err != nil {
+		return Inventory{}, fmt.Errorf("decode inventory: %w", err)
+	}
+	if err := inv.normalize(); err != nil {
+		return Inventory{}, fmt.Errorf("inventory %s: %w", file, err)
+	}
+	return inv, nil
+}
+
+func (inv *Inventory) normalize() error {
+	for i, raw := range inv.Managed.DeviceNames {
+		pattern := strings.TrimSpace(raw)
+		if _, err := path.Match(pattern, ""); pattern == "" || err != nil {
+			return fmt.Errorf("managed.device_names[%d] has invalid glob pattern %q", i, raw)
+		}
+		inv.Managed.DeviceNames[i] = pattern
+	}
+	seen := map[string]struct{}{}
+	for i := range inv.Devices {
+		device := &inv.Devices[i]
+		device.Name = strings.TrimSpace(device.Name)
+		if device.Name == "" {
+			return fmt.Errorf("devices[%d].name is required", i)
+		}
+		key := strings.ToLower(device.Name)
+		if _, ok := seen[key]; ok {
+			return fmt.Errorf("devices[%d].name %q is duplicated", i, device.Name)
+		}
+		seen[key] = struct{}{}
+		for j, raw := range device.Routes {
+			prefix, err := netip.ParsePrefix(strings.TrimSpace(raw))
+			if err != nil {
+				return fmt.Errorf("devices[%d].routes[%d] %q is not a CIDR prefix", i, j, raw)
+			}
+			device.Routes[j] = prefix.Masked().String()
+		}
+		for j := range device.Tags {
+			device.Tags[j] = strings.TrimSpace(device.Tags[j])
+		}
+	}
+	return nil
+}
+
+// Compare reports how s differs from the inventory, sorted by device name
+// and kind.
+func Compare(inv Inventory, s snapshot.Snapshot) []Finding {
+	byName := map[string]snapshot.Peer{}
+	for _, p := range s.Peers {
+		key := strings.ToLower(strings.TrimSpace(p.Name))
+		if key == "" {
+			continue
+		}
+		// Prefer the online peer when a name is reused.
+		if existing, ok := byName[key]; ok && (existing.Online || !p.Online) {
+			continue
+		}
+		byName[key] = p
+	}
+
+	findings := make([]Finding, 0)
+	declared := map[string]struct{}{}
+	for _, device := range inv.Devices {
+		key := strings.ToLower(device.Name)
+		declared[key] = struct{}{}
+		p, ok := byName[key]
+		if !ok {
+			findings = append(findings, Finding{Kind: KindMissingDevice, Device: device.Name, Reason: ReasonAbsent})
+			continue
+		}
+		peer := p
+		if device.MustBeOnline && !p.Online {
+			findings = append(findings, Finding{Kind: KindMissingDevice, Device: device.Name, PeerID: p.ID, Reason: ReasonOffline, Peer: &peer})
+		}
+		if len(device.Tags) > 0 {
+			if f, drifted := mismatch(KindTagsMismatch, device.Tags, p.Tags); drifted {
+				f.Device, f.PeerID, f.Peer = device.Name, p.ID, &peer
+				findings = append(findings, f)
+			}
+		}
+		if len(device.Routes) > 0 {
+			if f, drifted := mismatch(KindRoutesMismatch, device.Routes, peerRoutes(p)); drifted {
+				f.Device, f.PeerID, f.Peer = device.Name, p.ID, &peer
+				findings = append(findings, f)
+			}
+		}
+	}
+	for _, p := range s.Peers {
+		if _, ok := declared[strings.ToLower(strings.TrimSpace(p.Name))]; ok || !inv.Managed.selects(p) {
+			continue
+		}
+		peer := p
+		name := p.Name
+		if name == "" {
+			name = p.ID
+		}
+		findings = append(findings, Finding{Kind: KindUnexpectedDevice, Device: name, PeerID: p.ID, Peer: &peer})
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if !strings.EqualFold(a.Device, b.Device) {
+			return strings.ToLower(a.Device) < strings.ToLower(b.Device)
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.PeerID < b.PeerID
+	})
+	return findings
+}
+
+func (s Selector) selects(p snapshot.Peer) bool {
+	for _, want := range s.Tags {
+		for _, tag := range p.Tags {
+			if strings.EqualFold(strings.TrimSpace(want), tag) {
+				return true
+			}
+		}
+	}
+	name := strings.ToLower(strings.TrimSpace(p.Name))
+	for _, pattern := range s.DeviceNames {
+		if ok, err := path.Match(strings.ToLower(pattern), name); err == nil && ok && name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// peerRoutes returns the union of a peer's primary and advertised routes.
+func peerRoutes(p snapshot.Peer) []string {
+	out := make([]string, 0, len(p.Routes)+len(p.AdvertisedRoutes))
+	for _, routes := range [][]string{p.Routes, p.AdvertisedRoutes} {
+		for _, raw := range routes {
+			prefix, err := netip.ParsePrefix(strings.TrimSpace(raw))
+			if err != nil {
+				continue
+			}
+			out = append(out, prefix.Masked().String())
+		}
+	}
+	return out
+}
+
+func mismatch(kind string, expected, actual []string) (Finding, bool) {
+	want, have := sortedSet(expected), sortedSet(actual)
+	f := Finding{
+		Kind:     kind,
+		Expected: want,
+		Actual:   have,
+		Missing:  difference(want, have),
+		Extra:    difference(have, want),
+	}
+	return f, len(f.Missing) > 0 || len(f.Extra) > 0
+}
+
+func sortedSet(values []string) []string {
+	seen := map[string]struct{}{}
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if _, ok := seen[v]; ok || v == "" {
+			continue
+		}
+		seen[v] = struct{}{}
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// difference returns the values of a missing from b.
+func difference(a, b []string) []string {
+	out := make([]string, 0)
+	for _, v := range a {
+		if !slicesContainsFold(b, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func slicesContainsFold(values []string, want string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+func writeInventory(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "inventory.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadNormalizesInventory(t *testing.T) {
+	inv, err := Load(writeInventory(t, `
+managed:
+  tags: ["tag:infra"]
+devices:
+  - name: " router-1 "
+    tags: ["tag:infra", "tag:router"]
+    routes: ["10.0.0.1/24"]
+    must_be_online: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inv.Devices) != 1 {
+		t.Fatalf("expected one device, got %+v", inv.Devices)
+	}
+	device := inv.Devices[0]
+	if device.Name != "router-1" || !device.MustBeOnline || !reflect.DeepEqual(device.Routes, []string{"10.0.0.0/24"}) {
+		t.Fatalf("unexpected device: %+v", device)
+	}
+	if !reflect.DeepEqual(inv.Managed.Tags, []string{"tag:infra"}) {
+		t.Fatalf("unexpected managed selector: %+v", inv.Managed)
+	}
+}
+
+func TestLoadRejectsInvalidInventory(t *testing.T) {
+	cases := map[string]string{
+		"devices[0].name is required":              "devices:\n  - tags: [\"tag:a\"]\n",
+		"is duplicated":                            "devices:\n  - name: a\n  - name: A\n",
+		"is not a CIDR prefix":                     "devices:\n  - name: a\n    routes: [\"10.0.0.0\"]\n",
+		"managed.device_names[0] has invalid glob": "managed:\n  device_names: [\"[\"]\n",
+	}
+	for want, content := range cases {
+		if _, err := Load(writeInventory(t, content)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error containing %q, got %v", want, err)
+		}
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
+
+func TestCompareReportsDrift(t *testing.T) {
+	inv := Inventory{
+		Managed: Selector{Tags: []string{"tag:infra"}},
+		Devices: []Device{
+			{Name: "router-1", Tags: []string{"tag:infra", "tag:router"}, Routes: []string{"10.0.0.0/24"}, MustBeOnline: true},
+			{Name: "db-1", Tags: []string{"tag:infra"}},
+			{Name: "vpn-1"},
+		},
+	}
+	s := snapshot.Snapshot{Peers: []snapshot.Peer{
+		{ID: "n1", Name: "Router-1", Tags: []string{"tag:infra"}, Routes: []string{"10.0.0.0/24"}, AdvertisedRoutes: []string{"10.0.0.0/24", "10.1.0.0/24"}},
+		{ID: "n2", Name: "db-1", Online: true, Tags: []string{"tag:infra"}},
+		{ID: "n3", Name: "stray", Online: true, Tags: []string{"tag:infra"}},
+		{ID: "n4", Name: "laptop", Online: true},
+	}}
+
+	findings := Compare(inv, s)
+	got := make([]string, 0, len(findings))
+	for _, f := range findings {
+		got = append(got, f.Device+"/"+f.Kind+"/"+f.Reason)
+	}
+	want := []string{
+		"router-1/missing_device/offline",
+		"router-1/routes_mismatch/",
+		"router-1/tags_mismatch/",
+		"stray/unexpected_device/",
+		"vpn-1/missing_device/absent",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected findings %v, got %v", want, got)
+	}
+	routes := findings[1]
+	if routes.PeerID != "n1" || !reflect.DeepEqual(routes.Extra, []string{"10.1.0.0/24"}) || len(routes.Missing) != 0 {
+		t.Fatalf("unexpected routes mismatch: %+v", routes)
+	}
+	tags := findings[2]
+	if !reflect.DeepEqual(tags.Missing, []string{"tag:router"}) || len(tags.Extra) != 0 {
+		t.Fatalf("unexpected tags mismatch: %+v", tags)
+	}
+	if findings[4].Peer != nil || findings[3].Peer == nil || findings[3].PeerID != "n3" {
+		t.Fatalf("unexpected peers on findings: %+v", findings)
+	}
+}
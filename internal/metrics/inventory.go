@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/durfmt"
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+)
+
+const unknownOS = "unknown"
+
+// ObserveSnapshot replaces the tailnet inventory gauges with counts from s.
+// A peer counts toward every horizon its unexpired key falls within.
+func (m *Metrics) ObserveSnapshot(s snapshot.Snapshot, now time.Time, horizons []time.Duration) {
+	var online, expired, unauthorized int
+	byTag := map[string]int{}
+	byOS := map[string]int{}
+	expiring := make([]int, len(horizons))
+	for _, p := range s.Peers {
+		if p.Online {
+			online++
+		}
+		if p.Expired {
+			expired++
+		}
+		if !p.MachineAuthorized {
+			unauthorized++
+		}
+		for _, tag := range p.Tags {
+			byTag[tag]++
+		}
+		os := strings.ToLower(strings.TrimSpace(p.Meta["os"]))
+		if os == "" {
+			os = unknownOS
+		}
+		byOS[os]++
+		if p.Expired {
+			continue
+		}
+		expiry, ok := p.KeyExpiryTime()
+		if !ok || expiry.Before(now) {
+			continue
+		}
+		for i, horizon := range horizons {
+			if !expiry.After(now.Add(horizon)) {
+				expiring[i]++
+			}
+		}
+	}
+
+	m.PeersTotal.Set(float64(len(s.Peers)))
+	m.PeersOnline.Set(float64(online))
+	m.PeersOffline.Set(float64(len(s.Peers) - online))
+	m.PeersKeyExpired.Set(float64(expired))
+	m.PeersUnauthorized.Set(float64(unauthorized))
+	m.PeersByTag.Reset()
+	for tag, count := range byTag {
+		m.PeersByTag.WithLabelValues(tag).Set(float64(count))
+	}
+	m.PeersByOS.Reset()
+	for os, count := range byOS {
+		m.PeersByOS.WithLabelValues(os).Set(float64(count))
+	}
+	m.PeersKeyExpiringWithin.Reset()
+	for i, horizon := range horizons {
+		m.PeersKeyExpiringWithin.WithLabelValues(durfmt.Short(horizon)).Set(float64(expiring[i]))
+	}
+}
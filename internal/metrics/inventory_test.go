@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/snapshot"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveSnapshotSetsInventoryGauges(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := New(prometheus.NewRegistry())
+	s := snapshot.Snapshot{Peers: []snapshot.Peer{
+		{ID: "a", Online: true, MachineAuthorized: true, Tags: []string{"tag:prod", "tag:web"}, Meta: map[string]string{"os": "linux"}, KeyExpiry: now.Add(12 * time.Hour).Format(time.RFC3339)},
+		{ID: "b", Online: false, MachineAuthorized: true, Tags: []string{"tag:prod"}, Meta: map[string]string{"os": "macOS"}, KeyExpiry: now.Add(5 * 24 * time.Hour).Format(time.RFC3339)},
+		{ID: "c", Online: false, MachineAuthorized: false, Expired: true, KeyExpiry: now.Add(-time.Hour).Format(time.RFC3339)},
+		{ID: "d", Online: true, MachineAuthorized: true, Meta: map[string]string{"os": "linux"}, KeyExpiry: "0001-01-01T00:00:00Z"},
+	}}
+
+	m.ObserveSnapshot(s, now, []time.Duration{24 * time.Hour, 7 * 24 * time.Hour})
+
+	gauges := map[string]prometheus.Collector{
+		"total":        m.PeersTotal,
+		"online":       m.PeersOnline,
+		"offline":      m.PeersOffline,
+		"expired":      m.PeersKeyExpired,
+		"unauthorized": m.PeersUnauthorized,
+	}
+	want := map[string]float64{"total": 4, "online": 2, "offline": 2, "expired": 1, "unauthorized": 1}
+	for name, gauge := range gauges {
+		if got := testutil.ToFloat64(gauge); got != want[name] {
+			t.Fatalf("expected %s=%v, got %v", name, want[name], got)
+		}
+	}
+	if got := testutil.ToFloat64(m.PeersByTag.WithLabelValues("tag:prod")); got != 2 {
+		t.Fatalf("expected 2 peers tagged tag:prod, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.PeersByOS.WithLabelValues("linux")); got != 2 {
+		t.Fatalf("expected 2 linux peers, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.PeersByOS.WithLabelValues(unknownOS)); got != 1 {
+		t.Fatalf("expected 1 peer with unknown os, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.PeersKeyExpiringWithin.WithLabelValues("24h")); got != 1 {
+		t.Fatalf("expected 1 key expiring within 24h, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.PeersKeyExpiringWithin.WithLabelValues("168h")); got != 2 {
+		t.Fatalf("expected 2 keys expiring within 168h, got %v", got)
+	}
+}
+
+func TestObserveSnapshotDropsStaleLabels(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	now := time.Now()
+	m.ObserveSnapshot(snapshot.Snapshot{Peers: []snapshot.Peer{{ID: "a", Tags: []string{"tag:old"}}}}, now, nil)
+	m.ObserveSnapshot(snapshot.Snapshot{Peers: []snapshot.Peer{{ID: "a", Tags: []string{"tag:new"}}}}, now, nil)
+	if got := testutil.CollectAndCount(m.PeersByTag); got != 1 {
+		t.Fatalf("expected only the current tag series, got %d", got)
+	}
+}
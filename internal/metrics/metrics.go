@@ -10,6 +10,20 @@ type Metrics struct {
 	NotificationsSentTotal    *prometheus.CounterVec
 	NotificationsSuppressed   *prometheus.CounterVec
 	StateStoreErrorsTotal     prometheus.Counter
+
+	NotificationAttemptsTotal       *prometheus.CounterVec
+	NotificationFailuresTotal       *prometheus.CounterVec
+	NotificationRetriesTotal        *prometheus.CounterVec
+	NotificationSendDurationSeconds *prometheus.HistogramVec
+
+	PeersTotal             prometheus.Gauge
+	PeersOnline            prometheus.Gauge
+	PeersOffline           prometheus.Gauge
+	PeersByTag             *prometheus.GaugeVec
+	PeersByOS              *prometheus.GaugeVec
+	PeersKeyExpired        prometheus.Gauge
+	PeersUnauthorized      prometheus.Gauge
+	PeersKeyExpiringWithin *prometheus.GaugeVec
 }
 
 func New(reg prometheus.Registerer) *Metrics {
@@ -21,6 +35,24 @@ func New(reg prometheus.Registerer) *Metrics {
 		NotificationsSentTotal:    prometheus.NewCounterVec(prometheus.CounterOpts{Name: "notifications_sent_total", Help: "Notifications sent by sink"}, []string{"sink"}),
 		NotificationsSuppressed:   prometheus.NewCounterVec(prometheus.CounterOpts{Name: "notifications_suppressed_total", Help: "Suppressed notifications by reason"}, []string{"reason"}),
 		StateStoreErrorsTotal:     prometheus.NewCounter(prometheus.CounterOpts{Name: "state_store_errors_total", Help: "State store errors"}),
+
+		NotificationAttemptsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "notification_attempts_total", Help: "Notification deliveries attempted by sink"}, []string{"sink"}),
+		NotificationFailuresTotal: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "notification_failures_total", Help: "Failed notification deliveries by sink and reason"}, []string{"sink", "reason"}),
+		NotificationRetriesTotal:  prometheus.NewCounterVec(prometheus.CounterOpts{Name: "notification_retries_total", Help: "Notification send retries by sink"}, []string{"sink"}),
+		NotificationSendDurationSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "notification_send_duration_seconds",
+			Help:    "Notification delivery latency by sink, including retries",
+			Buckets: prometheus.ExponentialBuckets(0.005, 2, 14),
+		}, []string{"sink"}),
+
+		PeersTotal:             prometheus.NewGauge(prometheus.GaugeOpts{Name: "tailnet_peers", Help: "Peers in the latest snapshot"}),
+		PeersOnline:            prometheus.NewGauge(prometheus.GaugeOpts{Name: "tailnet_peers_online", Help: "Online peers in the latest snapshot"}),
+		PeersOffline:           prometheus.NewGauge(prometheus.GaugeOpts{Name: "tailnet_peers_offline", Help: "Offline peers in the latest snapshot"}),
+		PeersByTag:             prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "tailnet_peers_by_tag", Help: "Peers carrying each tag in the latest snapshot"}, []string{"tag"}),
+		PeersByOS:              prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "tailnet_peers_by_os", Help: "Peers by operating system in the latest snapshot"}, []string{"os"}),
+		PeersKeyExpired:        prometheus.NewGauge(prometheus.GaugeOpts{Name: "tailnet_peers_key_expired", Help: "Peers whose node key has expired"}),
+		PeersUnauthorized:      prometheus.NewGauge(prometheus.GaugeOpts{Name: "tailnet_peers_unauthorized", Help: "Peers whose machine is not authorized"}),
+		PeersKeyExpiringWithin: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "tailnet_peers_key_expiring", Help: "Peers whose node key expires within the horizon"}, []string{"within"}),
 	}
 	reg.MustRegister(
 		m.NetmapPollsTotal,
@@ -30,6 +62,18 @@ func New(reg prometheus.Registerer) *Metrics {
 		m.NotificationsSentTotal,
 		m.NotificationsSuppressed,
 		m.StateStoreErrorsTotal,
+		m.NotificationAttemptsTotal,
+		m.NotificationFailuresTotal,
+		m.NotificationRetriesTotal,
+		m.NotificationSendDurationSeconds,
+		m.PeersTotal,
+		m.PeersOnline,
+		m.PeersOffline,
+		m.PeersByTag,
+		m.PeersByOS,
+		m.PeersKeyExpired,
+		m.PeersUnauthorized,
+		m.PeersKeyExpiringWithin,
 	)
 	return m
 }
@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+)
+
+const (
+	FormatJSON        = "json"
+	FormatCloudEvents = "cloudevents"
+
+	CloudEventsStructured = "structured"
+	CloudEventsBinary     = "binary"
+
+	// DefaultCloudEventsSource is the CloudEvents source used when a sink
+	// does not configure one.
+	DefaultCloudEventsSource = "/sentinel"
+
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsContentType = "application/cloudevents+json"
+	jsonContentType        = "application/json"
+)
+
+// cloudEvent is the structured-mode CloudEvents 1.0 JSON envelope. Data holds
+// the event in the sink's schema version.
+type cloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	DataSchema      string    `json:"dataschema,omitempty"`
+	Severity        string    `json:"severity,omitempty"`
+	Data            any       `json:"data"`
+}
+
+func (o sinkOptions) cloudEvent(n Notification) cloudEvent {
+	evt := n.Event
+	subject := evt.SubjectID
+	if evt.SubjectType != "" {
+		subject = evt.SubjectType + "/" + evt.SubjectID
+	}
+	return cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              evt.EventID,
+		Source:          o.cloudEventsSource,
+		Type:            evt.EventType,
+		Subject:         subject,
+		Time:            evt.Timestamp,
+		DataContentType: jsonContentType,
+		DataSchema:      event.SchemaURL(o.schemaVersion),
+		Severity:        evt.Severity,
+		Data:            o.wireEvent(evt),
+	}
+}
+
+// encodeHTTP returns the request body and content headers for n in the
+// sink's format.
+func (o sinkOptions) encodeHTTP(n Notification) ([]byte, http.Header, error) {
+	header := http.Header{}
+	if o.format != FormatCloudEvents {
+		body, err := json.Marshal(o.wireNotification(n))
+		header.Set("Content-Type", jsonContentType)
+		return body, header, err
+	}
+	ce := o.cloudEvent(n)
+	if o.cloudEventsMode != CloudEventsBinary {
+		body, err := json.Marshal(ce)
+		header.Set("Content-Type", cloudEventsContentType)
+		return body, header, err
+	}
+	body, err := json.Marshal(ce.Data)
+	header.Set("Content-Type", ce.DataContentType)
+	header.Set("ce-specversion", ce.SpecVersion)
+	header.Set("ce-id", ce.ID)
+	header.Set("ce-source", ce.Source)
+	header.Set("ce-type", ce.Type)
+	header.Set("ce-time", ce.Time.Format(time.RFC3339Nano))
+	if ce.Subject != "" {
+		header.Set("ce-subject", ce.Subject)
+	}
+	if ce.DataSchema != "" {
+		header.Set("ce-dataschema", ce.DataSchema)
+	}
+	if ce.Severity != "" {
+		header.Set("ce-severity", ce.Severity)
+	}
+	return body, header, err
+}
@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"go.uber.org/zap"
+)
+
+func cloudEventsNotification() Notification {
+	return Notification{
+		Event:          event.NewPeerEvent(event.TypePeerOffline, "node-1", "before", "after", map[string]any{"name": "laptop"}, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)),
+		IdempotencyKey: "k1",
+	}
+}
+
+type capturedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func captureServer(t *testing.T) (*httptest.Server, *capturedRequest) {
+	t.Helper()
+	got := &capturedRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.header = r.Header.Clone()
+		got.body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, got
+}
+
+func TestWebhookSinkSendsStructuredCloudEvent(t *testing.T) {
+	srv, got := captureServer(t)
+	sink := NewWebhookSink("ce", srv.URL, zap.NewNop(), WithFormat(FormatCloudEvents), WithCloudEvents("", "https://sentinel.example.com"))
+	n := cloudEventsNotification()
+	if err := sink.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+
+	if ct := got.header.Get("Content-Type"); ct != "application/cloudevents+json" {
+		t.Fatalf("expected structured content type, got %q", ct)
+	}
+	var ce struct {
+		SpecVersion string      `json:"specversion"`
+		ID          string      `json:"id"`
+		Source      string      `json:"source"`
+		Type        string      `json:"type"`
+		Subject     string      `json:"subject"`
+		Time        time.Time   `json:"time"`
+		Data        event.Event `json:"data"`
+	}
+	if err := json.Unmarshal(got.body, &ce); err != nil {
+		t.Fatal(err)
+	}
+	if ce.SpecVersion != "1.0" || ce.ID != n.Event.EventID || ce.Type != event.TypePeerOffline {
+		t.Fatalf("unexpected cloudevent attributes: %+v", ce)
+	}
+	if ce.Source != "https://sentinel.example.com" || ce.Subject != "peer/node-1" {
+		t.Fatalf("unexpected source/subject: %q %q", ce.Source, ce.Subject)
+	}
+	if !ce.Time.Equal(n.Event.Timestamp) || ce.Data.EventID != n.Event.EventID {
+		t.Fatalf("expected time and data to carry the event, got %+v", ce)
+	}
+	if key := got.header.Get("Idempotency-Key"); key != "k1" {
+		t.Fatalf("expected idempotency key header, got %q", key)
+	}
+}
+
+func TestWebhookSinkSendsBinaryCloudEvent(t *testing.T) {
+	srv, got := captureServer(t)
+	sink := NewWebhookSink("ce", srv.URL, zap.NewNop(),
+		WithFormat(FormatCloudEvents),
+		WithCloudEvents(CloudEventsBinary, ""),
+		WithSchemaVersion(event.SchemaVersionV2),
+	)
+	n := cloudEventsNotification()
+	if err := sink.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"Content-Type":   "application/json",
+		"Ce-Specversion": "1.0",
+		"Ce-Id":          n.Event.EventID,
+		"Ce-Source":      DefaultCloudEventsSource,
+		"Ce-Type":        event.TypePeerOffline,
+		"Ce-Subject":     "peer/node-1",
+		"Ce-Time":        "2026-03-01T12:00:00Z",
+		"Ce-Dataschema":  event.SchemaURL(event.SchemaVersionV2),
+	}
+	for key, value := range want {
+		if got := got.header.Get(key); got != value {
+			t.Fatalf("expected header %s=%q, got %q", key, value, got)
+		}
+	}
+	var data event.EventV2
+	if err := json.Unmarshal(got.body, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.SchemaVersion != event.SchemaVersionV2 || data.EventID != n.Event.EventID {
+		t.Fatalf("expected v2 event as binary body, got %+v", data)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jaxxstorm/sentinel/internal/event"
 	"go.uber.org/zap"
 )
 
@@ -26,6 +27,7 @@ type DiscordSink struct {
 	maxRetries int
 	backoff    time.Duration
 	logger     *zap.Logger
+	opts       sinkOptions
 }
 
 type discordPayload struct {
@@ -47,7 +49,7 @@ type discordEmbedItem struct {
 	Inline bool   `json:"inline,omitempty"`
 }
 
-func NewDiscordSink(name, url string, logger *zap.Logger) *DiscordSink {
+func NewDiscordSink(name, url string, logger *zap.Logger, opts ...SinkOption) *DiscordSink {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
@@ -58,13 +60,14 @@ func NewDiscordSink(name, url string, logger *zap.Logger) *DiscordSink {
 		maxRetries: 3,
 		backoff:    200 * time.Millisecond,
 		logger:     logger,
+		opts:       newSinkOptions(opts),
 	}
 }
 
 func (s *DiscordSink) Name() string { return s.name }
 
 func (s *DiscordSink) Send(ctx context.Context, n Notification) error {
-	payload, err := json.Marshal(discordWebhookPayload(n))
+	payload, err := json.Marshal(discordWebhookPayload(n, s.opts))
 	if err != nil {
 		return err
 	}
@@ -89,7 +92,7 @@ func (s *DiscordSink) Send(ctx context.Context, n Notification) error {
 		}
 		if resp != nil {
 			_ = resp.Body.Close()
-			lastErr = fmt.Errorf("unexpected status %d", resp.StatusCode)
+			lastErr = &StatusError{StatusCode: resp.StatusCode}
 			s.logger.Warn("discord send failed",
 				zap.String("sink", s.name),
 				zap.Int("status_code", resp.StatusCode),
@@ -116,18 +119,19 @@ func (s *DiscordSink) Send(ctx context.Context, n Notification) error {
 				return ctx.Err()
 			case <-time.After(s.backoff * time.Duration(i+1)):
 			}
+			recordRetry(ctx)
 		}
 	}
 	return fmt.Errorf("discord sink failed after retries: %w", lastErr)
 }
 
-func discordWebhookPayload(n Notification) discordPayload {
+func discordWebhookPayload(n Notification, opts sinkOptions) discordPayload {
 	return discordPayload{
-		Embeds: []discordEmbed{discordEmbedForEvent(n)},
+		Embeds: []discordEmbed{discordEmbedForEvent(n, opts)},
 	}
 }
 
-func discordEmbedForEvent(n Notification) discordEmbed {
+func discordEmbedForEvent(n Notification, opts sinkOptions) discordEmbed {
 	evt := n.Event
 	title := truncateString("Sentinel "+evt.EventType, discordEmbedTitleLimit)
 	desc := truncateString(
@@ -154,15 +158,15 @@ func discordEmbedForEvent(n Notification) discordEmbed {
 			},
 			{
 				Name:  "Payload",
-				Value: discordPayloadFieldValue(evt.Payload),
+				Value: discordPayloadFieldValue(discordPayloadForSchema(evt, opts)),
 			},
 		},
 	}
 }
 
-func discordPayloadFieldValue(payload map[string]any) string {
+func discordPayloadFieldValue(payload any) string {
 	value := "{}"
-	if len(payload) > 0 {
+	if payload != nil {
 		if b, err := json.MarshalIndent(payload, "", "  "); err == nil {
 			value = string(b)
 		}
@@ -175,6 +179,18 @@ func discordPayloadFieldValue(payload map[string]any) string {
 	return truncateString(wrapped, discordEmbedFieldLimit)
 }
 
+// discordPayloadForSchema returns the payload shown in the embed, typed for v2
+// sinks. It returns nil when there is nothing to show.
+func discordPayloadForSchema(evt event.Event, opts sinkOptions) any {
+	if len(evt.Payload) == 0 {
+		return nil
+	}
+	if wire, ok := opts.wireEvent(evt).(event.EventV2); ok {
+		return wire.Payload
+	}
+	return evt.Payload
+}
+
 func truncateString(in string, limit int) string {
 	if limit <= 0 || len(in) <= limit {
 		return in
@@ -187,7 +203,7 @@ func truncateString(in string, limit int) string {
 
 func discordSeverityColor(sev string) int {
 	switch strings.ToLower(strings.TrimSpace(sev)) {
-	case "error":
+	case "critical", "error":
 		return 0xE74C3C
 	case "warn", "warning":
 		return 0xF39C12
@@ -104,7 +104,7 @@ func TestDiscordSinkPayloadFieldTruncation(t *testing.T) {
 		Event:          event.NewPeerEvent(event.TypePeerHostinfoChanged, "peer1", "before", "after", payload, time.Unix(1700000000, 0)),
 		IdempotencyKey: "idempotency-1",
 	}
-	out := discordWebhookPayload(n)
+	out := discordWebhookPayload(n, newSinkOptions(nil))
 	if len(out.Embeds) != 1 || len(out.Embeds[0].Fields) < 3 {
 		t.Fatalf("expected embed with payload field, got %#v", out)
 	}
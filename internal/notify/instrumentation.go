@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/metrics"
+	"github.com/jaxxstorm/sentinel/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	FailureReasonTimeout  = "timeout"
+	FailureReasonCanceled = "canceled"
+	FailureReasonError    = "error"
+)
+
+// StatusError reports a sink endpoint answering with a non-2xx HTTP status.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.StatusCode)
+}
+
+// FailureReason classifies a delivery error into a low-cardinality metric label:
+// http_4xx/http_5xx style status classes, timeout, canceled, or error.
+func FailureReason(err error) string {
+	var statusErr *StatusError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &statusErr):
+		return fmt.Sprintf("http_%dxx", statusErr.StatusCode/100)
+	case errors.Is(err, context.Canceled):
+		return FailureReasonCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return FailureReasonTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return FailureReasonTimeout
+	}
+	return FailureReasonError
+}
+
+type retryCounterKey struct{}
+
+// recordRetry notes that a sink is about to re-send the current notification.
+// Sinks with internal retry loops call it so retries show up per sink.
+func recordRetry(ctx context.Context) {
+	if counter, ok := ctx.Value(retryCounterKey{}).(*atomic.Int64); ok {
+		counter.Add(1)
+	}
+}
+
+// instrumentedSink traces each delivery and, when metrics are configured,
+// records attempts, outcomes, retries and latency for any Sink.
+type instrumentedSink struct {
+	Sink
+	metrics *metrics.Metrics
+}
+
+func instrumentSink(sink Sink, m *metrics.Metrics) Sink {
+	return &instrumentedSink{Sink: sink, metrics: m}
+}
+
+func (s *instrumentedSink) Send(ctx context.Context, n Notification) error {
+	name := s.Name()
+	ctx, span := tracing.Tracer().Start(ctx, "notify.Send", trace.WithAttributes(
+		tracing.AttrSink.String(name),
+		tracing.AttrEventID.String(n.Event.EventID),
+		tracing.AttrEventType.String(n.Event.EventType),
+		tracing.AttrIdempotencyKey.String(n.IdempotencyKey),
+	))
+	retries := &atomic.Int64{}
+	ctx = context.WithValue(ctx, retryCounterKey{}, retries)
+
+	start := time.Now()
+	err := s.Sink.Send(ctx, n)
+	tracing.EndSpan(span, err)
+	if s.metrics == nil {
+		return err
+	}
+	s.metrics.NotificationAttemptsTotal.WithLabelValues(name).Inc()
+	s.metrics.NotificationSendDurationSeconds.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	if count := retries.Load(); count > 0 {
+		s.metrics.NotificationRetriesTotal.WithLabelValues(name).Add(float64(count))
+	}
+	if err != nil {
+		s.metrics.NotificationFailuresTotal.WithLabelValues(name, FailureReason(err)).Inc()
+		return err
+	}
+	s.metrics.NotificationsSentTotal.WithLabelValues(name).Inc()
+	return nil
+}
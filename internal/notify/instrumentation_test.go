@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/metrics"
+	"github.com/jaxxstorm/sentinel/internal/state"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type failingSink struct {
+	name string
+	err  error
+}
+
+func (s *failingSink) Name() string { return s.name }
+
+func (s *failingSink) Send(context.Context, Notification) error { return s.err }
+
+func TestNotifierRecordsPerSinkDeliveryMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	m := metrics.New(prometheus.NewRegistry())
+	store := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+	webhook := NewWebhookSink("webhook-primary", srv.URL, nil)
+	failing := &failingSink{name: "discord-ops", err: &StatusError{StatusCode: http.StatusTooManyRequests}}
+	n := New(Config{
+		Routes:  []Route{{EventTypes: []string{"*"}, Sinks: []string{"webhook-primary", "discord-ops"}}},
+		Metrics: m,
+	}, store, []Sink{webhook, failing})
+
+	evt := event.NewPresenceEvent(event.TypePeerOnline, "peer1", "before", "after", nil, time.Now())
+	if _, err := n.Notify(context.Background(), []event.Event{evt}, false); err == nil {
+		t.Fatal("expected failing sink error")
+	}
+
+	if got := testutil.ToFloat64(m.NotificationAttemptsTotal.WithLabelValues("webhook-primary")); got != 1 {
+		t.Fatalf("expected 1 webhook attempt, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.NotificationsSentTotal.WithLabelValues("webhook-primary")); got != 1 {
+		t.Fatalf("expected 1 webhook success, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.NotificationAttemptsTotal.WithLabelValues("discord-ops")); got != 1 {
+		t.Fatalf("expected 1 discord attempt, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.NotificationsSentTotal.WithLabelValues("discord-ops")); got != 0 {
+		t.Fatalf("expected no discord successes, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.NotificationFailuresTotal.WithLabelValues("discord-ops", "http_4xx")); got != 1 {
+		t.Fatalf("expected 1 discord http_4xx failure, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.NotificationSendDurationSeconds); got != 2 {
+		t.Fatalf("expected latency histograms for 2 sinks, got %d", got)
+	}
+}
+
+func TestInstrumentedSinkCountsWebhookRetries(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	m := metrics.New(prometheus.NewRegistry())
+	webhook := NewWebhookSink("webhook-primary", srv.URL, nil)
+	webhook.backoff = time.Millisecond
+	sink := instrumentSink(webhook, m)
+
+	if err := sink.Send(context.Background(), testNotification()); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(m.NotificationRetriesTotal.WithLabelValues("webhook-primary")); got != 2 {
+		t.Fatalf("expected 2 retries, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.NotificationAttemptsTotal.WithLabelValues("webhook-primary")); got != 1 {
+		t.Fatalf("expected 1 delivery attempt, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.NotificationsSentTotal.WithLabelValues("webhook-primary")); got != 1 {
+		t.Fatalf("expected 1 success, got %v", got)
+	}
+}
+
+func TestFailureReason(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{err: &StatusError{StatusCode: http.StatusBadGateway}, want: "http_5xx"},
+		{err: fmt.Errorf("webhook sink failed after retries: %w", &StatusError{StatusCode: http.StatusNotFound}), want: "http_4xx"},
+		{err: context.DeadlineExceeded, want: FailureReasonTimeout},
+		{err: context.Canceled, want: FailureReasonCanceled},
+		{err: errors.New("connection refused"), want: FailureReasonError},
+	}
+	for _, tc := range cases {
+		if got := FailureReason(tc.err); got != tc.want {
+			t.Fatalf("FailureReason(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
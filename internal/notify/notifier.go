@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/metrics"
 	"github.com/jaxxstorm/sentinel/internal/state"
 )
 
@@ -49,6 +50,8 @@ type deviceIdentity struct {
 type Config struct {
 	Routes            []Route
 	IdempotencyKeyTTL time.Duration
+	// Metrics, when set, records per-sink delivery attempts, failures, retries and latency.
+	Metrics *metrics.Metrics
 }
 
 type Notification struct {
@@ -76,7 +79,7 @@ type Notifier struct {
 func New(cfg Config, store state.StateStore, sinks []Sink) *Notifier {
 	m := make(map[string]Sink, len(sinks))
 	for _, sink := range sinks {
-		m[sink.Name()] = sink
+		m[sink.Name()] = instrumentSink(sink, cfg.Metrics)
 	}
 	if cfg.IdempotencyKeyTTL <= 0 {
 		cfg.IdempotencyKeyTTL = 24 * time.Hour
@@ -127,6 +130,16 @@ func (n *Notifier) Notify(ctx context.Context, events []event.Event, dryRun bool
 	return result, nil
 }
 
+// Routed reports whether a route delivers evt to at least one sink.
+func (n *Notifier) Routed(evt event.Event) bool {
+	for _, target := range n.targetsFor(evt) {
+		if _, ok := n.sinks[target]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (n *Notifier) targetsFor(evt event.Event) []string {
 	out := []string{}
 	for _, r := range n.cfg.Routes {
@@ -367,7 +380,9 @@ func matchesSelectorAnyIPs(selector []string, values []string) bool {
 }
 
 func deviceIdentityFromEvent(evt event.Event) (deviceIdentity, bool) {
-	if evt.SubjectType != event.SubjectPeer {
+	switch evt.SubjectType {
+	case event.SubjectPeer, event.SubjectCompliance, event.SubjectDrift:
+	default:
 		return deviceIdentity{}, false
 	}
 	id := deviceIdentity{
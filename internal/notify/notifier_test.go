@@ -137,6 +137,34 @@ func TestNotifierExplicitRouteDoesNotMatchDifferentType(t *testing.T) {
 	}
 }
 
+func TestNotifierRoutedReportsDeliverableEvents(t *testing.T) {
+	store := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+	cfg := Config{
+		Routes: []Route{
+			{EventTypes: []string{event.TypePeerOffline}, Sinks: []string{"sink-a"}},
+			{EventTypes: []string{event.TypeTailnetMassOffline}, Severities: []string{event.SeverityCritical}, Sinks: []string{"sink-a"}},
+			{EventTypes: []string{event.TypePeerOnline}, Sinks: []string{"missing"}},
+		},
+		IdempotencyKeyTTL: time.Hour,
+	}
+	n := New(cfg, store, []Sink{&fakeSink{name: "sink-a"}})
+	now := time.Now()
+	massOffline := event.NewTailnetEvent(event.TypeTailnetMassOffline, "tailnet", "before", "after", nil, now)
+	if !n.Routed(event.NewPresenceEvent(event.TypePeerOffline, "peer1", "before", "after", nil, now)) {
+		t.Fatal("expected peer.offline to be routed")
+	}
+	if n.Routed(massOffline) {
+		t.Fatal("expected tailnet.mass_offline below the route's severity to be unrouted")
+	}
+	massOffline.Severity = event.SeverityCritical
+	if !n.Routed(massOffline) {
+		t.Fatal("expected critical tailnet.mass_offline to be routed")
+	}
+	if n.Routed(event.NewPresenceEvent(event.TypePeerOnline, "peer1", "before", "after", nil, now)) {
+		t.Fatal("expected a route to a missing sink to be unrouted")
+	}
+}
+
 func TestNotifierMixedWildcardAndLiteralStillMatchesAll(t *testing.T) {
 	store := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
 	sink := &fakeSink{name: "sink-mixed"}
@@ -0,0 +1,76 @@
+package notify
+
+import "github.com/jaxxstorm/sentinel/internal/event"
+
+// SinkOption customizes how a sink encodes notifications.
+type SinkOption func(*sinkOptions)
+
+type sinkOptions struct {
+	schemaVersion     string
+	format            string
+	cloudEventsMode   string
+	cloudEventsSource string
+}
+
+func newSinkOptions(opts []SinkOption) sinkOptions {
+	o := sinkOptions{
+		schemaVersion:     event.SchemaVersion,
+		format:            FormatJSON,
+		cloudEventsMode:   CloudEventsStructured,
+		cloudEventsSource: DefaultCloudEventsSource,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithSchemaVersion selects the event wire format: event.SchemaVersion (v1,
+// the default) or event.SchemaVersionV2. Empty keeps the default.
+func WithSchemaVersion(version string) SinkOption {
+	return func(o *sinkOptions) {
+		if version != "" {
+			o.schemaVersion = version
+		}
+	}
+}
+
+// WithFormat selects the HTTP body format: FormatJSON (the default) or
+// FormatCloudEvents. Only the webhook sink honours it.
+func WithFormat(format string) SinkOption {
+	return func(o *sinkOptions) {
+		if format != "" {
+			o.format = format
+		}
+	}
+}
+
+// WithCloudEvents sets the CloudEvents HTTP binding mode (CloudEventsStructured
+// or CloudEventsBinary) and source URI. Empty values keep the defaults.
+func WithCloudEvents(mode, source string) SinkOption {
+	return func(o *sinkOptions) {
+		if mode != "" {
+			o.cloudEventsMode = mode
+		}
+		if source != "" {
+			o.cloudEventsSource = source
+		}
+	}
+}
+
+// wireNotification is the JSON body sinks emit for a notification.
+type wireNotification struct {
+	Event          any    `json:"event"`
+	IdempotencyKey string `json:"idempotency_key"`
+}
+
+func (o sinkOptions) wireEvent(evt event.Event) any {
+	if o.schemaVersion == event.SchemaVersionV2 {
+		return event.ToV2(evt)
+	}
+	return evt
+}
+
+func (o sinkOptions) wireNotification(n Notification) wireNotification {
+	return wireNotification{Event: o.wireEvent(n.Event), IdempotencyKey: n.IdempotencyKey}
+}
@@ -13,10 +13,11 @@ type StdoutSink struct {
 	name string
 	w    io.Writer
 	mu   sync.Mutex
+	opts sinkOptions
 }
 
-func NewStdoutSink(name string, w io.Writer) *StdoutSink {
-	return &StdoutSink{name: name, w: w}
+func NewStdoutSink(name string, w io.Writer, opts ...SinkOption) *StdoutSink {
+	return &StdoutSink{name: name, w: w, opts: newSinkOptions(opts)}
 }
 
 func (s *StdoutSink) Name() string { return s.name }
@@ -25,11 +26,11 @@ func (s *StdoutSink) Send(_ context.Context, n Notification) error {
 	payload, err := json.Marshal(struct {
 		LogSource string `json:"log_source"`
 		Sink      string `json:"sink"`
-		Notification
+		wireNotification
 	}{
-		LogSource:    logging.LogSourceSink,
-		Sink:         s.name,
-		Notification: n,
+		LogSource:        logging.LogSourceSink,
+		Sink:             s.name,
+		wireNotification: s.opts.wireNotification(n),
 	})
 	if err != nil {
 		return err
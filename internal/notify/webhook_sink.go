@@ -3,7 +3,6 @@ package notify
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
@@ -18,9 +17,10 @@ type WebhookSink struct {
 	maxRetries int
 	backoff    time.Duration
 	logger     *zap.Logger
+	opts       sinkOptions
 }
 
-func NewWebhookSink(name, url string, logger *zap.Logger) *WebhookSink {
+func NewWebhookSink(name, url string, logger *zap.Logger, opts ...SinkOption) *WebhookSink {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
@@ -31,13 +31,14 @@ func NewWebhookSink(name, url string, logger *zap.Logger) *WebhookSink {
 		maxRetries: 3,
 		backoff:    200 * time.Millisecond,
 		logger:     logger,
+		opts:       newSinkOptions(opts),
 	}
 }
 
 func (s *WebhookSink) Name() string { return s.name }
 
 func (s *WebhookSink) Send(ctx context.Context, n Notification) error {
-	payload, err := json.Marshal(n)
+	payload, header, err := s.opts.encodeHTTP(n)
 	if err != nil {
 		return err
 	}
@@ -48,7 +49,9 @@ func (s *WebhookSink) Send(ctx context.Context, n Notification) error {
 		if err != nil {
 			return err
 		}
-		req.Header.Set("Content-Type", "application/json")
+		for key, values := range header {
+			req.Header[key] = values
+		}
 		req.Header.Set("Idempotency-Key", n.IdempotencyKey)
 
 		resp, err := s.client.Do(req)
@@ -62,7 +65,7 @@ func (s *WebhookSink) Send(ctx context.Context, n Notification) error {
 		}
 		if resp != nil {
 			_ = resp.Body.Close()
-			lastErr = fmt.Errorf("unexpected status %d", resp.StatusCode)
+			lastErr = &StatusError{StatusCode: resp.StatusCode}
 			s.logger.Warn("webhook send failed",
 				zap.String("sink", s.name),
 				zap.Int("status_code", resp.StatusCode),
@@ -89,6 +92,7 @@ func (s *WebhookSink) Send(ctx context.Context, n Notification) error {
 				return ctx.Err()
 			case <-time.After(s.backoff * time.Duration(i+1)):
 			}
+			recordRetry(ctx)
 		}
 	}
 	return fmt.Errorf("webhook sink failed after retries: %w", lastErr)
@@ -2,6 +2,7 @@ package notify
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -74,3 +75,47 @@ func TestWebhookSinkLogsFailuresWithStatusCodeAndSinkName(t *testing.T) {
 		t.Fatalf("expected status_code %d, got %#v", http.StatusBadGateway, got)
 	}
 }
+
+func TestWebhookSinkEncodesSchemaV2Payload(t *testing.T) {
+	var body struct {
+		Event struct {
+			SchemaVersion string `json:"schema_version"`
+			Payload       struct {
+				Device struct {
+					Name string   `json:"name"`
+					Tags []string `json:"tags"`
+				} `json:"device"`
+				Online bool `json:"online"`
+			} `json:"payload"`
+		} `json:"event"`
+		IdempotencyKey string `json:"idempotency_key"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink("webhook-v2", srv.URL, zap.NewNop(), WithSchemaVersion(event.SchemaVersionV2))
+	n := Notification{
+		Event:          event.NewPeerEvent(event.TypePeerAdded, "peer1", "", "after", map[string]any{"name": "laptop", "online": true}, time.Now()),
+		IdempotencyKey: "k1",
+	}
+	if err := sink.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if body.Event.SchemaVersion != event.SchemaVersionV2 {
+		t.Fatalf("expected schema_version v2, got %q", body.Event.SchemaVersion)
+	}
+	if body.Event.Payload.Device.Name != "laptop" || !body.Event.Payload.Online {
+		t.Fatalf("expected typed v2 payload, got %+v", body.Event.Payload)
+	}
+	if body.Event.Payload.Device.Tags == nil {
+		t.Fatal("expected empty tags list rather than null")
+	}
+	if body.IdempotencyKey != "k1" {
+		t.Fatalf("expected idempotency key k1, got %q", body.IdempotencyKey)
+	}
+}
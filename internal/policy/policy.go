@@ -12,6 +12,15 @@ type Config struct {
 	SuppressionWindow time.Duration
 	RateLimitPerMin   int
 	BatchSize         int
+	// Suppressors run before debounce and rate limiting, in order.
+	Suppressors []Suppressor
+}
+
+// Suppressor holds back events based on state kept outside the policy
+// engine, such as a detector's view of flapping peers. It returns the
+// suppression reason when evt should be dropped.
+type Suppressor interface {
+	Suppress(evt event.Event) (reason string, suppressed bool)
 }
 
 type SuppressedEvent struct {
@@ -49,6 +58,10 @@ func (e *Engine) Apply(events []event.Event) (Result, error) {
 	now := e.now().UTC()
 
 	for _, evt := range events {
+		if reason, ok := e.suppress(evt); ok {
+			res.Suppressed = append(res.Suppressed, SuppressedEvent{Event: evt, Reason: reason})
+			continue
+		}
 		key := fmt.Sprintf("%s|%s", evt.EventType, evt.SubjectID)
 		if t, ok := e.lastSeen[key]; ok {
 			if e.cfg.DebounceWindow > 0 && now.Sub(t) < e.cfg.DebounceWindow {
@@ -86,3 +99,12 @@ func (e *Engine) Apply(events []event.Event) (Result, error) {
 	}
 	return res, nil
 }
+
+func (e *Engine) suppress(evt event.Event) (string, bool) {
+	for _, s := range e.cfg.Suppressors {
+		if reason, ok := s.Suppress(evt); ok {
+			return reason, true
+		}
+	}
+	return "", false
+}
@@ -35,3 +35,31 @@ func TestPolicyDebounceRateLimitBatching(t *testing.T) {
 		t.Fatalf("expected one batch of 2, got %#v", res.Batches)
 	}
 }
+
+type subjectSuppressor string
+
+func (s subjectSuppressor) Suppress(evt event.Event) (string, bool) {
+	return "flapping", evt.SubjectID == string(s)
+}
+
+func TestPolicySuppressorsRunBeforeRateLimit(t *testing.T) {
+	engine := NewEngine(Config{
+		RateLimitPerMin: 1,
+		BatchSize:       10,
+		Suppressors:     []Suppressor{subjectSuppressor("a")},
+	})
+
+	res, err := engine.Apply([]event.Event{
+		{EventType: "peer.online", SubjectID: "a"},
+		{EventType: "peer.online", SubjectID: "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Suppressed) != 1 || res.Suppressed[0].Reason != "flapping" {
+		t.Fatalf("expected one flapping suppression, got %#v", res.Suppressed)
+	}
+	if len(res.Batches) != 1 || res.Batches[0][0].SubjectID != "b" {
+		t.Fatalf("expected b to use the rate limit budget, got %#v", res.Batches)
+	}
+}
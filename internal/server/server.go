@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// ReadinessFunc returns nil once Sentinel is ready to serve traffic, or an
+// error describing what it is still waiting for.
+type ReadinessFunc func() error
+
+// ListenFunc binds the listener the server accepts connections on.
+type ListenFunc func() (net.Listener, error)
+
+type Config struct {
+	ListenAddr string
+	// Listen overrides the default host TCP listener, for example to bind on the tailnet.
+	Listen ListenFunc
+	// TailnetAuth, when set, requires every request to come from an allowed tailnet caller.
+	TailnetAuth *TailnetAuth
+	Gatherer    prometheus.Gatherer
+	Ready       ReadinessFunc
+	Logger      *zap.Logger
+}
+
+// Server hosts Sentinel's operational HTTP endpoints: /metrics, /healthz and /readyz.
+type Server struct {
+	cfg     Config
+	handler http.Handler
+}
+
+func New(cfg Config) *Server {
+	if cfg.Gatherer == nil {
+		cfg.Gatherer = prometheus.DefaultGatherer
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+	s := &Server{cfg: cfg}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(cfg.Gatherer, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+	s.handler = mux
+	if cfg.TailnetAuth != nil {
+		s.handler = cfg.TailnetAuth.middleware(mux, cfg.Logger)
+	}
+	return s
+}
+
+func (s *Server) Handler() http.Handler { return s.handler }
+
+func (s *Server) ListenAddr() string { return s.cfg.ListenAddr }
+
+// Listen binds the configured listen address.
+func (s *Server) Listen() (net.Listener, error) {
+	if s.cfg.Listen != nil {
+		ln, err := s.cfg.Listen()
+		if err != nil {
+			return nil, fmt.Errorf("listen %s: %w", s.cfg.ListenAddr, err)
+		}
+		return ln, nil
+	}
+	ln, err := net.Listen("tcp", s.cfg.ListenAddr)
+	if err != nil {
+		return nil, fmt.Errorf("listen %s: %w", s.cfg.ListenAddr, err)
+	}
+	return ln, nil
+}
+
+// Serve accepts connections on ln until ctx is canceled, then shuts down gracefully.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:           s.handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+	s.cfg.Logger.Info("http server listening", zap.String("addr", ln.Addr().String()))
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	writeText(w, http.StatusOK, "ok")
+}
+
+func (s *Server) handleReadyz(w http.ResponseWriter, _ *http.Request) {
+	if s.cfg.Ready != nil {
+		if err := s.cfg.Ready(); err != nil {
+			writeText(w, http.StatusServiceUnavailable, "not ready: "+err.Error())
+			return
+		}
+	}
+	writeText(w, http.StatusOK, "ok")
+}
+
+func writeText(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(body + "\n"))
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+	"tailscale.com/client/tailscale/apitype"
+)
+
+// WhoIsFunc resolves a tailnet remote address (ip:port) to its node and user identity.
+type WhoIsFunc func(ctx context.Context, remoteAddr string) (*apitype.WhoIsResponse, error)
+
+// TailnetAuth restricts access to callers identified over the tailnet.
+// An empty allow list accepts any caller that WhoIs can identify.
+type TailnetAuth struct {
+	WhoIs        WhoIsFunc
+	AllowedTags  []string
+	AllowedUsers []string
+}
+
+type caller struct {
+	LoginName string
+	NodeName  string
+	Tags      []string
+}
+
+func (a TailnetAuth) middleware(next http.Handler, logger *zap.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := a.identify(r)
+		if err != nil {
+			logger.Warn("tailnet caller identification failed",
+				zap.String("remote_addr", r.RemoteAddr),
+				zap.String("path", r.URL.Path),
+				zap.Error(err),
+			)
+			writeText(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		if !a.allowed(c) {
+			logger.Warn("tailnet caller not allowed",
+				zap.String("remote_addr", r.RemoteAddr),
+				zap.String("path", r.URL.Path),
+				zap.String("user", c.LoginName),
+				zap.String("node", c.NodeName),
+				zap.Strings("tags", c.Tags),
+			)
+			writeText(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a TailnetAuth) identify(r *http.Request) (caller, error) {
+	if a.WhoIs == nil {
+		return caller{}, errors.New("whois is not configured")
+	}
+	resp, err := a.WhoIs(r.Context(), r.RemoteAddr)
+	if err != nil {
+		return caller{}, err
+	}
+	if resp == nil || resp.Node == nil {
+		return caller{}, errors.New("whois returned no node")
+	}
+	c := caller{
+		NodeName: resp.Node.ComputedName,
+		Tags:     append([]string(nil), resp.Node.Tags...),
+	}
+	if resp.UserProfile != nil {
+		c.LoginName = resp.UserProfile.LoginName
+	}
+	return c, nil
+}
+
+func (a TailnetAuth) allowed(c caller) bool {
+	if len(a.AllowedTags) == 0 && len(a.AllowedUsers) == 0 {
+		return true
+	}
+	for _, allowed := range a.AllowedTags {
+		for _, tag := range c.Tags {
+			if strings.EqualFold(strings.TrimSpace(allowed), tag) {
+				return true
+			}
+		}
+	}
+	// Tagged nodes carry a placeholder user profile, so only match users for untagged callers.
+	if len(c.Tags) > 0 || c.LoginName == "" {
+		return false
+	}
+	for _, allowed := range a.AllowedUsers {
+		if strings.EqualFold(strings.TrimSpace(allowed), c.LoginName) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tailscale.com/client/tailscale/apitype"
+	"tailscale.com/tailcfg"
+)
+
+func whoIsReturning(resp *apitype.WhoIsResponse, err error) WhoIsFunc {
+	return func(context.Context, string) (*apitype.WhoIsResponse, error) {
+		return resp, err
+	}
+}
+
+func serveWithAuth(t *testing.T, auth TailnetAuth) int {
+	t.Helper()
+	s := New(Config{TailnetAuth: &auth})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.RemoteAddr = "100.64.0.9:41000"
+	s.Handler().ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestTailnetAuthAllowsTaggedCaller(t *testing.T) {
+	code := serveWithAuth(t, TailnetAuth{
+		WhoIs: whoIsReturning(&apitype.WhoIsResponse{
+			Node:        &tailcfg.Node{ComputedName: "prometheus", Tags: []string{"tag:monitoring"}},
+			UserProfile: &tailcfg.UserProfile{LoginName: "tagged-devices"},
+		}, nil),
+		AllowedTags: []string{"tag:monitoring"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("expected 200 for allowed tag, got %d", code)
+	}
+}
+
+func TestTailnetAuthAllowsListedUser(t *testing.T) {
+	code := serveWithAuth(t, TailnetAuth{
+		WhoIs: whoIsReturning(&apitype.WhoIsResponse{
+			Node:        &tailcfg.Node{ComputedName: "laptop"},
+			UserProfile: &tailcfg.UserProfile{LoginName: "alice@example.com"},
+		}, nil),
+		AllowedUsers: []string{"Alice@example.com"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("expected 200 for allowed user, got %d", code)
+	}
+}
+
+func TestTailnetAuthRejectsUnlistedCaller(t *testing.T) {
+	code := serveWithAuth(t, TailnetAuth{
+		WhoIs: whoIsReturning(&apitype.WhoIsResponse{
+			Node:        &tailcfg.Node{ComputedName: "laptop", Tags: []string{"tag:dev"}},
+			UserProfile: &tailcfg.UserProfile{LoginName: "tagged-devices"},
+		}, nil),
+		AllowedTags:  []string{"tag:monitoring"},
+		AllowedUsers: []string{"tagged-devices"},
+	})
+	if code != http.StatusForbidden {
+		t.Fatalf("expected 403 for unlisted caller, got %d", code)
+	}
+}
+
+func TestTailnetAuthRejectsUnidentifiedCaller(t *testing.T) {
+	code := serveWithAuth(t, TailnetAuth{
+		WhoIs: whoIsReturning(nil, errors.New("peer not found")),
+	})
+	if code != http.StatusForbidden {
+		t.Fatalf("expected 403 when whois fails, got %d", code)
+	}
+}
+
+func TestTailnetAuthWithoutAllowListAcceptsIdentifiedCaller(t *testing.T) {
+	code := serveWithAuth(t, TailnetAuth{
+		WhoIs: whoIsReturning(&apitype.WhoIsResponse{
+			Node: &tailcfg.Node{ComputedName: "laptop"},
+		}, nil),
+	})
+	if code != http.StatusOK {
+		t.Fatalf("expected 200 without allow list, got %d", code)
+	}
+}
@@ -0,0 +1,159 @@
+package severity
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+)
+
+// Rule assigns Severity to events matching every configured field.
+// Values within a field are ORed; empty fields match anything.
+type Rule struct {
+	Name        string
+	EventTypes  []string
+	Tags        []string
+	Owners      []string
+	DeviceNames []string
+	Payload     map[string]any
+	Severity    string
+}
+
+// Classifier sets event severity from the first matching rule. Events that
+// match no rule keep the severity their detector assigned.
+type Classifier struct {
+	rules []Rule
+}
+
+func NewClassifier(rules []Rule) *Classifier {
+	return &Classifier{rules: append([]Rule(nil), rules...)}
+}
+
+func (c *Classifier) Classify(events []event.Event) []event.Event {
+	if c == nil || len(c.rules) == 0 {
+		return events
+	}
+	out := make([]event.Event, len(events))
+	for i, evt := range events {
+		for _, rule := range c.rules {
+			if rule.matches(evt) {
+				evt.Severity = rule.Severity
+				break
+			}
+		}
+		out[i] = evt
+	}
+	return out
+}
+
+func (r Rule) matches(evt event.Event) bool {
+	if len(r.EventTypes) > 0 && !matchesEventType(r.EventTypes, evt.EventType) {
+		return false
+	}
+	if len(r.Tags) > 0 && !matchesAny(r.Tags, payloadStrings(evt.Payload, "tags")) {
+		return false
+	}
+	if len(r.Owners) > 0 && !matchesAny(r.Owners, payloadStrings(evt.Payload, "owners")) {
+		return false
+	}
+	if len(r.DeviceNames) > 0 && !matchesDeviceName(r.DeviceNames, deviceName(evt)) {
+		return false
+	}
+	for key, want := range r.Payload {
+		if !matchesPayloadValue(evt.Payload[key], want) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesEventType(items []string, target string) bool {
+	for _, item := range items {
+		if item == "*" || item == target {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(selector []string, values []string) bool {
+	for _, value := range values {
+		for _, raw := range selector {
+			if strings.EqualFold(strings.TrimSpace(raw), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesDeviceName(patterns []string, name string) bool {
+	if name == "" {
+		return false
+	}
+	name = strings.ToLower(name)
+	for _, raw := range patterns {
+		pattern := strings.ToLower(strings.TrimSpace(raw))
+		if pattern == "" {
+			continue
+		}
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPayloadValue compares payload values by their string form, so a
+// configured "false" or false both match a boolean payload field. List
+// payload values match when any element does.
+func matchesPayloadValue(got, want any) bool {
+	if got == nil {
+		return false
+	}
+	wantStr := strings.ToLower(strings.TrimSpace(fmt.Sprint(want)))
+	switch v := got.(type) {
+	case []string:
+		for _, item := range v {
+			if strings.EqualFold(item, wantStr) {
+				return true
+			}
+		}
+		return false
+	case []any:
+		for _, item := range v {
+			if strings.EqualFold(fmt.Sprint(item), wantStr) {
+				return true
+			}
+		}
+		return false
+	}
+	return strings.EqualFold(fmt.Sprint(got), wantStr)
+}
+
+func deviceName(evt event.Event) string {
+	if name, ok := evt.Payload["name"].(string); ok && strings.TrimSpace(name) != "" {
+		return strings.TrimSpace(name)
+	}
+	if evt.SubjectType == event.SubjectPeer {
+		return evt.SubjectID
+	}
+	return ""
+}
+
+func payloadStrings(payload map[string]any, key string) []string {
+	switch v := payload[key].(type) {
+	case []string:
+		return v
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
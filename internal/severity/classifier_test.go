@@ -0,0 +1,78 @@
+package severity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+)
+
+func peerEvent(eventType, name string, tags []string, extra map[string]any) event.Event {
+	payload := map[string]any{
+		"name":   name,
+		"tags":   tags,
+		"owners": []string{"alice@example.com"},
+		"ips":    []string{"100.64.0.1"},
+	}
+	for k, v := range extra {
+		payload[k] = v
+	}
+	return event.NewPeerEvent(eventType, "node-"+name, "before", "after", payload, time.Now())
+}
+
+func TestClassifierFirstMatchingRuleWins(t *testing.T) {
+	c := NewClassifier([]Rule{
+		{Name: "prod-offline", EventTypes: []string{event.TypePeerOffline}, Tags: []string{"tag:prod"}, Severity: event.SeverityCritical},
+		{Name: "any-offline", EventTypes: []string{event.TypePeerOffline}, Severity: event.SeverityWarning},
+		{Name: "key-expired", EventTypes: []string{event.TypePeerKeyExpired}, Severity: event.SeverityWarning},
+	})
+	events := c.Classify([]event.Event{
+		peerEvent(event.TypePeerOffline, "db-1", []string{"tag:prod"}, nil),
+		peerEvent(event.TypePeerOffline, "laptop", nil, nil),
+		peerEvent(event.TypePeerKeyExpired, "laptop", nil, nil),
+		peerEvent(event.TypePeerOnline, "db-1", []string{"tag:prod"}, nil),
+	})
+	want := []string{event.SeverityCritical, event.SeverityWarning, event.SeverityWarning, event.SeverityInfo}
+	for i, evt := range events {
+		if evt.Severity != want[i] {
+			t.Fatalf("event %d (%s): expected severity %q, got %q", i, evt.EventType, want[i], evt.Severity)
+		}
+	}
+}
+
+func TestClassifierMatchesOwnersDeviceGlobsAndPayload(t *testing.T) {
+	c := NewClassifier([]Rule{{
+		EventTypes:  []string{"*"},
+		Owners:      []string{"ALICE@example.com"},
+		DeviceNames: []string{"db-*"},
+		Payload:     map[string]any{"after_authorized": false},
+		Severity:    event.SeverityCritical,
+	}})
+	matching := peerEvent(event.TypePeerMachineAuthorizedChanged, "db-2", nil, map[string]any{"after_authorized": false})
+	wrongName := peerEvent(event.TypePeerMachineAuthorizedChanged, "web-1", nil, map[string]any{"after_authorized": false})
+	wrongPayload := peerEvent(event.TypePeerMachineAuthorizedChanged, "db-2", nil, map[string]any{"after_authorized": true})
+
+	events := c.Classify([]event.Event{matching, wrongName, wrongPayload})
+	if events[0].Severity != event.SeverityCritical {
+		t.Fatalf("expected matching event to be critical, got %q", events[0].Severity)
+	}
+	if events[1].Severity != event.SeverityInfo || events[2].Severity != event.SeverityInfo {
+		t.Fatalf("expected non-matching events to stay info, got %q and %q", events[1].Severity, events[2].Severity)
+	}
+}
+
+func TestClassifierPayloadListMatchesAnyElement(t *testing.T) {
+	c := NewClassifier([]Rule{{Payload: map[string]any{"after_routes": "0.0.0.0/0"}, Severity: event.SeverityWarning}})
+	evt := peerEvent(event.TypePeerRoutesChanged, "exit-1", nil, map[string]any{"after_routes": []string{"0.0.0.0/0", "::/0"}})
+	if got := c.Classify([]event.Event{evt})[0].Severity; got != event.SeverityWarning {
+		t.Fatalf("expected list payload match, got %q", got)
+	}
+}
+
+func TestNilClassifierLeavesEventsUnchanged(t *testing.T) {
+	var c *Classifier
+	evt := peerEvent(event.TypePeerOffline, "db-1", nil, nil)
+	if got := c.Classify([]event.Event{evt})[0].Severity; got != event.SeverityInfo {
+		t.Fatalf("expected info severity, got %q", got)
+	}
+}
@@ -5,26 +5,101 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/jaxxstorm/sentinel/internal/source"
 )
 
 type Peer struct {
-	ID                string            `json:"id"`
-	Name              string            `json:"name"`
-	Online            bool              `json:"online"`
-	Tags              []string          `json:"tags,omitempty"`
-	Owners            []string          `json:"owners,omitempty"`
-	IPs               []string          `json:"ips,omitempty"`
-	Routes            []string          `json:"routes,omitempty"`
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Online bool     `json:"online"`
+	Tags   []string `json:"tags,omitempty"`
+	Owners []string `json:"owners,omitempty"`
+	IPs    []string `json:"ips,omitempty"`
+	Routes []string `json:"routes,omitempty"`
+	// AdvertisedRoutes are the subnet routes the peer offers, including
+	// standby routes of an HA pair that are not in Routes (the primary
+	// routes). Empty when the source does not report Hostinfo.
+	AdvertisedRoutes []string `json:"advertised_routes,omitempty"`
+	// ApprovedRoutes are the routes control approved for the peer, from its
+	// AllowedIPs, whether or not it is primary for them. Empty in snapshots
+	// taken before they were captured.
+	ApprovedRoutes    []string          `json:"approved_routes,omitempty"`
 	MachineAuthorized bool              `json:"machine_authorized,omitempty"`
 	Expired           bool              `json:"expired,omitempty"`
 	KeyExpiry         string            `json:"key_expiry,omitempty"`
 	HostinfoHash      string            `json:"hostinfo_hash,omitempty"`
+	Hostinfo          *Hostinfo         `json:"hostinfo,omitempty"`
 	Meta              map[string]string `json:"meta,omitempty"`
 }
 
+// Hostinfo holds the Hostinfo fields diffed field by field. Services are
+// sorted "proto:port" entries. Peer.Hostinfo is nil when the source does not
+// report Hostinfo.
+type Hostinfo struct {
+	OS           string   `json:"os,omitempty"`
+	OSVersion    string   `json:"os_version,omitempty"`
+	IPNVersion   string   `json:"ipn_version,omitempty"`
+	Distro       string   `json:"distro,omitempty"`
+	DeviceModel  string   `json:"device_model,omitempty"`
+	ShieldsUp    bool     `json:"shields_up,omitempty"`
+	AllowsUpdate bool     `json:"allows_update,omitempty"`
+	Services     []string `json:"services,omitempty"`
+}
+
+// KeyExpiryTime parses KeyExpiry. It reports false for peers whose key does
+// not expire, which the netmap encodes as an empty or zero time.
+func (p Peer) KeyExpiryTime() (time.Time, bool) {
+	return parseKeyExpiry(p.KeyExpiry)
+}
+
+// Self is Sentinel's own node. Snapshot.Self is nil when the source does not
+// report it.
+type Self struct {
+	ID                string   `json:"id"`
+	Name              string   `json:"name"`
+	Tags              []string `json:"tags,omitempty"`
+	Owners            []string `json:"owners,omitempty"`
+	IPs               []string `json:"ips,omitempty"`
+	MachineAuthorized bool     `json:"machine_authorized,omitempty"`
+	Expired           bool     `json:"expired,omitempty"`
+	KeyExpiry         string   `json:"key_expiry,omitempty"`
+}
+
+// KeyExpiryTime parses KeyExpiry like Peer.KeyExpiryTime.
+func (s Self) KeyExpiryTime() (time.Time, bool) {
+	return parseKeyExpiry(s.KeyExpiry)
+}
+
+// Peer returns the node as an online peer, for code shared with peers.
+func (s Self) Peer() Peer {
+	return Peer{
+		ID:                s.ID,
+		Name:              s.Name,
+		Online:            true,
+		Tags:              s.Tags,
+		Owners:            s.Owners,
+		IPs:               s.IPs,
+		MachineAuthorized: s.MachineAuthorized,
+		Expired:           s.Expired,
+		KeyExpiry:         s.KeyExpiry,
+	}
+}
+
+func parseKeyExpiry(raw string) (time.Time, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil || t.IsZero() {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
 type Prefs struct {
 	AdvertiseRoutes []string `json:"advertise_routes,omitempty"`
 	ExitNodeID      string   `json:"exit_node_id,omitempty"`
@@ -40,6 +115,7 @@ type Tailnet struct {
 type Snapshot struct {
 	CapturedAt    time.Time `json:"captured_at"`
 	Peers         []Peer    `json:"peers"`
+	Self          *Self     `json:"self,omitempty"`
 	DaemonState   string    `json:"daemon_state,omitempty"`
 	Prefs         Prefs     `json:"prefs,omitempty"`
 	Tailnet       Tailnet   `json:"tailnet,omitempty"`
@@ -58,6 +134,15 @@ func Normalize(nm source.Netmap, now time.Time) Snapshot {
 		sort.Strings(ips)
 		routes := append([]string(nil), p.Routes...)
 		sort.Strings(routes)
+		var advertised, approved []string
+		if len(p.AdvertisedRoutes) > 0 {
+			advertised = append(advertised, p.AdvertisedRoutes...)
+			sort.Strings(advertised)
+		}
+		if len(p.ApprovedRoutes) > 0 {
+			approved = append(approved, p.ApprovedRoutes...)
+			sort.Strings(approved)
+		}
 		peers = append(peers, Peer{
 			ID:                p.ID,
 			Name:              p.Name,
@@ -66,10 +151,13 @@ func Normalize(nm source.Netmap, now time.Time) Snapshot {
 			Owners:            owners,
 			IPs:               ips,
 			Routes:            routes,
+			AdvertisedRoutes:  advertised,
+			ApprovedRoutes:    approved,
 			MachineAuthorized: p.MachineAuthorized,
 			Expired:           p.Expired,
 			KeyExpiry:         p.KeyExpiry,
 			HostinfoHash:      p.HostinfoHash,
+			Hostinfo:          normalizeHostinfo(p.Hostinfo),
 			Meta:              redactVolatileMeta(p.Metadata),
 		})
 	}
@@ -80,6 +168,7 @@ func Normalize(nm source.Netmap, now time.Time) Snapshot {
 	s := Snapshot{
 		CapturedAt:  now.UTC(),
 		Peers:       peers,
+		Self:        normalizeSelf(nm.Self),
 		DaemonState: nm.DaemonState,
 		Prefs: Prefs{
 			AdvertiseRoutes: advertiseRoutes,
@@ -97,15 +186,63 @@ func Normalize(nm source.Netmap, now time.Time) Snapshot {
 	return s
 }
 
+func normalizeSelf(in *source.Self) *Self {
+	if in == nil {
+		return nil
+	}
+	return &Self{
+		ID:                in.ID,
+		Name:              in.Name,
+		Tags:              sortedCopy(in.Tags),
+		Owners:            sortedCopy(in.Owners),
+		IPs:               sortedCopy(in.IPs),
+		MachineAuthorized: in.MachineAuthorized,
+		Expired:           in.Expired,
+		KeyExpiry:         in.KeyExpiry,
+	}
+}
+
+func sortedCopy(in []string) []string {
+	if len(in) == 0 {
+		return nil
+	}
+	out := append([]string(nil), in...)
+	sort.Strings(out)
+	return out
+}
+
+func normalizeHostinfo(h *source.Hostinfo) *Hostinfo {
+	if h == nil {
+		return nil
+	}
+	var services []string
+	if len(h.Services) > 0 {
+		services = append(services, h.Services...)
+		sort.Strings(services)
+	}
+	return &Hostinfo{
+		OS:           h.OS,
+		OSVersion:    h.OSVersion,
+		IPNVersion:   h.IPNVersion,
+		Distro:       h.Distro,
+		DeviceModel:  h.DeviceModel,
+		ShieldsUp:    h.ShieldsUp,
+		AllowsUpdate: h.AllowsUpdate,
+		Services:     services,
+	}
+}
+
 func Hash(s Snapshot) string {
 	normalized := struct {
 		Peers         []Peer  `json:"peers"`
+		Self          *Self   `json:"self,omitempty"`
 		DaemonState   string  `json:"daemon_state,omitempty"`
 		Prefs         Prefs   `json:"prefs,omitempty"`
 		Tailnet       Tailnet `json:"tailnet,omitempty"`
 		LastErrorText string  `json:"last_error_text,omitempty"`
 	}{
 		Peers:         s.Peers,
+		Self:          s.Self,
 		DaemonState:   s.DaemonState,
 		Prefs:         s.Prefs,
 		Tailnet:       s.Tailnet,
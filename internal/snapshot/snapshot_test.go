@@ -1,6 +1,7 @@
 package snapshot
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -148,3 +149,24 @@ func TestNormalizeSortsDeviceIdentityFieldsDeterministically(t *testing.T) {
 		t.Fatalf("expected deterministic identity ordering to preserve hash, got %q != %q", a.Hash, b.Hash)
 	}
 }
+
+func TestNormalizeSelf(t *testing.T) {
+	now := time.Date(2026, 2, 13, 0, 0, 0, 0, time.UTC)
+	without := Normalize(source.Netmap{}, now)
+	if without.Self != nil {
+		t.Fatalf("expected nil self when the source does not report it, got %#v", without.Self)
+	}
+
+	a := Normalize(source.Netmap{Self: &source.Self{
+		ID:   "self-1",
+		Tags: []string{"tag:z", "tag:a"},
+		IPs:  []string{"fd7a:115c:a1e0::1", "100.64.0.1"},
+	}}, now)
+	if a.Self == nil || !reflect.DeepEqual(a.Self.Tags, []string{"tag:a", "tag:z"}) || a.Self.IPs[0] != "100.64.0.1" {
+		t.Fatalf("expected sorted self identity, got %#v", a.Self)
+	}
+	b := Normalize(source.Netmap{Self: &source.Self{ID: "self-1", Tags: []string{"tag:a"}}}, now)
+	if a.Hash == b.Hash {
+		t.Fatal("expected self tag changes to alter hash")
+	}
+}
@@ -18,13 +18,42 @@ type Peer struct {
 	Owners            []string          `json:"owners,omitempty"`
 	IPs               []string          `json:"ips,omitempty"`
 	Routes            []string          `json:"routes,omitempty"`
+	AdvertisedRoutes  []string          `json:"advertised_routes,omitempty"`
+	ApprovedRoutes    []string          `json:"approved_routes,omitempty"`
 	MachineAuthorized bool              `json:"machine_authorized,omitempty"`
 	Expired           bool              `json:"expired,omitempty"`
 	KeyExpiry         string            `json:"key_expiry,omitempty"`
 	HostinfoHash      string            `json:"hostinfo_hash,omitempty"`
+	Hostinfo          *Hostinfo         `json:"hostinfo,omitempty"`
 	Metadata          map[string]string `json:"metadata,omitempty"`
 }
 
+// Hostinfo holds the Hostinfo fields Sentinel tracks. Services are
+// "proto:port" entries. Peer.Hostinfo is nil when the source does not report
+// Hostinfo.
+type Hostinfo struct {
+	OS           string   `json:"os,omitempty"`
+	OSVersion    string   `json:"os_version,omitempty"`
+	IPNVersion   string   `json:"ipn_version,omitempty"`
+	Distro       string   `json:"distro,omitempty"`
+	DeviceModel  string   `json:"device_model,omitempty"`
+	ShieldsUp    bool     `json:"shields_up,omitempty"`
+	AllowsUpdate bool     `json:"allows_update,omitempty"`
+	Services     []string `json:"services,omitempty"`
+}
+
+// Self is Sentinel's own node, from Status.Self or NetMap.SelfNode.
+type Self struct {
+	ID                string   `json:"id"`
+	Name              string   `json:"name"`
+	Tags              []string `json:"tags,omitempty"`
+	Owners            []string `json:"owners,omitempty"`
+	IPs               []string `json:"ips,omitempty"`
+	MachineAuthorized bool     `json:"machine_authorized,omitempty"`
+	Expired           bool     `json:"expired,omitempty"`
+	KeyExpiry         string   `json:"key_expiry,omitempty"`
+}
+
 type Prefs struct {
 	AdvertiseRoutes []string `json:"advertise_routes,omitempty"`
 	ExitNodeID      string   `json:"exit_node_id,omitempty"`
@@ -40,6 +69,7 @@ type Tailnet struct {
 type Netmap struct {
 	PolledAt     time.Time `json:"polled_at"`
 	Peers        []Peer    `json:"peers"`
+	Self         *Self     `json:"self,omitempty"`
 	DaemonState  string    `json:"daemon_state,omitempty"`
 	Prefs        Prefs     `json:"prefs,omitempty"`
 	Tailnet      Tailnet   `json:"tailnet,omitempty"`
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/netip"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -102,6 +103,7 @@ func decodeNetmapFromStatusJSON(data []byte) (Netmap, error) {
 	var raw struct {
 		BackendState   string                    `json:"BackendState"`
 		CurrentTailnet map[string]any            `json:"CurrentTailnet"`
+		Self           map[string]any            `json:"Self"`
 		Peer           map[string]map[string]any `json:"Peer"`
 	}
 	if err := json.Unmarshal(data, &raw); err != nil {
@@ -116,42 +118,56 @@ func decodeNetmapFromStatusJSON(data []byte) (Netmap, error) {
 		)
 	}
 
+	if raw.Self != nil {
+		self := selfFromPeer(decodeStatusPeer("", raw.Self))
+		nm.Self = &self
+	}
+
 	peers := make([]Peer, 0, len(raw.Peer))
 	for fallbackID, peer := range raw.Peer {
-		p := Peer{
-			ID:                firstNonEmpty(stringVal(peer, "StableID"), fallbackID),
-			Name:              firstNonEmpty(stringVal(peer, "HostName"), hostFromDNSName(stringVal(peer, "DNSName"))),
-			Online:            boolVal(peer, "Online"),
-			Tags:              sortedCopy(stringSliceVal(peer, "Tags")),
-			Owners:            normalizeIdentityValues([]string{anyToString(peer["UserID"])}),
-			IPs:               extractIdentityIPs(peer, "TailscaleIPs", "Addresses"),
-			Routes:            sortedCopy(stringSliceVal(peer, "PrimaryRoutes")),
-			MachineAuthorized: boolVal(peer, "MachineAuthorized"),
-			Expired:           boolVal(peer, "Expired"),
-			KeyExpiry:         anyToString(peer["KeyExpiry"]),
-		}
-		meta := map[string]string{}
-		if v := stringVal(peer, "OS"); v != "" {
-			meta["os"] = v
-		}
-		if hostinfo := mapVal(peer, "Hostinfo"); hostinfo != nil {
-			p.HostinfoHash = stableMapHash(hostinfo)
-		}
-		if v := anyToString(peer["UserID"]); v != "" {
-			meta["user_id"] = v
-		}
-		if v := anyToString(peer["LastSeen"]); v != "" {
-			meta["last_seen"] = v
-		}
-		if len(meta) > 0 {
-			p.Metadata = meta
-		}
-		peers = append(peers, p)
+		peers = append(peers, decodeStatusPeer(fallbackID, peer))
 	}
 	nm.Peers = peers
 	return nm, nil
 }
 
+// decodeStatusPeer converts one ipnstate.PeerStatus. fallbackID is used when
+// the status has no StableID.
+func decodeStatusPeer(fallbackID string, peer map[string]any) Peer {
+	p := Peer{
+		ID:                firstNonEmpty(stringVal(peer, "StableID"), fallbackID),
+		Name:              firstNonEmpty(stringVal(peer, "HostName"), hostFromDNSName(stringVal(peer, "DNSName"))),
+		Online:            boolVal(peer, "Online"),
+		Tags:              sortedCopy(stringSliceVal(peer, "Tags")),
+		Owners:            normalizeIdentityValues([]string{anyToString(peer["UserID"])}),
+		IPs:               extractIdentityIPs(peer, "TailscaleIPs", "Addresses"),
+		Routes:            sortedCopy(stringSliceVal(peer, "PrimaryRoutes")),
+		MachineAuthorized: boolVal(peer, "MachineAuthorized"),
+		Expired:           boolVal(peer, "Expired"),
+		KeyExpiry:         anyToString(peer["KeyExpiry"]),
+	}
+	p.ApprovedRoutes = approvedRoutes(stringSliceVal(peer, "AllowedIPs"), p.IPs)
+	meta := map[string]string{}
+	if v := stringVal(peer, "OS"); v != "" {
+		meta["os"] = v
+	}
+	if hostinfo := mapVal(peer, "Hostinfo"); hostinfo != nil {
+		p.HostinfoHash = stableMapHash(hostinfo)
+		p.Hostinfo = decodeHostinfo(hostinfo)
+		p.AdvertisedRoutes = sortedCopy(stringSliceVal(hostinfo, "RoutableIPs"))
+	}
+	if v := anyToString(peer["UserID"]); v != "" {
+		meta["user_id"] = v
+	}
+	if v := anyToString(peer["LastSeen"]); v != "" {
+		meta["last_seen"] = v
+	}
+	if len(meta) > 0 {
+		p.Metadata = meta
+	}
+	return p
+}
+
 func decodePeersFromNetMapJSON(data []byte) ([]Peer, error) {
 	nm, err := decodeNetMapJSON(data)
 	if err != nil {
@@ -164,6 +180,7 @@ func decodeNetMapJSON(data []byte) (Netmap, error) {
 	var raw struct {
 		Domain     string           `json:"Domain"`
 		TKAEnabled bool             `json:"TKAEnabled"`
+		SelfNode   map[string]any   `json:"SelfNode"`
 		Peers      []map[string]any `json:"Peers"`
 	}
 	if err := json.Unmarshal(data, &raw); err != nil {
@@ -177,43 +194,101 @@ func decodeNetMapJSON(data []byte) (Netmap, error) {
 		},
 	}
 
+	if len(raw.SelfNode) > 0 {
+		self := selfFromPeer(decodeNetMapNode(raw.SelfNode))
+		nm.Self = &self
+	}
+
 	peers := make([]Peer, 0, len(raw.Peers))
 	for _, node := range raw.Peers {
-		p := Peer{
-			ID:                firstNonEmpty(stringVal(node, "StableID"), anyToString(node["ID"])),
-			Name:              firstNonEmpty(stringVal(node, "ComputedName"), hostFromDNSName(stringVal(node, "Name"))),
-			Online:            boolVal(node, "Online"),
-			Tags:              sortedCopy(stringSliceVal(node, "Tags")),
-			Owners:            normalizeIdentityValues([]string{anyToString(node["User"])}),
-			IPs:               extractIdentityIPs(node, "Addresses", "TailscaleIPs"),
-			Routes:            sortedCopy(stringSliceVal(node, "PrimaryRoutes")),
-			MachineAuthorized: boolVal(node, "MachineAuthorized"),
-			Expired:           boolVal(node, "Expired"),
-			KeyExpiry:         anyToString(node["KeyExpiry"]),
+		peers = append(peers, decodeNetMapNode(node))
+	}
+	nm.Peers = peers
+	return nm, nil
+}
+
+// decodeNetMapNode converts one tailcfg.Node.
+func decodeNetMapNode(node map[string]any) Peer {
+	p := Peer{
+		ID:                firstNonEmpty(stringVal(node, "StableID"), anyToString(node["ID"])),
+		Name:              firstNonEmpty(stringVal(node, "ComputedName"), hostFromDNSName(stringVal(node, "Name"))),
+		Online:            boolVal(node, "Online"),
+		Tags:              sortedCopy(stringSliceVal(node, "Tags")),
+		Owners:            normalizeIdentityValues([]string{anyToString(node["User"])}),
+		IPs:               extractIdentityIPs(node, "Addresses", "TailscaleIPs"),
+		Routes:            sortedCopy(stringSliceVal(node, "PrimaryRoutes")),
+		MachineAuthorized: boolVal(node, "MachineAuthorized"),
+		Expired:           boolVal(node, "Expired"),
+		KeyExpiry:         anyToString(node["KeyExpiry"]),
+	}
+	p.ApprovedRoutes = approvedRoutes(stringSliceVal(node, "AllowedIPs"), p.IPs)
+	meta := map[string]string{}
+	if hostinfo := mapVal(node, "Hostinfo"); hostinfo != nil {
+		p.HostinfoHash = stableMapHash(hostinfo)
+		p.Hostinfo = decodeHostinfo(hostinfo)
+		p.AdvertisedRoutes = sortedCopy(stringSliceVal(hostinfo, "RoutableIPs"))
+		if v := stringVal(hostinfo, "OS"); v != "" {
+			meta["os"] = v
 		}
-		meta := map[string]string{}
-		if hostinfo := mapVal(node, "Hostinfo"); hostinfo != nil {
-			p.HostinfoHash = stableMapHash(hostinfo)
-			if v := stringVal(hostinfo, "OS"); v != "" {
-				meta["os"] = v
+		if p.Name == "" {
+			p.Name = stringVal(hostinfo, "Hostname")
+		}
+	}
+	if v := anyToString(node["User"]); v != "" {
+		meta["user_id"] = v
+	}
+	if v := anyToString(node["LastSeen"]); v != "" {
+		meta["last_seen"] = v
+	}
+	if len(meta) > 0 {
+		p.Metadata = meta
+	}
+	return p
+}
+
+// selfFromPeer keeps the fields of Sentinel's own node that are tracked.
+func selfFromPeer(p Peer) Self {
+	return Self{
+		ID:                p.ID,
+		Name:              p.Name,
+		Tags:              p.Tags,
+		Owners:            p.Owners,
+		IPs:               p.IPs,
+		MachineAuthorized: p.MachineAuthorized,
+		Expired:           p.Expired,
+		KeyExpiry:         p.KeyExpiry,
+	}
+}
+
+// decodeHostinfo extracts the tracked fields of a tailcfg.Hostinfo. Distro
+// includes DistroVersion when set.
+func decodeHostinfo(m map[string]any) *Hostinfo {
+	h := &Hostinfo{
+		OS:           stringVal(m, "OS"),
+		OSVersion:    stringVal(m, "OSVersion"),
+		IPNVersion:   stringVal(m, "IPNVersion"),
+		Distro:       strings.TrimSpace(stringVal(m, "Distro") + " " + stringVal(m, "DistroVersion")),
+		DeviceModel:  stringVal(m, "DeviceModel"),
+		ShieldsUp:    boolVal(m, "ShieldsUp"),
+		AllowsUpdate: boolVal(m, "AllowsUpdate"),
+	}
+	if raw, ok := m["Services"].([]any); ok {
+		seen := map[string]struct{}{}
+		for _, item := range raw {
+			svc, ok := item.(map[string]any)
+			if !ok {
+				continue
 			}
-			if p.Name == "" {
-				p.Name = stringVal(hostinfo, "Hostname")
+			entry := stringVal(svc, "Proto") + ":" + anyToString(svc["Port"])
+			if _, dup := seen[entry]; dup {
+				continue
 			}
+			seen[entry] = struct{}{}
+			h.Services = append(h.Services, entry)
 		}
-		if v := anyToString(node["User"]); v != "" {
-			meta["user_id"] = v
-		}
-		if v := anyToString(node["LastSeen"]); v != "" {
-			meta["last_seen"] = v
-		}
-		if len(meta) > 0 {
-			p.Metadata = meta
-		}
-		peers = append(peers, p)
+		sort.Strings(h.Services)
 	}
-	nm.Peers = peers
-	return nm, nil
+	return h
 }
 
 func stringVal(m map[string]any, key string) string {
@@ -311,6 +386,21 @@ func firstNonEmpty(values ...string) string {
 	return ""
 }
 
+// approvedRoutes returns the routes in a peer's AllowedIPs, which control
+// sets to the routes approved for the peer, whether or not it is primary for
+// them. The peer's own addresses are dropped.
+func approvedRoutes(allowed, ips []string) []string {
+	out := make([]string, 0, len(allowed))
+	for _, raw := range allowed {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(raw))
+		if err != nil || (prefix.IsSingleIP() && slices.Contains(ips, prefix.Addr().String())) {
+			continue
+		}
+		out = append(out, prefix.String())
+	}
+	return sortedCopy(out)
+}
+
 func sortedCopy(items []string) []string {
 	if len(items) == 0 {
 		return nil
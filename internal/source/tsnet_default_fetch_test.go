@@ -1,6 +1,9 @@
 package source
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestDecodePeersFromStatusJSON(t *testing.T) {
 	input := []byte(`{
@@ -14,6 +17,7 @@ func TestDecodePeersFromStatusJSON(t *testing.T) {
 				"OS": "linux",
 				"UserID": 123,
 				"TailscaleIPs": ["100.64.0.10", "fd7a:115c:a1e0::10"],
+				"AllowedIPs": ["100.64.0.10/32", "fd7a:115c:a1e0::10/128", "10.0.0.0/24", "10.1.0.0/24"],
 				"PrimaryRoutes": ["10.0.0.0/24"],
 				"MachineAuthorized": true,
 				"Expired": false,
@@ -35,6 +39,9 @@ func TestDecodePeersFromStatusJSON(t *testing.T) {
 	if peers[0].Name != "ai" {
 		t.Fatalf("expected name ai, got %q", peers[0].Name)
 	}
+	if got := peers[0].ApprovedRoutes; len(got) != 2 || got[0] != "10.0.0.0/24" || got[1] != "10.1.0.0/24" {
+		t.Fatalf("expected approved routes from AllowedIPs, got %#v", got)
+	}
 	if !peers[0].Online {
 		t.Fatal("expected peer to be online")
 	}
@@ -69,11 +76,12 @@ func TestDecodePeersFromNetMapJSON(t *testing.T) {
 				"Online": false,
 				"Tags": ["tag:dev"],
 				"Addresses": ["100.64.0.20/32", "fd7a:115c:a1e0::20/128"],
+				"AllowedIPs": ["100.64.0.20/32", "fd7a:115c:a1e0::20/128", "10.42.0.0/24"],
 				"PrimaryRoutes": ["10.42.0.0/24"],
 				"MachineAuthorized": true,
 				"Expired": true,
 				"KeyExpiry": "2026-08-11T19:20:24Z",
-				"Hostinfo": {"OS":"macOS","Hostname":"sentinel","Services":[{"Proto":"peerapi4","Port":50626}]}
+				"Hostinfo": {"OS":"macOS","Hostname":"sentinel","RoutableIPs":["10.42.0.0/24","10.43.0.0/24"],"Services":[{"Proto":"peerapi4","Port":50626}]}
 			}
 		]
 	}`)
@@ -105,6 +113,12 @@ func TestDecodePeersFromNetMapJSON(t *testing.T) {
 	if got := len(peers[0].Routes); got != 1 || peers[0].Routes[0] != "10.42.0.0/24" {
 		t.Fatalf("expected route to be decoded, got %#v", peers[0].Routes)
 	}
+	if got := peers[0].AdvertisedRoutes; len(got) != 2 || got[1] != "10.43.0.0/24" {
+		t.Fatalf("expected advertised routes from hostinfo, got %#v", got)
+	}
+	if got := peers[0].ApprovedRoutes; len(got) != 1 || got[0] != "10.42.0.0/24" {
+		t.Fatalf("expected approved routes from AllowedIPs without the peer's addresses, got %#v", got)
+	}
 	if !peers[0].MachineAuthorized {
 		t.Fatal("expected machine_authorized=true")
 	}
@@ -167,3 +181,93 @@ func TestDecodeStatusAndNetmapIdentityParity(t *testing.T) {
 		t.Fatalf("expected IP identity parity, status=%#v netmap=%#v", got, want)
 	}
 }
+
+func TestDecodeNetMapJSONHostinfoFields(t *testing.T) {
+	data := []byte(`{"Peers":[{"StableID":"n1","ComputedName":"laptop","Hostinfo":{
+		"OS":"linux","OSVersion":"6.8.0","IPNVersion":"1.80.2-t123","Distro":"ubuntu","DistroVersion":"24.04",
+		"DeviceModel":"ThinkPad","ShieldsUp":true,"AllowsUpdate":true,"NetInfo":{"PreferredDERP":1},
+		"Services":[{"Proto":"tcp","Port":22},{"Proto":"peerapi4","Port":41641},{"Proto":"tcp","Port":22}]
+	}}]}`)
+	nm, err := decodeNetMapJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Hostinfo{
+		OS:           "linux",
+		OSVersion:    "6.8.0",
+		IPNVersion:   "1.80.2-t123",
+		Distro:       "ubuntu 24.04",
+		DeviceModel:  "ThinkPad",
+		ShieldsUp:    true,
+		AllowsUpdate: true,
+		Services:     []string{"peerapi4:41641", "tcp:22"},
+	}
+	if got := nm.Peers[0].Hostinfo; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected hostinfo:\n got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestDecodeSelfFromStatusAndNetMap(t *testing.T) {
+	want := &Self{
+		ID:                "nSelf",
+		Name:              "sentinel",
+		Tags:              []string{"tag:sentinel"},
+		Owners:            []string{"42"},
+		IPs:               []string{"100.64.0.1"},
+		MachineAuthorized: true,
+		KeyExpiry:         "2026-06-01T00:00:00Z",
+	}
+
+	status, err := decodeNetmapFromStatusJSON([]byte(`{
+		"BackendState": "Running",
+		"Self": {
+			"StableID": "nSelf",
+			"HostName": "sentinel",
+			"Online": true,
+			"UserID": 42,
+			"Tags": ["tag:sentinel"],
+			"TailscaleIPs": ["100.64.0.1"],
+			"MachineAuthorized": true,
+			"KeyExpiry": "2026-06-01T00:00:00Z",
+			"LastSeen": "2026-03-01T00:00:00Z"
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(status.Self, want) {
+		t.Fatalf("status self mismatch:\n got: %#v\nwant: %#v", status.Self, want)
+	}
+	if len(status.Peers) != 0 {
+		t.Fatalf("expected self not to be listed as a peer, got %#v", status.Peers)
+	}
+
+	netmap, err := decodeNetMapJSON([]byte(`{
+		"SelfNode": {
+			"ID": 1,
+			"StableID": "nSelf",
+			"Name": "sentinel.tail.test.",
+			"User": 42,
+			"Tags": ["tag:sentinel"],
+			"Addresses": ["100.64.0.1/32"],
+			"MachineAuthorized": true,
+			"KeyExpiry": "2026-06-01T00:00:00Z",
+			"Hostinfo": {"Hostname": "sentinel"}
+		},
+		"Peers": []
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(netmap.Self, want) {
+		t.Fatalf("netmap self mismatch:\n got: %#v\nwant: %#v", netmap.Self, want)
+	}
+
+	none, err := decodeNetMapJSON([]byte(`{"Peers": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if none.Self != nil {
+		t.Fatalf("expected nil self when not reported, got %#v", none.Self)
+	}
+}
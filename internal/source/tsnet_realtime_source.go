@@ -130,6 +130,9 @@ func (s *TSNetRealtimeSource) Poll(ctx context.Context) (Netmap, error) {
 		if note.ErrMessage != nil {
 			s.cache.ErrorMessage = *note.ErrMessage
 			updated = true
+		} else if note.NetMap != nil {
+			// A fresh netmap supersedes an earlier bus error.
+			s.cache.ErrorMessage = ""
 		}
 		if note.NetMap == nil {
 			if !updated || !s.ready {
@@ -155,6 +158,7 @@ func (s *TSNetRealtimeSource) Poll(ctx context.Context) (Netmap, error) {
 			decoded.Tailnet.TKAEnabled = note.NetMap.TKAEnabled
 		}
 		s.cache.Peers = decoded.Peers
+		s.cache.Self = decoded.Self
 		s.cache.Tailnet = decoded.Tailnet
 		s.ready = true
 		updated = true
@@ -239,6 +243,19 @@ func cloneNetmap(nm Netmap) Netmap {
 		if len(p.Routes) > 0 {
 			clone.Routes = append([]string(nil), p.Routes...)
 		}
+		if len(p.AdvertisedRoutes) > 0 {
+			clone.AdvertisedRoutes = append([]string(nil), p.AdvertisedRoutes...)
+		}
+		if len(p.ApprovedRoutes) > 0 {
+			clone.ApprovedRoutes = append([]string(nil), p.ApprovedRoutes...)
+		}
+		if p.Hostinfo != nil {
+			hostinfo := *p.Hostinfo
+			if len(p.Hostinfo.Services) > 0 {
+				hostinfo.Services = append([]string(nil), p.Hostinfo.Services...)
+			}
+			clone.Hostinfo = &hostinfo
+		}
 		if len(p.Metadata) > 0 {
 			meta := make(map[string]string, len(p.Metadata))
 			for k, v := range p.Metadata {
@@ -251,5 +268,12 @@ func cloneNetmap(nm Netmap) Netmap {
 	if len(nm.Prefs.AdvertiseRoutes) > 0 {
 		out.Prefs.AdvertiseRoutes = append([]string(nil), nm.Prefs.AdvertiseRoutes...)
 	}
+	if nm.Self != nil {
+		self := *nm.Self
+		self.Tags = append([]string(nil), nm.Self.Tags...)
+		self.Owners = append([]string(nil), nm.Self.Owners...)
+		self.IPs = append([]string(nil), nm.Self.IPs...)
+		out.Self = &self
+	}
 	return out
 }
@@ -293,3 +293,14 @@ func TestTSNetRealtimeSourceEmitsPrefsAndStateAfterBaseline(t *testing.T) {
 		t.Fatalf("expected advertise route to be populated, got %#v", third.Prefs.AdvertiseRoutes)
 	}
 }
+
+func TestCloneNetmapCopiesHostinfo(t *testing.T) {
+	nm := Netmap{Peers: []Peer{{ID: "peer1", Hostinfo: &Hostinfo{IPNVersion: "1.80.0", Services: []string{"tcp:22"}}}}}
+	clone := cloneNetmap(nm)
+
+	nm.Peers[0].Hostinfo.IPNVersion = "1.82.0"
+	nm.Peers[0].Hostinfo.Services[0] = "tcp:80"
+	if got := clone.Peers[0].Hostinfo; got.IPNVersion != "1.80.0" || got.Services[0] != "tcp:22" {
+		t.Fatalf("expected the clone's hostinfo to be unaffected by later updates, got %#v", got)
+	}
+}
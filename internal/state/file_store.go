@@ -6,17 +6,23 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/jaxxstorm/sentinel/internal/snapshot"
 )
 
 type fileData struct {
-	Snapshot        *snapshot.Snapshot   `json:"snapshot,omitempty"`
-	IdempotencyKeys map[string]time.Time `json:"idempotency_keys,omitempty"`
+	Snapshot        *snapshot.Snapshot         `json:"snapshot,omitempty"`
+	IdempotencyKeys map[string]time.Time       `json:"idempotency_keys,omitempty"`
+	Detectors       map[string]json.RawMessage `json:"detectors,omitempty"`
+	LastRunAt       *time.Time                 `json:"last_run_at,omitempty"`
 }
 
+// FileStore keeps all state in one JSON file. Its methods are safe for
+// concurrent use within a process.
 type FileStore struct {
+	mu   sync.Mutex
 	path string
 	now  func() time.Time
 }
@@ -26,6 +32,8 @@ func NewFileStore(path string) *FileStore {
 }
 
 func (s *FileStore) LoadSnapshot() (snapshot.Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	data, err := s.read()
 	if err != nil {
 		return snapshot.Snapshot{}, err
@@ -37,6 +45,8 @@ func (s *FileStore) LoadSnapshot() (snapshot.Snapshot, error) {
 }
 
 func (s *FileStore) SaveSnapshot(in snapshot.Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	data, err := s.read()
 	if err != nil && !errors.Is(err, os.ErrNotExist) && !errors.Is(err, ErrNoSnapshot) {
 		return err
@@ -49,6 +59,8 @@ func (s *FileStore) SaveSnapshot(in snapshot.Snapshot) error {
 }
 
 func (s *FileStore) SeenIdempotencyKey(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	data, err := s.read()
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -71,6 +83,8 @@ func (s *FileStore) SeenIdempotencyKey(key string) (bool, error) {
 }
 
 func (s *FileStore) RecordIdempotencyKey(key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	data, err := s.read()
 	if err != nil && !errors.Is(err, os.ErrNotExist) {
 		return err
@@ -82,6 +96,61 @@ func (s *FileStore) RecordIdempotencyKey(key string, ttl time.Duration) error {
 	return s.write(data)
 }
 
+func (s *FileStore) LoadDetectorState(name string) (json.RawMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.read()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return data.Detectors[name], nil
+}
+
+func (s *FileStore) SaveDetectorState(name string, in json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.read()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if data.Detectors == nil {
+		data.Detectors = map[string]json.RawMessage{}
+	}
+	data.Detectors[name] = in
+	return s.write(data)
+}
+
+func (s *FileStore) LoadLastRun() (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.read()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	if data.LastRunAt == nil {
+		return time.Time{}, nil
+	}
+	return *data.LastRunAt, nil
+}
+
+func (s *FileStore) SaveLastRun(at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.read()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	at = at.UTC()
+	data.LastRunAt = &at
+	return s.write(data)
+}
+
 func (s *FileStore) read() (fileData, error) {
 	b, err := os.ReadFile(s.path)
 	if err != nil {
@@ -1,6 +1,8 @@
 package state
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -55,3 +57,55 @@ func TestFileStoreRecoversFromCorruption(t *testing.T) {
 		t.Fatal("expected corrupt backup file to be created")
 	}
 }
+
+func TestFileStoreDetectorStateSurvivesSnapshotWrites(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+
+	empty, err := store.LoadDetectorState("key_expiry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty != nil {
+		t.Fatalf("expected no detector state, got %s", empty)
+	}
+
+	if err := store.SaveDetectorState("key_expiry", []byte(`{"peer1":["24h"]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveSnapshot(snapshot.Snapshot{Hash: "hash1"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.LoadDetectorState("key_expiry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, got); err != nil {
+		t.Fatal(err)
+	}
+	if compact.String() != `{"peer1":["24h"]}` {
+		t.Fatalf("expected detector state to persist, got %s", got)
+	}
+}
+
+func TestFileStoreLastRunSurvivesSnapshotWrites(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+
+	if got, err := store.LoadLastRun(); err != nil || !got.IsZero() {
+		t.Fatalf("expected no last run, got %s, %v", got, err)
+	}
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := store.SaveLastRun(at); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveSnapshot(snapshot.Snapshot{Hash: "hash1"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.LoadLastRun()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(at) {
+		t.Fatalf("expected last run %s, got %s", at, got)
+	}
+}
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -12,6 +13,14 @@ type StateStore interface {
 	SaveSnapshot(snapshot.Snapshot) error
 	SeenIdempotencyKey(key string) (bool, error)
 	RecordIdempotencyKey(key string, ttl time.Duration) error
+	// LoadDetectorState returns the state a detector saved under name, or
+	// nil when none has been saved.
+	LoadDetectorState(name string) (json.RawMessage, error)
+	SaveDetectorState(name string, data json.RawMessage) error
+	// LoadLastRun returns when Sentinel last recorded that it was running
+	// and could see the tailnet, or the zero time when it never has.
+	LoadLastRun() (time.Time, error)
+	SaveLastRun(at time.Time) error
 }
 
 var ErrNoSnapshot = errors.New("no snapshot")
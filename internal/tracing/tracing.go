@@ -0,0 +1,125 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/jaxxstorm/sentinel/internal/event"
+	"github.com/jaxxstorm/sentinel/internal/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/jaxxstorm/sentinel"
+
+// Span attribute keys shared by the pipeline stages.
+const (
+	AttrEventID        = attribute.Key("sentinel.event.id")
+	AttrEventType      = attribute.Key("sentinel.event.type")
+	AttrEventIDs       = attribute.Key("sentinel.event.ids")
+	AttrEventCount     = attribute.Key("sentinel.event.count")
+	AttrIdempotencyKey = attribute.Key("sentinel.idempotency_key")
+	AttrDetector       = attribute.Key("sentinel.detector")
+	AttrSink           = attribute.Key("sentinel.sink")
+	AttrPeerCount      = attribute.Key("sentinel.peer_count")
+	AttrSuppressed     = attribute.Key("sentinel.suppressed_count")
+	AttrDryRun         = attribute.Key("sentinel.dry_run")
+)
+
+type Config struct {
+	Enabled bool
+	// Endpoint is the OTLP/HTTP collector URL, for example http://localhost:4318.
+	// A URL without a path is sent to /v1/traces.
+	// Empty falls back to the standard OTEL_EXPORTER_OTLP_* environment variables.
+	Endpoint    string
+	ServiceName string
+	SampleRatio float64
+}
+
+// ShutdownFunc flushes buffered spans and stops the exporter.
+type ShutdownFunc func(context.Context) error
+
+// Setup installs the global tracer provider. When tracing is disabled the
+// global no-op provider is left in place, so spans cost next to nothing.
+func Setup(ctx context.Context, cfg Config) (ShutdownFunc, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+	opts := []otlptracehttp.Option{}
+	if cfg.Endpoint != "" {
+		endpoint, err := tracesEndpoint(cfg.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create otlp trace exporter: %w", err)
+	}
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "sentinel"
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+		attribute.String("service.version", version.Current().Version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("build trace resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// tracesEndpoint appends the OTLP traces path when endpoint is a bare collector URL.
+func tracesEndpoint(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("parse tracing endpoint: %w", err)
+	}
+	if strings.Trim(u.Path, "/") == "" {
+		u.Path = "/v1/traces"
+	}
+	return u.String(), nil
+}
+
+// Tracer returns Sentinel's tracer from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// EndSpan records err on span, if any, and ends it.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// EventAttributes identifies a batch of events on a span.
+func EventAttributes(events []event.Event) []attribute.KeyValue {
+	ids := make([]string, 0, len(events))
+	for _, evt := range events {
+		ids = append(ids, evt.EventID)
+	}
+	return []attribute.KeyValue{
+		AttrEventCount.Int(len(events)),
+		AttrEventIDs.StringSlice(ids),
+	}
+}
@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestSetupExportsSpansToCollector(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	shutdown, err := Setup(context.Background(), Config{
+		Enabled:     true,
+		Endpoint:    collector.URL,
+		ServiceName: "sentinel-test",
+		SampleRatio: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := Tracer().Start(context.Background(), "sentinel.RunOnce")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) == 0 {
+		t.Fatal("expected the collector to receive an export request")
+	}
+	if paths[0] != "POST /v1/traces" {
+		t.Fatalf("expected POST /v1/traces, got %q", paths[0])
+	}
+}
+
+func TestSetupDisabledLeavesNoopProvider(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Config{Enabled: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := Tracer().Start(context.Background(), "sentinel.RunOnce")
+	if span.SpanContext().IsValid() {
+		t.Fatal("expected a non-recording span when tracing is disabled")
+	}
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}